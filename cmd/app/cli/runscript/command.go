@@ -7,6 +7,14 @@ import (
 	cliapp "exusiai.dev/backend-next/cmd/app/cli"
 	script_archive_drop_reports "exusiai.dev/backend-next/cmd/app/cli/runscript/scripts/archive_drop_reports"
 	script_migrate_drop_report_extras_cols "exusiai.dev/backend-next/cmd/app/cli/runscript/scripts/at20230110-migrate_drop_report_extras_cols"
+	script_add_accounts_weight_col "exusiai.dev/backend-next/cmd/app/cli/runscript/scripts/at20261018-add_accounts_weight_col"
+	script_add_drop_report_extras_country_col "exusiai.dev/backend-next/cmd/app/cli/runscript/scripts/at20261018-add_drop_report_extras_country_col"
+	script_create_admin_audit_logs "exusiai.dev/backend-next/cmd/app/cli/runscript/scripts/at20261018-create_admin_audit_logs"
+	script_create_reject_rule_revisions "exusiai.dev/backend-next/cmd/app/cli/runscript/scripts/at20261018-create_reject_rule_revisions"
+	script_create_scheduled_notices "exusiai.dev/backend-next/cmd/app/cli/runscript/scripts/at20261018-create_scheduled_notices"
+	script_create_short_links "exusiai.dev/backend-next/cmd/app/cli/runscript/scripts/at20261018-create_short_links"
+	script_create_site_stats_daily "exusiai.dev/backend-next/cmd/app/cli/runscript/scripts/at20261018-create_site_stats_daily"
+	script_restore_drop_reports "exusiai.dev/backend-next/cmd/app/cli/runscript/scripts/restore_drop_reports"
 )
 
 func depsFn[T any]() func() T {
@@ -23,7 +31,15 @@ func Command() *cli.Command {
 		Description: "run maintenance go scripts",
 		Subcommands: []*cli.Command{
 			script_migrate_drop_report_extras_cols.Command(depsFn[script_migrate_drop_report_extras_cols.CommandDeps]()),
+			script_create_reject_rule_revisions.Command(depsFn[script_create_reject_rule_revisions.CommandDeps]()),
+			script_create_site_stats_daily.Command(depsFn[script_create_site_stats_daily.CommandDeps]()),
+			script_create_short_links.Command(depsFn[script_create_short_links.CommandDeps]()),
+			script_add_drop_report_extras_country_col.Command(depsFn[script_add_drop_report_extras_country_col.CommandDeps]()),
+			script_create_scheduled_notices.Command(depsFn[script_create_scheduled_notices.CommandDeps]()),
+			script_create_admin_audit_logs.Command(depsFn[script_create_admin_audit_logs.CommandDeps]()),
+			script_add_accounts_weight_col.Command(depsFn[script_add_accounts_weight_col.CommandDeps]()),
 			script_archive_drop_reports.Command(depsFn[script_archive_drop_reports.CommandDeps]()),
+			script_restore_drop_reports.Command(depsFn[script_restore_drop_reports.CommandDeps]()),
 		},
 	}
 }
@@ -16,7 +16,7 @@ type CommandDeps struct {
 func Command(depsFn func() CommandDeps) *cli.Command {
 	return &cli.Command{
 		Name:        "archive_drop_reports",
-		Description: "archive one day's drop reports to S3",
+		Description: "archive one day's drop reports to the archive storage",
 		Flags: []cli.Flag{
 			&cli.StringFlag{
 				Name:     "date",
@@ -28,11 +28,17 @@ func Command(depsFn func() CommandDeps) *cli.Command {
 				Name:  "delete-after-archive",
 				Usage: "delete the archived drop reports and extras after archiving",
 			},
+			&cli.StringSliceFlag{
+				Name:    "format",
+				Aliases: []string{"f"},
+				Usage:   "formats to archive into, available formats are jsonl and parquet; defaults to the configured DROP_REPORT_ARCHIVE_FORMATS",
+			},
 		},
 		Action: func(ctx *cli.Context) error {
 			date := ctx.String("date")
 			deleteAfterArchive := ctx.Bool("delete-after-archive")
-			return run(ctx, depsFn(), date, deleteAfterArchive)
+			formats := ctx.StringSlice("format")
+			return run(ctx, depsFn(), date, deleteAfterArchive, formats)
 		},
 	}
 }
@@ -9,8 +9,8 @@ import (
 	"github.com/urfave/cli/v2"
 )
 
-func run(ctx *cli.Context, deps CommandDeps, dateStr string, deleteAfterArchive bool) error {
-	log.Info().Str("date", dateStr).Msg("running script")
+func run(ctx *cli.Context, deps CommandDeps, dateStr string, deleteAfterArchive bool, formats []string) error {
+	log.Info().Str("date", dateStr).Strs("formats", formats).Msg("running script")
 
 	var err error
 
@@ -19,7 +19,12 @@ func run(ctx *cli.Context, deps CommandDeps, dateStr string, deleteAfterArchive
 		return errors.Wrap(err, "failed to parse date")
 	}
 
-	if err = deps.ArchiveService.ArchiveByDate(ctx.Context, date, deleteAfterArchive); err != nil {
+	if len(formats) == 0 {
+		err = deps.ArchiveService.ArchiveByDate(ctx.Context, date, deleteAfterArchive)
+	} else {
+		err = deps.ArchiveService.ArchiveByDateWithFormats(ctx.Context, date, deleteAfterArchive, formats)
+	}
+	if err != nil {
 		return errors.Wrap(err, "failed to run archiveDropReports")
 	}
 
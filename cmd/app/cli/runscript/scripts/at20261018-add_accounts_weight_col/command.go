@@ -0,0 +1,23 @@
+package script_add_accounts_weight_col
+
+import (
+	"github.com/uptrace/bun"
+	"github.com/urfave/cli/v2"
+	"go.uber.org/fx"
+)
+
+type CommandDeps struct {
+	fx.In
+
+	DB *bun.DB
+}
+
+func Command(depsFn func() CommandDeps) *cli.Command {
+	return &cli.Command{
+		Name:        "add_accounts_weight_col",
+		Description: "add the `weight` column of the weighted matrices and advanced queries to the `accounts` table",
+		Action: func(ctx *cli.Context) error {
+			return run(ctx, depsFn())
+		},
+	}
+}
@@ -0,0 +1,24 @@
+package script_add_accounts_weight_col
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+)
+
+func run(ctx *cli.Context, deps CommandDeps) error {
+	log.Info().Msg("running script")
+
+	db := deps.DB
+
+	_, err := db.ExecContext(ctx.Context, `ALTER TABLE accounts ADD COLUMN IF NOT EXISTS weight DOUBLE PRECISION NOT NULL DEFAULT 0`)
+	if err != nil {
+		return errors.Wrap(err, "failed to add weight column to accounts table")
+	}
+
+	log.Info().Msg("weight column added to accounts table")
+
+	log.Info().Msg("script finished")
+
+	return nil
+}
@@ -0,0 +1,23 @@
+package script_add_drop_report_extras_country_col
+
+import (
+	"github.com/uptrace/bun"
+	"github.com/urfave/cli/v2"
+	"go.uber.org/fx"
+)
+
+type CommandDeps struct {
+	fx.In
+
+	DB *bun.DB
+}
+
+func Command(depsFn func() CommandDeps) *cli.Command {
+	return &cli.Command{
+		Name:        "add_drop_report_extras_country_col",
+		Description: "add the `country` column, and an index on (country, report_id) for the region filter, to the `drop_report_extras` table",
+		Action: func(ctx *cli.Context) error {
+			return run(ctx, depsFn())
+		},
+	}
+}
@@ -0,0 +1,31 @@
+package script_add_drop_report_extras_country_col
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+)
+
+func run(ctx *cli.Context, deps CommandDeps) error {
+	log.Info().Msg("running script")
+
+	db := deps.DB
+
+	_, err := db.ExecContext(ctx.Context, `ALTER TABLE drop_report_extras ADD COLUMN IF NOT EXISTS country TEXT NULL`)
+	if err != nil {
+		return errors.Wrap(err, "failed to add country column to drop_report_extras table")
+	}
+
+	log.Info().Msg("country column added to drop_report_extras table")
+
+	_, err = db.ExecContext(ctx.Context, `CREATE INDEX CONCURRENTLY IF NOT EXISTS drop_report_extras_country_report_id_idx ON drop_report_extras (country, report_id) WHERE country IS NOT NULL`)
+	if err != nil {
+		return errors.Wrap(err, "failed to create index on (country, report_id) columns of drop_report_extras table")
+	}
+
+	log.Info().Msg("index created on (country, report_id) columns of drop_report_extras table")
+
+	log.Info().Msg("script finished")
+
+	return nil
+}
@@ -0,0 +1,23 @@
+package script_create_admin_audit_logs
+
+import (
+	"github.com/uptrace/bun"
+	"github.com/urfave/cli/v2"
+	"go.uber.org/fx"
+)
+
+type CommandDeps struct {
+	fx.In
+
+	DB *bun.DB
+}
+
+func Command(depsFn func() CommandDeps) *cli.Command {
+	return &cli.Command{
+		Name:        "create_admin_audit_logs",
+		Description: "create the `admin_audit_logs` table recording game data changes made through the admin API",
+		Action: func(ctx *cli.Context) error {
+			return run(ctx, depsFn())
+		},
+	}
+}
@@ -0,0 +1,40 @@
+package script_create_admin_audit_logs
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+)
+
+func run(ctx *cli.Context, deps CommandDeps) error {
+	log.Info().Msg("running script")
+
+	db := deps.DB
+
+	_, err := db.ExecContext(ctx.Context, `CREATE TABLE IF NOT EXISTS admin_audit_logs (
+		log_id SERIAL PRIMARY KEY,
+		action TEXT NOT NULL,
+		entity TEXT NOT NULL,
+		entity_id INTEGER NOT NULL,
+		before JSONB NULL,
+		after JSONB NULL,
+		ip TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NULL
+	)`)
+	if err != nil {
+		return errors.Wrap(err, "failed to create admin_audit_logs table")
+	}
+
+	log.Info().Msg("admin_audit_logs table created")
+
+	_, err = db.ExecContext(ctx.Context, `CREATE INDEX IF NOT EXISTS admin_audit_logs_entity_entity_id_idx ON admin_audit_logs (entity, entity_id)`)
+	if err != nil {
+		return errors.Wrap(err, "failed to create index on (entity, entity_id) columns of admin_audit_logs table")
+	}
+
+	log.Info().Msg("index created on (entity, entity_id) columns of admin_audit_logs table")
+
+	log.Info().Msg("script finished")
+
+	return nil
+}
@@ -0,0 +1,23 @@
+package script_create_reject_rule_revisions
+
+import (
+	"github.com/uptrace/bun"
+	"github.com/urfave/cli/v2"
+	"go.uber.org/fx"
+)
+
+type CommandDeps struct {
+	fx.In
+
+	DB *bun.DB
+}
+
+func Command(depsFn func() CommandDeps) *cli.Command {
+	return &cli.Command{
+		Name:        "create_reject_rule_revisions",
+		Description: "create the `reject_rule_revisions` table recording every saved revision of reject rules",
+		Action: func(ctx *cli.Context) error {
+			return run(ctx, depsFn())
+		},
+	}
+}
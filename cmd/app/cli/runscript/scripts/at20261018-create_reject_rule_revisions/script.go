@@ -0,0 +1,41 @@
+package script_create_reject_rule_revisions
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+)
+
+func run(ctx *cli.Context, deps CommandDeps) error {
+	log.Info().Msg("running script")
+
+	db := deps.DB
+
+	_, err := db.ExecContext(ctx.Context, `CREATE TABLE IF NOT EXISTS reject_rule_revisions (
+		revision_id SERIAL PRIMARY KEY,
+		rule_id INTEGER NOT NULL REFERENCES reject_rules (rule_id),
+		revision INTEGER NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		status SMALLINT NOT NULL,
+		expr TEXT NOT NULL,
+		with_reliability INTEGER NOT NULL,
+		dry_run_evaluated_count INTEGER NULL,
+		dry_run_rejected_count INTEGER NULL
+	)`)
+	if err != nil {
+		return errors.Wrap(err, "failed to create reject_rule_revisions table")
+	}
+
+	log.Info().Msg("reject_rule_revisions table created")
+
+	_, err = db.ExecContext(ctx.Context, `CREATE UNIQUE INDEX IF NOT EXISTS reject_rule_revisions_rule_id_revision_idx ON reject_rule_revisions (rule_id, revision)`)
+	if err != nil {
+		return errors.Wrap(err, "failed to create unique index on (rule_id, revision) columns of reject_rule_revisions table")
+	}
+
+	log.Info().Msg("unique index created on (rule_id, revision) columns of reject_rule_revisions table")
+
+	log.Info().Msg("script finished")
+
+	return nil
+}
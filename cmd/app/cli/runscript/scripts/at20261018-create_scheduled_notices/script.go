@@ -0,0 +1,37 @@
+package script_create_scheduled_notices
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+)
+
+func run(ctx *cli.Context, deps CommandDeps) error {
+	log.Info().Msg("running script")
+
+	db := deps.DB
+
+	_, err := db.ExecContext(ctx.Context, `CREATE TABLE IF NOT EXISTS scheduled_notices (
+		notice_id SERIAL PRIMARY KEY,
+		severity SMALLINT NOT NULL DEFAULT 0,
+		title JSONB NOT NULL,
+		content JSONB NOT NULL,
+		start_time TIMESTAMPTZ NULL,
+		end_time TIMESTAMPTZ NULL,
+		servers JSONB NULL,
+		variants JSONB NULL,
+		sources JSONB NULL,
+		enabled BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at TIMESTAMPTZ NULL,
+		updated_at TIMESTAMPTZ NULL
+	)`)
+	if err != nil {
+		return errors.Wrap(err, "failed to create scheduled_notices table")
+	}
+
+	log.Info().Msg("scheduled_notices table created")
+
+	log.Info().Msg("script finished")
+
+	return nil
+}
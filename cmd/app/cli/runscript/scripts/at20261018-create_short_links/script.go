@@ -0,0 +1,58 @@
+package script_create_short_links
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+)
+
+func run(ctx *cli.Context, deps CommandDeps) error {
+	log.Info().Msg("running script")
+
+	db := deps.DB
+
+	_, err := db.ExecContext(ctx.Context, `CREATE TABLE IF NOT EXISTS short_links (
+		link_id SERIAL PRIMARY KEY,
+		word TEXT NOT NULL,
+		targets JSONB NOT NULL,
+		expires_at TIMESTAMPTZ NULL,
+		comment TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NULL,
+		updated_at TIMESTAMPTZ NULL
+	)`)
+	if err != nil {
+		return errors.Wrap(err, "failed to create short_links table")
+	}
+
+	log.Info().Msg("short_links table created")
+
+	_, err = db.ExecContext(ctx.Context, `CREATE UNIQUE INDEX IF NOT EXISTS short_links_word_idx ON short_links (word)`)
+	if err != nil {
+		return errors.Wrap(err, "failed to create unique index on word column of short_links table")
+	}
+
+	log.Info().Msg("unique index created on word column of short_links table")
+
+	_, err = db.ExecContext(ctx.Context, `CREATE TABLE IF NOT EXISTS short_link_clicks (
+		link_id INTEGER NOT NULL,
+		date DATE NOT NULL,
+		country TEXT NOT NULL DEFAULT '',
+		clicks INTEGER NOT NULL DEFAULT 0
+	)`)
+	if err != nil {
+		return errors.Wrap(err, "failed to create short_link_clicks table")
+	}
+
+	log.Info().Msg("short_link_clicks table created")
+
+	_, err = db.ExecContext(ctx.Context, `CREATE UNIQUE INDEX IF NOT EXISTS short_link_clicks_link_id_date_country_idx ON short_link_clicks (link_id, date, country)`)
+	if err != nil {
+		return errors.Wrap(err, "failed to create unique index on (link_id, date, country) columns of short_link_clicks table")
+	}
+
+	log.Info().Msg("unique index created on (link_id, date, country) columns of short_link_clicks table")
+
+	log.Info().Msg("script finished")
+
+	return nil
+}
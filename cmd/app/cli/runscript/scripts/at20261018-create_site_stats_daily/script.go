@@ -0,0 +1,42 @@
+package script_create_site_stats_daily
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+)
+
+func run(ctx *cli.Context, deps CommandDeps) error {
+	log.Info().Msg("running script")
+
+	db := deps.DB
+
+	_, err := db.ExecContext(ctx.Context, `CREATE TABLE IF NOT EXISTS site_stats_daily (
+		stats_id SERIAL PRIMARY KEY,
+		server TEXT NOT NULL,
+		day_num INTEGER NOT NULL,
+		start_time TIMESTAMPTZ NOT NULL,
+		unique_accounts INTEGER NOT NULL DEFAULT 0,
+		reports INTEGER NOT NULL DEFAULT 0,
+		times INTEGER NOT NULL DEFAULT 0,
+		sources JSONB NULL,
+		rejections JSONB NULL,
+		updated_at TIMESTAMPTZ NULL
+	)`)
+	if err != nil {
+		return errors.Wrap(err, "failed to create site_stats_daily table")
+	}
+
+	log.Info().Msg("site_stats_daily table created")
+
+	_, err = db.ExecContext(ctx.Context, `CREATE UNIQUE INDEX IF NOT EXISTS site_stats_daily_server_day_num_idx ON site_stats_daily (server, day_num)`)
+	if err != nil {
+		return errors.Wrap(err, "failed to create unique index on (server, day_num) columns of site_stats_daily table")
+	}
+
+	log.Info().Msg("unique index created on (server, day_num) columns of site_stats_daily table")
+
+	log.Info().Msg("script finished")
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package script_restore_drop_reports
+
+import (
+	"github.com/urfave/cli/v2"
+	"go.uber.org/fx"
+
+	"exusiai.dev/backend-next/internal/service"
+)
+
+type CommandDeps struct {
+	fx.In
+
+	ArchiveService *service.Archive
+}
+
+func Command(depsFn func() CommandDeps) *cli.Command {
+	return &cli.Command{
+		Name:        "restore_drop_reports",
+		Description: "restore one day's archived drop reports from the archive storage; already existing reports are skipped",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "date",
+				Aliases:  []string{"d"},
+				Usage:    "date to restore in GMT+8, in format of YYYY-MM-DD",
+				Required: true,
+			},
+		},
+		Action: func(ctx *cli.Context) error {
+			date := ctx.String("date")
+			return run(ctx, depsFn(), date)
+		},
+	}
+}
@@ -0,0 +1,32 @@
+package script_restore_drop_reports
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+)
+
+func run(ctx *cli.Context, deps CommandDeps, dateStr string) error {
+	log.Info().Str("date", dateStr).Msg("running script")
+
+	date, err := time.Parse("2006-01-02", dateStr)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse date")
+	}
+
+	result, err := deps.ArchiveService.RestoreByDate(ctx.Context, date)
+	if err != nil {
+		return errors.Wrap(err, "failed to run restoreDropReports")
+	}
+
+	log.Info().
+		Int("drop_reports_archived", result.DropReports.Archived).
+		Int64("drop_reports_restored", result.DropReports.Restored).
+		Int("drop_report_extras_archived", result.DropReportExtras.Archived).
+		Int64("drop_report_extras_restored", result.DropReportExtras.Restored).
+		Msg("script finished")
+
+	return nil
+}
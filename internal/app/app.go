@@ -58,6 +58,7 @@ func Options(ctx appcontext.Ctx, additionalOpts ...fx.Option) []fx.Option {
 		// are called in the order of their registration.
 		fx.Invoke(infra.SentryInit),
 		fx.Invoke(cache.Initialize),
+		fx.Invoke(infra.CacheInvalidationBusInit),
 
 		// Controllers
 		controller.Module(controller.OptIncludeSwagger),
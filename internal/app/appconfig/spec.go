@@ -54,6 +54,15 @@ type ConfigSpec struct {
 	// for more information on how to construct a NATS URL.
 	NatsURL string `required:"true" split_words:"true" default:"nats://127.0.0.1:4222"`
 
+	// CacheInvalidationBusEnabled is a flag to indicate whether deletions and flushes of the process-local caches
+	// are broadcast to, and applied from, the other instances over NATS, so that admin purges and worker updates
+	// take effect on every replica rather than only the one which handled them.
+	CacheInvalidationBusEnabled bool `split_words:"true" default:"true"`
+
+	// CacheInvalidationSubject is the NATS subject cache invalidations are broadcast on. Instances only evict
+	// each other's caches if they share the subject.
+	CacheInvalidationSubject string `split_words:"true" default:"CACHE.INVALIDATION"`
+
 	// RedisURL is the URL of the Redis server, and by default uses redis db 1, to avoid potential collision
 	// with the previous running backend instance. See https://pkg.go.dev/github.com/redis/go-redis/v9#ParseURL
 	// for more information on how to construct a Redis URL.
@@ -121,17 +130,111 @@ type ConfigSpec struct {
 	// Available categories are: all, automated, manual.
 	MatrixWorkerSourceCategories []string `required:"true" split_words:"true" default:"all"`
 
+	// MatrixIncrementalEnabled is a flag to indicate whether the report worker maintains the daily drop and pattern
+	// matrix elements as reports are committed, recalled or have their reliability changed. The matrix jobs then
+	// reconcile today's elements against the drop reports, rather than recalculating and replacing all of them.
+	MatrixIncrementalEnabled bool `split_words:"true" default:"true"`
+
+	// MatrixIncrementalPurgeDelay is the delay before the global matrix caches are purged after the elements have
+	// been updated incrementally, so that a burst of reports only purges the caches once.
+	MatrixIncrementalPurgeDelay time.Duration `split_words:"true" default:"5s"`
+
 	// For PatternMatrix query api, if showAllPatterns is false, then only show the top 50 patterns for all stages
 	// We don't want to show all patterns because it will be too many. So we set a limit here (default 19)
 	PatternMatrixLimit int `split_words:"true" default:"19"`
 
+	// ReportRateAccountWindow and ReportRateAccountLimit describe the sliding window used to limit the amount of
+	// runs (report times) a single account could submit before its reports are marked with a lowered reliability.
+	ReportRateAccountWindow time.Duration `split_words:"true" default:"1h"`
+	ReportRateAccountLimit  int           `split_words:"true" default:"600"`
+
+	// ReportRateIPWindow and ReportRateIPLimit are the same as ReportRateAccount*, but are applied per IP.
+	// IPs are limited more loosely than accounts as a single IP could be shared by many users (e.g. NAT).
+	ReportRateIPWindow time.Duration `split_words:"true" default:"1h"`
+	ReportRateIPLimit  int           `split_words:"true" default:"3000"`
+
+	// ReportAnomalyWindow is the window an account's sequence of drops in a stage is accumulated over, to be
+	// compared with the current drop matrix of that stage.
+	ReportAnomalyWindow time.Duration `split_words:"true" default:"24h"`
+
+	// ReportAnomalyMinRuns is the minimum amount of runs required, both in the account's sequence and in the
+	// drop matrix, before the sequence is scored.
+	ReportAnomalyMinRuns int `split_words:"true" default:"30"`
+
+	// ReportAnomalyZScoreThreshold is the z-score above which an account's sequence of drops is considered
+	// statistically implausible.
+	ReportAnomalyZScoreThreshold float64 `split_words:"true" default:"6"`
+
+	// ReportWorkerMaxDeliver is the amount of times a report task is delivered to the report worker before it is
+	// moved to the dead letter stream, where admins could inspect, replay or discard it.
+	ReportWorkerMaxDeliver int `split_words:"true" default:"5"`
+
+	// ReportWorkerRetryBackoff is the delay before a failed report task is redelivered for the first time. The
+	// delay doubles on every subsequent failure, up to ReportWorkerRetryMaxBackoff.
+	ReportWorkerRetryBackoff    time.Duration `split_words:"true" default:"5s"`
+	ReportWorkerRetryMaxBackoff time.Duration `split_words:"true" default:"5m"`
+
+	// ReportDeadLetterMaxAge is the duration dead-lettered report tasks are kept for. 0 keeps them forever.
+	ReportDeadLetterMaxAge time.Duration `split_words:"true" default:"720h"`
+
+	// AccountWeightMin and AccountWeightMax bound the weights assigned to accounts by the weight recalculation.
+	// Accounts whose weight has never been set count as 1 in weighted aggregations, so AccountWeightMin is
+	// usually kept at 1 for new accounts not to count more than the least trusted evaluated ones.
+	AccountWeightMin float64 `split_words:"true" default:"1"`
+	AccountWeightMax float64 `split_words:"true" default:"5"`
+
+	// AccountWeightLookbackWindow is the window of reliable reports accounts are evaluated over by the weight
+	// recalculation.
+	AccountWeightLookbackWindow time.Duration `split_words:"true" default:"720h"`
+
+	// AccountWeightMinReports is the minimum amount of reliable reports an account needs within the lookback
+	// window to be evaluated. Weights of other accounts are left untouched.
+	AccountWeightMinReports int `split_words:"true" default:"20"`
+
+	// AccountWeightFullReports is the amount of reliable reports within the lookback window at which an account
+	// is considered fully experienced. Below that, its weight grows logarithmically with its reports.
+	AccountWeightFullReports int `split_words:"true" default:"1000"`
+
+	// DropDriftTScoreThreshold is the t-score above which the drop rate of an item in the current time range of a
+	// stage is considered to have drifted from the previous time range. As thousands of stage/item pairs are
+	// compared in every run, it is set much higher than the usual 95% or 99% thresholds.
+	DropDriftTScoreThreshold float64 `split_words:"true" default:"4"`
+
+	// DropDriftMinTimes is the minimum amount of runs required in both time ranges before they are compared.
+	DropDriftMinTimes int `split_words:"true" default:"300"`
+
+	// DropReportCountryAttributionEnabled is a flag to indicate whether the report worker resolves the country of
+	// the IP of every report, which is the base of the region filter of matrix and advanced queries.
+	DropReportCountryAttributionEnabled bool `split_words:"true" default:"false"`
+
+	// DropReportIPRetentionDays is the amount of days raw IPs of reports are kept for, after which they are erased
+	// by the stats worker. The resolved countries are kept. 0 keeps IPs forever.
+	DropReportIPRetentionDays int `split_words:"true" default:"0"`
+
 	DropReportArchiveEnabled   bool `split_words:"true" default:"false"`
 	DropReportArchiveBatchSize int  `split_words:"true" default:"1000"`
 
-	DropReportArchiveS3Bucket string `required:"true" split_words:"true"`
-	DropReportArchiveS3Region string `required:"true" split_words:"true"`
-	AWSAccessKey              string `required:"true" split_words:"true"`
-	AWSSecretKey              string `required:"true" split_words:"true"`
+	// DropReportArchiveFormats is the list of formats drop reports are archived into.
+	// Available formats are: jsonl (gzipped JSON lines), parquet.
+	DropReportArchiveFormats []string `split_words:"true" default:"jsonl"`
+
+	// DropReportArchiveStorage is the storage backend archives are stored in. Available backends are:
+	//   - s3: AWS S3, configured by DropReportArchiveS3Bucket, DropReportArchiveS3Region and the AWS credentials
+	//   - s3compatible: S3-compatible services such as MinIO, additionally configured by DropReportArchiveS3Endpoint
+	//   - local: a directory on the local filesystem, configured by DropReportArchiveLocalDir
+	// The backend configuration is only required when DropReportArchiveEnabled is true; otherwise the archive
+	// service fails on use instead of on startup.
+	DropReportArchiveStorage string `split_words:"true" default:"s3"`
+
+	DropReportArchiveS3Bucket string `split_words:"true"`
+	DropReportArchiveS3Region string `split_words:"true"`
+	// DropReportArchiveS3Endpoint is the endpoint of the S3-compatible service, e.g. http://localhost:9000
+	DropReportArchiveS3Endpoint string `split_words:"true"`
+	// AWSAccessKey and AWSSecretKey are optional: the default AWS credential chain is used when left empty.
+	AWSAccessKey string `split_words:"true"`
+	AWSSecretKey string `split_words:"true"`
+
+	DropReportArchiveLocalDir string `split_words:"true"`
 
 	NoArchiveDays int `split_words:"true" default:"60"`
 
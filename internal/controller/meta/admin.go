@@ -26,11 +26,13 @@ import (
 	"exusiai.dev/backend-next/internal/model/cache"
 	"exusiai.dev/backend-next/internal/model/gamedata"
 	"exusiai.dev/backend-next/internal/model/types"
+	"exusiai.dev/backend-next/internal/pkg/archiver"
 	"exusiai.dev/backend-next/internal/pkg/flog"
 	"exusiai.dev/backend-next/internal/pkg/pgerr"
 	"exusiai.dev/backend-next/internal/repo"
 	"exusiai.dev/backend-next/internal/server/svr"
 	"exusiai.dev/backend-next/internal/service"
+	"exusiai.dev/backend-next/internal/util"
 	"exusiai.dev/backend-next/internal/util/rekuest"
 )
 
@@ -59,6 +61,14 @@ type AdminController struct {
 	ExportService            *service.Export
 	AccountService           *service.Account
 	ArchiveService           *service.Archive
+	RejectRuleService        *service.RejectRule
+	DriftService             *service.Drift
+	ShortLinkService         *service.ShortLink
+	NoticeService            *service.Notice
+	ReportDeadLetterService  *service.ReportDeadLetter
+	MatrixDeltaService       *service.MatrixDelta
+	AccountWeightService     *service.AccountWeight
+	ReportTaskStatusService  *service.ReportTaskStatus
 }
 
 func RegisterAdmin(admin *svr.Admin, c AdminController) {
@@ -68,9 +78,49 @@ func RegisterAdmin(admin *svr.Admin, c AdminController) {
 
 	admin.Post("/clone", c.CloneFromCN)
 
+	admin.Put("/gamedata/zones/:id", c.UpdateZone)
+	admin.Delete("/gamedata/zones/:id", c.DeleteZone)
+	admin.Put("/gamedata/stages/:id", c.UpdateStage)
+	admin.Delete("/gamedata/stages/:id", c.DeleteStage)
+	admin.Put("/gamedata/time-ranges/:id", c.UpdateTimeRange)
+	admin.Delete("/gamedata/time-ranges/:id", c.DeleteTimeRange)
+	admin.Put("/gamedata/drop-infos/:id", c.UpdateDropInfo)
+	admin.Delete("/gamedata/drop-infos/:id", c.DeleteDropInfo)
+	admin.Get("/gamedata/audit-logs", c.GetAdminAuditLogs)
+
+	admin.Get("/rejections/reject-rules", c.GetRejectRules)
+	admin.Post("/rejections/reject-rules", c.CreateRejectRule)
+	admin.Post("/rejections/reject-rules/dry-run", c.DryRunRejectRule)
+	admin.Get("/rejections/reject-rules/:ruleId", c.GetRejectRule)
+	admin.Put("/rejections/reject-rules/:ruleId", c.UpdateRejectRule)
+	admin.Delete("/rejections/reject-rules/:ruleId", c.DisableRejectRule)
+	admin.Post("/rejections/reject-rules/:ruleId/activate", c.ActivateRejectRule)
+	admin.Get("/rejections/reject-rules/:ruleId/revisions", c.GetRejectRuleRevisions)
 	admin.Post("/rejections/reject-rules/reevaluation/preview", c.RejectRulesReevaluationPreview)
 	admin.Post("/rejections/reject-rules/reevaluation/apply", c.RejectRulesReevaluationApply)
 
+	admin.Get("/short-links", c.GetShortLinks)
+	admin.Post("/short-links", c.CreateShortLink)
+	admin.Get("/short-links/:linkId", c.GetShortLink)
+	admin.Put("/short-links/:linkId", c.UpdateShortLink)
+	admin.Delete("/short-links/:linkId", c.DeleteShortLink)
+	admin.Get("/short-links/:linkId/clicks", c.GetShortLinkClicks)
+
+	admin.Get("/notices", c.GetScheduledNotices)
+	admin.Post("/notices", c.CreateScheduledNotice)
+	admin.Get("/notices/:noticeId", c.GetScheduledNotice)
+	admin.Put("/notices/:noticeId", c.UpdateScheduledNotice)
+	admin.Delete("/notices/:noticeId", c.DeleteScheduledNotice)
+
+	admin.Get("/reports/tasks/:taskId", c.GetReportTaskStatus)
+	admin.Get("/reports/dead-letters", c.GetReportDeadLetters)
+	admin.Get("/reports/dead-letters/:seq", c.GetReportDeadLetter)
+	admin.Post("/reports/dead-letters/:seq/replay", c.ReplayReportDeadLetter)
+	admin.Delete("/reports/dead-letters/:seq", c.DiscardReportDeadLetter)
+
+	admin.Put("/accounts/:accountId/weight", c.UpdateAccountWeight)
+	admin.Post("/accounts/weights/recalculate", c.RecalculateAccountWeights)
+
 	admin.Get("/cli/gamedata/seed", c.GetCliGameDataSeed)
 	admin.Get("/internal/time-faked/stages", c.GetFakeTimeStages)
 	admin.Get("/_temp/pattern/merging", c.FindPatterns)
@@ -81,6 +131,9 @@ func RegisterAdmin(admin *svr.Admin, c AdminController) {
 	admin.Post("/refresh/matrix", c.CalcDropMatrixElements)
 	admin.Post("/refresh/pattern", c.CalcPatternMatrixElements)
 	admin.Get("/refresh/sitestats/:server", c.RefreshAllSiteStats)
+	admin.Post("/refresh/drift/:server", c.RefreshDropDrift)
+
+	admin.Get("/drift/:server", c.GetDropDrift)
 
 	admin.Get("/recognition/defects", c.GetRecognitionDefects)
 	admin.Get("/recognition/defects/:defectId", c.GetRecognitionDefect)
@@ -91,6 +144,7 @@ func RegisterAdmin(admin *svr.Admin, c AdminController) {
 	admin.Post("/snapshots", c.CreateSnapshot)
 
 	admin.Post("/archive", c.ArchiveDropReports)
+	admin.Post("/archive/restore", c.RestoreDropReports)
 }
 
 type CliGameDataSeedResponse struct {
@@ -341,6 +395,35 @@ func (c *AdminController) RefreshAllSiteStats(ctx *fiber.Ctx) error {
 	return err
 }
 
+// GetDropDrift returns the stage/item pairs whose drop rate drifted significantly from the previous time range.
+//
+// Query params:
+//   - category: source category; defaults to "all"
+func (c *AdminController) GetDropDrift(ctx *fiber.Ctx) error {
+	server := ctx.Params("server")
+	if err := rekuest.ValidServer(ctx, server); err != nil {
+		return err
+	}
+	category := ctx.Query("category", constant.SourceCategoryAll)
+	if err := rekuest.ValidCategory(ctx, category); err != nil {
+		return err
+	}
+
+	report, err := c.DriftService.GetDropDriftReport(ctx.UserContext(), server, category)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(report)
+}
+
+func (c *AdminController) RefreshDropDrift(ctx *fiber.Ctx) error {
+	server := ctx.Params("server")
+	if err := rekuest.ValidServer(ctx, server); err != nil {
+		return err
+	}
+	return c.DriftService.RunDropDriftJob(ctx.UserContext(), server)
+}
+
 type RecognitionDefectsResponseImage struct {
 	Original  string `json:"original,omitempty"`
 	Thumbnail string `json:"thumbnail,omitempty"`
@@ -432,13 +515,524 @@ func (c *AdminController) GetRecognitionDefect(ctx *fiber.Ctx) error {
 	return ctx.JSON(response)
 }
 
+func parseRejectRuleID(ctx *fiber.Ctx) (int, error) {
+	ruleId, err := strconv.Atoi(ctx.Params("ruleId"))
+	if err != nil || ruleId <= 0 {
+		return 0, pgerr.ErrInvalidReq.Msg("invalid rule id")
+	}
+	return ruleId, nil
+}
+
+type rejectRuleSaveResponse struct {
+	Rule     *model.RejectRule               `json:"rule"`
+	Revision *model.RejectRuleRevision       `json:"revision"`
+	DryRun   *service.RejectRuleDryRunResult `json:"dryRun"`
+}
+
+func (c *AdminController) GetRejectRules(ctx *fiber.Ctx) error {
+	rejectRules, err := c.RejectRuleService.GetAllRejectRules(ctx.UserContext())
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(rejectRules)
+}
+
+func (c *AdminController) GetRejectRule(ctx *fiber.Ctx) error {
+	ruleId, err := parseRejectRuleID(ctx)
+	if err != nil {
+		return err
+	}
+
+	rejectRule, err := c.RejectRuleService.GetRejectRule(ctx.UserContext(), ruleId)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(rejectRule)
+}
+
+func (c *AdminController) GetRejectRuleRevisions(ctx *fiber.Ctx) error {
+	ruleId, err := parseRejectRuleID(ctx)
+	if err != nil {
+		return err
+	}
+
+	revisions, err := c.RejectRuleService.GetRejectRuleRevisions(ctx.UserContext(), ruleId)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(revisions)
+}
+
+func (c *AdminController) DryRunRejectRule(ctx *fiber.Ctx) error {
+	var request types.RejectRuleDryRunRequest
+	if err := rekuest.ValidBody(ctx, &request); err != nil {
+		return err
+	}
+
+	result, err := c.RejectRuleService.DryRunRejectRuleExpr(ctx.UserContext(), request.Expr, request.DryRunHours)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(result)
+}
+
+func (c *AdminController) CreateRejectRule(ctx *fiber.Ctx) error {
+	var request types.RejectRuleSaveRequest
+	if err := rekuest.ValidBody(ctx, &request); err != nil {
+		return err
+	}
+
+	return c.saveRejectRule(ctx, &model.RejectRule{}, &request)
+}
+
+func (c *AdminController) UpdateRejectRule(ctx *fiber.Ctx) error {
+	ruleId, err := parseRejectRuleID(ctx)
+	if err != nil {
+		return err
+	}
+
+	var request types.RejectRuleSaveRequest
+	if err := rekuest.ValidBody(ctx, &request); err != nil {
+		return err
+	}
+
+	rejectRule, err := c.RejectRuleService.GetRejectRule(ctx.UserContext(), ruleId)
+	if err != nil {
+		return err
+	}
+
+	return c.saveRejectRule(ctx, rejectRule, &request)
+}
+
+// saveRejectRule dry-runs the requested expression against recent reports before saving it,
+// so that expressions which fail to evaluate on real reports never get persisted. Changed rules
+// are saved as inactive; see ActivateRejectRule.
+func (c *AdminController) saveRejectRule(ctx *fiber.Ctx, rejectRule *model.RejectRule, request *types.RejectRuleSaveRequest) error {
+	dryRun, err := c.RejectRuleService.DryRunRejectRuleExpr(ctx.UserContext(), request.Expr, request.DryRunHours)
+	if err != nil {
+		return err
+	}
+
+	rejectRule.Expr = request.Expr
+	rejectRule.WithReliability = request.WithReliability
+
+	revision, err := c.RejectRuleService.SaveRejectRule(ctx.UserContext(), rejectRule, dryRun)
+	if err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("evt.name", "admin.reject_rules.save").
+		Int("reject_rule.rule_id", rejectRule.RuleID).
+		Int("reject_rule.status", rejectRule.Status).
+		Int("reject_rule.revision", revision.Revision).
+		Int("dry_run.evaluated_count", dryRun.EvaluatedCount).
+		Int("dry_run.rejected_count", dryRun.RejectedCount).
+		Msg("reject rule saved")
+
+	return ctx.JSON(&rejectRuleSaveResponse{
+		Rule:     rejectRule,
+		Revision: revision,
+		DryRun:   dryRun,
+	})
+}
+
+// ActivateRejectRule activates a saved reject rule once the dry run result of its latest revision,
+// as returned by saving the rule, has been confirmed.
+func (c *AdminController) ActivateRejectRule(ctx *fiber.Ctx) error {
+	ruleId, err := parseRejectRuleID(ctx)
+	if err != nil {
+		return err
+	}
+
+	var request types.RejectRuleActivateRequest
+	if err := rekuest.ValidBody(ctx, &request); err != nil {
+		return err
+	}
+
+	rejectRule, err := c.RejectRuleService.ActivateRejectRule(ctx.UserContext(), ruleId, request.Revision, *request.DryRunRejectedCount)
+	if err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("evt.name", "admin.reject_rules.activate").
+		Int("reject_rule.rule_id", rejectRule.RuleID).
+		Int("reject_rule.revision", request.Revision).
+		Int("dry_run.rejected_count", *request.DryRunRejectedCount).
+		Msg("reject rule activated")
+
+	return ctx.JSON(rejectRule)
+}
+
+func (c *AdminController) DisableRejectRule(ctx *fiber.Ctx) error {
+	ruleId, err := parseRejectRuleID(ctx)
+	if err != nil {
+		return err
+	}
+
+	rejectRule, err := c.RejectRuleService.DisableRejectRule(ctx.UserContext(), ruleId)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(rejectRule)
+}
+
+func parseShortLinkID(ctx *fiber.Ctx) (int, error) {
+	linkId, err := strconv.Atoi(ctx.Params("linkId"))
+	if err != nil || linkId <= 0 {
+		return 0, pgerr.ErrInvalidReq.Msg("invalid link id")
+	}
+	return linkId, nil
+}
+
+func (c *AdminController) GetShortLinks(ctx *fiber.Ctx) error {
+	shortLinks, err := c.ShortLinkService.GetShortLinks(ctx.UserContext())
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(shortLinks)
+}
+
+func (c *AdminController) GetShortLink(ctx *fiber.Ctx) error {
+	linkId, err := parseShortLinkID(ctx)
+	if err != nil {
+		return err
+	}
+
+	shortLink, err := c.ShortLinkService.GetShortLink(ctx.UserContext(), linkId)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(shortLink)
+}
+
+func (c *AdminController) CreateShortLink(ctx *fiber.Ctx) error {
+	var request types.ShortLinkSaveRequest
+	if err := rekuest.ValidBody(ctx, &request); err != nil {
+		return err
+	}
+
+	return c.saveShortLink(ctx, &model.ShortLink{}, &request)
+}
+
+func (c *AdminController) UpdateShortLink(ctx *fiber.Ctx) error {
+	linkId, err := parseShortLinkID(ctx)
+	if err != nil {
+		return err
+	}
+
+	var request types.ShortLinkSaveRequest
+	if err := rekuest.ValidBody(ctx, &request); err != nil {
+		return err
+	}
+
+	shortLink, err := c.ShortLinkService.GetShortLink(ctx.UserContext(), linkId)
+	if err != nil {
+		return err
+	}
+
+	return c.saveShortLink(ctx, shortLink, &request)
+}
+
+func (c *AdminController) saveShortLink(ctx *fiber.Ctx, shortLink *model.ShortLink, request *types.ShortLinkSaveRequest) error {
+	shortLink.Word = request.Word
+	shortLink.Targets = request.Targets
+	shortLink.ExpiresAt = request.ExpiresAt
+	shortLink.Comment = request.Comment
+
+	if err := c.ShortLinkService.SaveShortLink(ctx.UserContext(), shortLink); err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("evt.name", "admin.short_links.save").
+		Int("short_link.link_id", shortLink.LinkID).
+		Str("short_link.word", shortLink.Word).
+		Msg("short link saved")
+
+	return ctx.JSON(shortLink)
+}
+
+func (c *AdminController) DeleteShortLink(ctx *fiber.Ctx) error {
+	linkId, err := parseShortLinkID(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := c.ShortLinkService.DeleteShortLink(ctx.UserContext(), linkId); err != nil {
+		return err
+	}
+
+	return ctx.SendStatus(http.StatusNoContent)
+}
+
+func (c *AdminController) GetShortLinkClicks(ctx *fiber.Ctx) error {
+	linkId, err := parseShortLinkID(ctx)
+	if err != nil {
+		return err
+	}
+
+	var request types.ShortLinkClicksRequest
+	if err := rekuest.ValidQuery(ctx, &request); err != nil {
+		return err
+	}
+
+	stats, err := c.ShortLinkService.GetShortLinkClickStats(ctx.UserContext(), linkId, request.Days)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(stats)
+}
+
+func parseNoticeID(ctx *fiber.Ctx) (int, error) {
+	noticeId, err := strconv.Atoi(ctx.Params("noticeId"))
+	if err != nil || noticeId <= 0 {
+		return 0, pgerr.ErrInvalidReq.Msg("invalid notice id")
+	}
+	return noticeId, nil
+}
+
+// GetScheduledNotices returns all scheduled notices, including disabled and expired ones.
+func (c *AdminController) GetScheduledNotices(ctx *fiber.Ctx) error {
+	notices, err := c.NoticeService.GetScheduledNotices(ctx.UserContext())
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(notices)
+}
+
+func (c *AdminController) GetScheduledNotice(ctx *fiber.Ctx) error {
+	noticeId, err := parseNoticeID(ctx)
+	if err != nil {
+		return err
+	}
+
+	notice, err := c.NoticeService.GetScheduledNotice(ctx.UserContext(), noticeId)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(notice)
+}
+
+func (c *AdminController) CreateScheduledNotice(ctx *fiber.Ctx) error {
+	var request types.ScheduledNoticeSaveRequest
+	if err := rekuest.ValidBody(ctx, &request); err != nil {
+		return err
+	}
+
+	return c.saveScheduledNotice(ctx, &model.ScheduledNotice{}, &request)
+}
+
+func (c *AdminController) UpdateScheduledNotice(ctx *fiber.Ctx) error {
+	noticeId, err := parseNoticeID(ctx)
+	if err != nil {
+		return err
+	}
+
+	var request types.ScheduledNoticeSaveRequest
+	if err := rekuest.ValidBody(ctx, &request); err != nil {
+		return err
+	}
+
+	notice, err := c.NoticeService.GetScheduledNotice(ctx.UserContext(), noticeId)
+	if err != nil {
+		return err
+	}
+
+	return c.saveScheduledNotice(ctx, notice, &request)
+}
+
+func (c *AdminController) saveScheduledNotice(ctx *fiber.Ctx, notice *model.ScheduledNotice, request *types.ScheduledNoticeSaveRequest) error {
+	notice.Severity = request.Severity
+	notice.Title = request.Title
+	notice.Content = request.Content
+	notice.StartTime = request.StartTime
+	notice.EndTime = request.EndTime
+	notice.Servers = request.Servers
+	notice.Variants = request.Variants
+	notice.Sources = request.Sources
+	notice.Enabled = request.Enabled
+
+	if err := c.NoticeService.SaveScheduledNotice(ctx.UserContext(), notice); err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("evt.name", "admin.notices.save").
+		Int("notice.notice_id", notice.NoticeID).
+		Bool("notice.enabled", notice.Enabled).
+		Msg("scheduled notice saved")
+
+	return ctx.JSON(notice)
+}
+
+func (c *AdminController) DeleteScheduledNotice(ctx *fiber.Ctx) error {
+	noticeId, err := parseNoticeID(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := c.NoticeService.DeleteScheduledNotice(ctx.UserContext(), noticeId); err != nil {
+		return err
+	}
+
+	return ctx.SendStatus(http.StatusNoContent)
+}
+
+// GetReportTaskStatus is the public GetReportTaskStatus including why verifiers lowered the reliability of reports.
+func (c *AdminController) GetReportTaskStatus(ctx *fiber.Ctx) error {
+	taskId := ctx.Params("taskId")
+	if err := rekuest.ValidVar(ctx, taskId, "required,max=128"); err != nil {
+		return err
+	}
+
+	status, err := c.ReportTaskStatusService.GetReportTaskStatus(ctx.UserContext(), taskId)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(status)
+}
+
+func parseDeadLetterSeq(ctx *fiber.Ctx) (uint64, error) {
+	seq, err := strconv.ParseUint(ctx.Params("seq"), 10, 64)
+	if err != nil || seq == 0 {
+		return 0, pgerr.ErrInvalidReq.Msg("invalid dead letter sequence")
+	}
+	return seq, nil
+}
+
+func (c *AdminController) GetReportDeadLetters(ctx *fiber.Ctx) error {
+	var request types.ReportDeadLettersRequest
+	if err := rekuest.ValidQuery(ctx, &request); err != nil {
+		return err
+	}
+
+	deadLetters, err := c.ReportDeadLetterService.GetDeadLetters(ctx.UserContext(), request.After, request.Limit)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(deadLetters)
+}
+
+func (c *AdminController) GetReportDeadLetter(ctx *fiber.Ctx) error {
+	seq, err := parseDeadLetterSeq(ctx)
+	if err != nil {
+		return err
+	}
+
+	deadLetter, err := c.ReportDeadLetterService.GetDeadLetter(ctx.UserContext(), seq)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(deadLetter)
+}
+
+func (c *AdminController) ReplayReportDeadLetter(ctx *fiber.Ctx) error {
+	seq, err := parseDeadLetterSeq(ctx)
+	if err != nil {
+		return err
+	}
+
+	deadLetter, err := c.ReportDeadLetterService.ReplayDeadLetter(ctx.UserContext(), seq)
+	if err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("evt.name", "admin.reports.dead_letters.replay").
+		Uint64("dead_letter.sequence", seq).
+		Str("dead_letter.task_id", deadLetter.Task.TaskID).
+		Msg("dead-lettered report task replayed")
+
+	return ctx.JSON(deadLetter)
+}
+
+func (c *AdminController) DiscardReportDeadLetter(ctx *fiber.Ctx) error {
+	seq, err := parseDeadLetterSeq(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := c.ReportDeadLetterService.DiscardDeadLetter(ctx.UserContext(), seq); err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("evt.name", "admin.reports.dead_letters.discard").
+		Uint64("dead_letter.sequence", seq).
+		Msg("dead-lettered report task discarded")
+
+	return ctx.SendStatus(http.StatusNoContent)
+}
+
+func (c *AdminController) UpdateAccountWeight(ctx *fiber.Ctx) error {
+	accountId, err := strconv.Atoi(ctx.Params("accountId"))
+	if err != nil || accountId <= 0 {
+		return pgerr.ErrInvalidReq.Msg("invalid account id")
+	}
+
+	var request types.AccountWeightUpdateRequest
+	if err := rekuest.ValidBody(ctx, &request); err != nil {
+		return err
+	}
+
+	account, err := c.AccountWeightService.UpdateAccountWeight(ctx.UserContext(), accountId, request.Weight)
+	if err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("evt.name", "admin.accounts.weight.update").
+		Int("account.account_id", accountId).
+		Float64("account.weight", request.Weight).
+		Msg("account weight updated")
+
+	return ctx.JSON(account)
+}
+
+func (c *AdminController) RecalculateAccountWeights(ctx *fiber.Ctx) error {
+	var request types.AccountWeightsRecalculateRequest
+	if err := rekuest.ValidBody(ctx, &request); err != nil {
+		return err
+	}
+
+	recalculation, err := c.AccountWeightService.RecalculateAccountWeights(ctx.UserContext(), request.DryRun)
+	if err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("evt.name", "admin.accounts.weights.recalculate").
+		Bool("dryRun", request.DryRun).
+		Int("evaluated", recalculation.Evaluated).
+		Int("skipped", recalculation.Skipped).
+		Float64("meanWeight", recalculation.MeanWeight).
+		Msg("account weights recalculated")
+
+	return ctx.JSON(recalculation)
+}
+
 func (c *AdminController) RejectRulesReevaluationPreview(ctx *fiber.Ctx) error {
 	var request types.RejectRulesReevaluationPreviewRequest
 	if err := rekuest.ValidBody(ctx, &request); err != nil {
 		return err
 	}
 
-	evalContexts, err := c.AdminService.GetRejectRulesReportContext(ctx.UserContext(), request)
+	evalContexts, err := c.AdminService.GetRejectRulesReportContext(ctx.UserContext(), request, 0)
 	if err != nil {
 		return err
 	}
@@ -463,7 +1057,7 @@ func (c *AdminController) RejectRulesReevaluationApply(ctx *fiber.Ctx) error {
 		return err
 	}
 
-	evalContexts, err := c.AdminService.GetRejectRulesReportContext(ctx.UserContext(), request)
+	evalContexts, err := c.AdminService.GetRejectRulesReportContext(ctx.UserContext(), request, 0)
 	if err != nil {
 		return err
 	}
@@ -474,6 +1068,7 @@ func (c *AdminController) RejectRulesReevaluationApply(ctx *fiber.Ctx) error {
 	}
 
 	changeSet := evaluation.ChangeSet()
+	matrixServers := make([]string, 0)
 
 	err = c.DB.RunInTx(ctx.UserContext(), nil, func(ictx context.Context, tx bun.Tx) error {
 		chunks := lo.Chunk(changeSet, 100)
@@ -489,6 +1084,15 @@ func (c *AdminController) RejectRulesReevaluationApply(ctx *fiber.Ctx) error {
 					Reliability: change.ToReliability,
 				}
 			})
+			reliabilities := lo.SliceToMap(changeChunk, func(change *service.RejectRulesReevaluationEvaluationResultSetDiff) (int, int) {
+				return change.ReportID, change.ToReliability
+			})
+
+			// lock the reports and read their current reliabilities, which the matrix elements are updated from
+			reports, err := c.DropReportRepo.GetDropReportsForUpdate(ictx, tx, lo.Keys(reliabilities))
+			if err != nil {
+				return err
+			}
 
 			if _, err := tx.NewUpdate().
 				With("_data", tx.NewValues(&data)).
@@ -504,6 +1108,12 @@ func (c *AdminController) RejectRulesReevaluationApply(ctx *fiber.Ctx) error {
 
 				return err
 			}
+
+			servers, err := c.MatrixDeltaService.ApplyReliabilityChanges(ictx, tx, reports, reliabilities)
+			if err != nil {
+				return err
+			}
+			matrixServers = lo.Union(matrixServers, servers)
 		}
 
 		return nil
@@ -514,6 +1124,7 @@ func (c *AdminController) RejectRulesReevaluationApply(ctx *fiber.Ctx) error {
 			fmt.Sprintf("failed to apply reevaluation: %s; all changes have been rolled back", err.Error()),
 		)
 	}
+	c.MatrixDeltaService.SchedulePurge(matrixServers...)
 
 	type rejectRulesReevaluationApplyResponse struct {
 		Summary service.RejectRulesReevaluationEvaluationResultSetSummary `json:"summary"`
@@ -721,7 +1332,11 @@ func (c *AdminController) ArchiveDropReports(ctx *fiber.Ctx) error {
 		return ctx.Status(fiber.StatusBadRequest).SendString("invalid date")
 	}
 
-	err = c.ArchiveService.ArchiveByDate(ctx.UserContext(), date, request.DeleteAfterArchive)
+	if len(request.Formats) > 0 {
+		err = c.ArchiveService.ArchiveByDateWithFormats(ctx.UserContext(), date, request.DeleteAfterArchive, request.Formats)
+	} else {
+		err = c.ArchiveService.ArchiveByDate(ctx.UserContext(), date, request.DeleteAfterArchive)
+	}
 	if err != nil {
 		flog.ErrorFrom(ctx, "archive.drop_report").
 			Err(err).
@@ -732,3 +1347,167 @@ func (c *AdminController) ArchiveDropReports(ctx *fiber.Ctx) error {
 	}
 	return ctx.SendStatus(fiber.StatusOK)
 }
+
+func (c *AdminController) RestoreDropReports(ctx *fiber.Ctx) error {
+	var request types.RestoreDropReportRequest
+	if err := rekuest.ValidBody(ctx, &request); err != nil {
+		return err
+	}
+
+	date, err := time.Parse("2006-01-02", request.Date)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).SendString("invalid date")
+	}
+
+	result, err := c.ArchiveService.RestoreByDate(ctx.UserContext(), date)
+	if err != nil {
+		if errors.Is(err, archiver.ErrFileNotFound) {
+			return pgerr.ErrNotFound.Msg("archive of %s not found", request.Date)
+		}
+		if errors.Is(err, service.ErrArchiveRestoreVerification) {
+			return pgerr.ErrInvalidReq.Msg("%s", err)
+		}
+
+		flog.ErrorFrom(ctx, "archive.restore").
+			Err(err).
+			Time("targetDay", date).
+			Msg("failed to restore drop reports")
+
+		return err
+	}
+	return ctx.JSON(result)
+}
+
+func parseGameDataID(ctx *fiber.Ctx) (int, error) {
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil || id <= 0 {
+		return 0, pgerr.ErrInvalidReq.Msg("invalid id")
+	}
+	return id, nil
+}
+
+// UpdateZone replaces a zone with the request body, which is in the same form as the zone of SaveRenderedObjects.
+func (c *AdminController) UpdateZone(ctx *fiber.Ctx) error {
+	zoneId, err := parseGameDataID(ctx)
+	if err != nil {
+		return err
+	}
+	var zone model.Zone
+	if err := rekuest.ValidBody(ctx, &zone); err != nil {
+		return err
+	}
+
+	if err := c.AdminService.UpdateZone(ctx.UserContext(), zoneId, &zone, util.ExtractIP(ctx)); err != nil {
+		return err
+	}
+	return ctx.JSON(zone)
+}
+
+func (c *AdminController) DeleteZone(ctx *fiber.Ctx) error {
+	zoneId, err := parseGameDataID(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := c.AdminService.DeleteZone(ctx.UserContext(), zoneId, util.ExtractIP(ctx)); err != nil {
+		return err
+	}
+	return ctx.SendStatus(http.StatusNoContent)
+}
+
+func (c *AdminController) UpdateStage(ctx *fiber.Ctx) error {
+	stageId, err := parseGameDataID(ctx)
+	if err != nil {
+		return err
+	}
+	var stage model.Stage
+	if err := rekuest.ValidBody(ctx, &stage); err != nil {
+		return err
+	}
+
+	if err := c.AdminService.UpdateStage(ctx.UserContext(), stageId, &stage, util.ExtractIP(ctx)); err != nil {
+		return err
+	}
+	return ctx.JSON(stage)
+}
+
+func (c *AdminController) DeleteStage(ctx *fiber.Ctx) error {
+	stageId, err := parseGameDataID(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := c.AdminService.DeleteStage(ctx.UserContext(), stageId, util.ExtractIP(ctx)); err != nil {
+		return err
+	}
+	return ctx.SendStatus(http.StatusNoContent)
+}
+
+func (c *AdminController) UpdateTimeRange(ctx *fiber.Ctx) error {
+	rangeId, err := parseGameDataID(ctx)
+	if err != nil {
+		return err
+	}
+	var timeRange model.TimeRange
+	if err := rekuest.ValidBody(ctx, &timeRange); err != nil {
+		return err
+	}
+
+	if err := c.AdminService.UpdateTimeRange(ctx.UserContext(), rangeId, &timeRange, util.ExtractIP(ctx)); err != nil {
+		return err
+	}
+	return ctx.JSON(timeRange)
+}
+
+func (c *AdminController) DeleteTimeRange(ctx *fiber.Ctx) error {
+	rangeId, err := parseGameDataID(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := c.AdminService.DeleteTimeRange(ctx.UserContext(), rangeId, util.ExtractIP(ctx)); err != nil {
+		return err
+	}
+	return ctx.SendStatus(http.StatusNoContent)
+}
+
+func (c *AdminController) UpdateDropInfo(ctx *fiber.Ctx) error {
+	dropId, err := parseGameDataID(ctx)
+	if err != nil {
+		return err
+	}
+	var dropInfo model.DropInfo
+	if err := rekuest.ValidBody(ctx, &dropInfo); err != nil {
+		return err
+	}
+
+	if err := c.AdminService.UpdateDropInfo(ctx.UserContext(), dropId, &dropInfo, util.ExtractIP(ctx)); err != nil {
+		return err
+	}
+	return ctx.JSON(dropInfo)
+}
+
+func (c *AdminController) DeleteDropInfo(ctx *fiber.Ctx) error {
+	dropId, err := parseGameDataID(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := c.AdminService.DeleteDropInfo(ctx.UserContext(), dropId, util.ExtractIP(ctx)); err != nil {
+		return err
+	}
+	return ctx.SendStatus(http.StatusNoContent)
+}
+
+func (c *AdminController) GetAdminAuditLogs(ctx *fiber.Ctx) error {
+	var request types.AdminAuditLogsRequest
+	if err := rekuest.ValidQuery(ctx, &request); err != nil {
+		return err
+	}
+
+	auditLogs, err := c.AdminService.GetAdminAuditLogs(ctx.UserContext(), request.Entity, request.EntityID, request.Limit)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(auditLogs)
+}
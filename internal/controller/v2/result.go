@@ -22,12 +22,17 @@ import (
 	"exusiai.dev/backend-next/internal/pkg/pgerr"
 	"exusiai.dev/backend-next/internal/server/svr"
 	"exusiai.dev/backend-next/internal/service"
+	"exusiai.dev/backend-next/internal/util"
 	"exusiai.dev/backend-next/internal/util/rekuest"
 )
 
 // ErrIntervalLengthTooSmall is returned when the interval length is invalid
 var ErrIntervalLengthTooSmall = pgerr.ErrInvalidReq.Msg("interval length must be greater than 1 hour")
 
+// ErrConfidenceWithWeighted is returned when confidence intervals are requested for a weighted drop matrix, whose
+// weighted times and quantities are not sample sizes the intervals could be calculated from
+var ErrConfidenceWithWeighted = pgerr.ErrInvalidReq.Msg("confidence intervals are not available for weighted drop matrices")
+
 type Result struct {
 	fx.In
 
@@ -44,32 +49,19 @@ func RegisterResult(v2 *svr.V2, c Result) {
 
 	// Cache requests with itemFilter and stageFilter as there appears to be an unknown source requesting
 	// with such behaviors very eagerly, causing a relatively high load on the database.
-	log.Info().Msg("enabling fiber-level cache & limiter for requests under /result group which contain itemFilter or stageFilter query params.")
+	// Requests with region or weighted are also cached, as such matrices are calculated from drop reports on request.
+	log.Info().Msg("enabling fiber-level cache & limiter for requests under /result group which contain itemFilter, stageFilter, region or weighted query params.")
 
-	group.Use(limiter.New(limiter.Config{
-		Next: func(c *fiber.Ctx) bool {
-			if c.Query("itemFilter") != "" || c.Query("stageFilter") != "" {
-				return false
-			}
-			return true
-		},
-		LimitReached: func(c *fiber.Ctx) error {
-			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
-				"code":    "TOO_MANY_REQUESTS",
-				"message": "Your client is sending requests too frequently. The Penguin Stats result matrix are updated periodically and should not be requested too frequently.",
-			})
-		},
-		Max:        300,
-		Expiration: time.Minute * 5,
+	group.Use(middlewares.MatrixLimiter(func(c *fiber.Ctx) bool {
+		return !isExpensiveResultQuery(c)
 	}))
 
 	group.Use(cachemiddleware.New(cachemiddleware.Config{
 		Next: func(c *fiber.Ctx) bool {
-			// only cache requests with itemFilter and stageFilter query params
-			if c.Query("itemFilter") != "" || c.Query("stageFilter") != "" {
-				return false
-			}
-			return true
+			// only cache requests with itemFilter, stageFilter, region or weighted query params. Personal results
+			// are never cached, since the cache is keyed by the URL only.
+			isPersonal, _ := strconv.ParseBool(c.Query("is_personal"))
+			return !isExpensiveResultQuery(c) || isPersonal
 		},
 		CacheHeader:  constant.CacheHeader,
 		CacheControl: true,
@@ -94,6 +86,13 @@ func RegisterResult(v2 *svr.V2, c Result) {
 	}), c.AdvancedQuery)
 }
 
+// isExpensiveResultQuery tells whether the request is filtered, or asks for a matrix which is calculated from drop
+// reports on request.
+func isExpensiveResultQuery(c *fiber.Ctx) bool {
+	weighted, _ := strconv.ParseBool(c.Query("weighted"))
+	return c.Query("itemFilter") != "" || c.Query("stageFilter") != "" || c.Query("region") != "" || weighted
+}
+
 //	@Summary	Get Drop Matrix
 //	@Tags		Result
 //	@Produce	json
@@ -103,7 +102,12 @@ func RegisterResult(v2 *svr.V2, c Result) {
 //	@Param		category			query		string							false	"Category; default to all"						Enums(all, automated, manual)
 //	@Param		stageFilter			query		[]string						false	"Comma separated list of stage IDs to filter"	collectionFormat(csv)
 //	@Param		itemFilter			query		[]string						false	"Comma separated list of item IDs to filter"	collectionFormat(csv)
+//	@Param		confidence			query		string							false	"Method to calculate drop rate confidence intervals with; intervals are omitted if not specified; not available with weighted"	Enums(wilson, clopper-pearson)
+//	@Param		confidence_level	query		number							false	"Confidence level of the intervals; default to 0.95"
+//	@Param		region				query		string							false	"ISO code of the country to only count reports submitted from; reports from all countries are counted if not specified"
+//	@Param		weighted			query		bool							false	"Whether to weight each report by the weight of its account or not; default to false"
 //	@Success	200					{object}	modelv2.DropMatrixQueryResult	"Drop Matrix response"
+//	@Failure	400					{object}	pgerr.PenguinError				"Invalid request, e.g. confidence with weighted"
 //	@Failure	500					{object}	pgerr.PenguinError				"An unexpected error occurred"
 //	@Security	PenguinIDAuth
 //	@Router		/PenguinStats/api/v2/result/matrix [GET]
@@ -127,6 +131,25 @@ func (c *Result) GetDropMatrix(ctx *fiber.Ctx) error {
 	}
 	stageFilterStr := ctx.Query("stageFilter")
 	itemFilterStr := ctx.Query("itemFilter")
+	confidenceMethod := ctx.Query("confidence")
+	confidenceLevel, err := strconv.ParseFloat(ctx.Query("confidence_level", strconv.FormatFloat(util.DefaultConfidenceLevel, 'f', -1, 64)), 64)
+	if err != nil {
+		return pgerr.ErrInvalidReq.Msg("invalid confidence_level: %s", err)
+	}
+	if err := rekuest.ValidConfidence(ctx, confidenceMethod, confidenceLevel); err != nil {
+		return err
+	}
+	region := ctx.Query("region")
+	if err := rekuest.ValidVar(ctx, region, "omitempty,iso3166_1_alpha2"); err != nil {
+		return err
+	}
+	weighted, err := strconv.ParseBool(ctx.Query("weighted", "false"))
+	if err != nil {
+		return err
+	}
+	if weighted && confidenceMethod != "" {
+		return ErrConfidenceWithWeighted
+	}
 
 	accountId := null.NewInt(0, false)
 	if isPersonal {
@@ -138,12 +161,21 @@ func (c *Result) GetDropMatrix(ctx *fiber.Ctx) error {
 		accountId.Valid = true
 	}
 
-	shimQueryResult, err := c.DropMatrixService.GetShimDropMatrix(ctx.UserContext(), server, showClosedZones, stageFilterStr, itemFilterStr, accountId, sourceCategory)
+	var shimQueryResult *modelv2.DropMatrixQueryResult
+	if region != "" || weighted {
+		shimQueryResult, err = c.DropMatrixService.GetShimLiveDropMatrix(ctx.UserContext(), server, showClosedZones, stageFilterStr, itemFilterStr, accountId, sourceCategory, region, weighted)
+	} else {
+		shimQueryResult, err = c.DropMatrixService.GetShimDropMatrix(ctx.UserContext(), server, showClosedZones, stageFilterStr, itemFilterStr, accountId, sourceCategory)
+	}
 	if err != nil {
 		return err
 	}
 
-	useCache := !accountId.Valid && stageFilterStr == "" && itemFilterStr == ""
+	if confidenceMethod != "" {
+		shimQueryResult = c.DropMatrixService.ApplyConfidenceIntervals(shimQueryResult, confidenceMethod, confidenceLevel)
+	}
+
+	useCache := !accountId.Valid && stageFilterStr == "" && itemFilterStr == "" && region == "" && !weighted
 	if useCache {
 		key := server + constant.CacheSep + strconv.FormatBool(showClosedZones) + constant.CacheSep + constant.SourceCategoryAll
 		var lastModifiedTime time.Time
@@ -162,6 +194,7 @@ func (c *Result) GetDropMatrix(ctx *fiber.Ctx) error {
 //	@Param		server			query		string	true	"Server; default to CN"	Enums(CN, US, JP, KR)
 //	@Param		is_personal		query		bool	false	"Whether to query for personal drop matrix or not. If `is_personal` equals to `true`, a valid PenguinID would be required to be provided (PenguinIDAuth)"
 //	@Param		showAllPatterns	query		bool	false	"Show all patterns; default to false"
+//	@Param		weighted		query		bool	false	"Whether to weight each report by the weight of its account or not; ignored for personal pattern matrices; default to false"
 //	@Success	200				{object}	modelv2.PatternMatrixQueryResult
 //	@Failure	500				{object}	pgerr.PenguinError	"An unexpected error occurred"
 //	@Security	PenguinIDAuth
@@ -189,12 +222,23 @@ func (c *Result) GetPatternMatrix(ctx *fiber.Ctx) error {
 		accountId.Valid = true
 	}
 
-	shimResult, err := c.PatternMatrixService.GetShimPatternMatrix(ctx.UserContext(), server, accountId, constant.SourceCategoryAll, showAllPatterns)
+	weighted, err := strconv.ParseBool(ctx.Query("weighted", "false"))
+	if err != nil {
+		return err
+	}
+	weighted = weighted && !accountId.Valid
+
+	var shimResult *modelv2.PatternMatrixQueryResult
+	if weighted {
+		shimResult, err = c.PatternMatrixService.GetShimWeightedPatternMatrix(ctx.UserContext(), server, constant.SourceCategoryAll, showAllPatterns)
+	} else {
+		shimResult, err = c.PatternMatrixService.GetShimPatternMatrix(ctx.UserContext(), server, accountId, constant.SourceCategoryAll, showAllPatterns)
+	}
 	if err != nil {
 		return err
 	}
 
-	if !accountId.Valid {
+	if !accountId.Valid && !weighted {
 		key := server + constant.CacheSep + constant.SourceCategoryAll + constant.CacheSep + strconv.FormatBool(showAllPatterns)
 		var lastModifiedTime time.Time
 		if err := cache.LastModifiedTime.Get("[shimGlobalPatternMatrix#server|sourceCategory|showAllPatterns:"+key+"]", &lastModifiedTime); err != nil {
@@ -317,7 +361,7 @@ func (c *Result) handleAdvancedQuery(ctx *fiber.Ctx, query *types.AdvancedQuery)
 			StartTime: &startTime,
 			EndTime:   &endTime,
 		}
-		return c.DropMatrixService.GetShimCustomizedDropMatrixResults(ctx.UserContext(), query.Server, timeRange, []int{stage.StageID}, itemIds, accountId, sourceCategory)
+		return c.DropMatrixService.GetShimCustomizedDropMatrixResults(ctx.UserContext(), query.Server, timeRange, []int{stage.StageID}, itemIds, accountId, sourceCategory, query.Region, query.Weighted)
 	} else {
 		// interval originally is in milliseconds, so we need to convert it to nanoseconds
 		intervalLength := time.Duration(query.Interval.Int64 * 1e6).Round(time.Hour)
@@ -329,7 +373,7 @@ func (c *Result) handleAdvancedQuery(ctx *fiber.Ctx, query *types.AdvancedQuery)
 			return nil, pgerr.ErrInvalidReq.Msg("too many sections: interval number is %d sections, which is larger than %d sections", intervalNum, constant.MaxIntervalNum)
 		}
 
-		shimTrendQueryResult, err := c.TrendService.GetShimCustomizedTrendResults(ctx.UserContext(), query.Server, &startTime, intervalLength, intervalNum, []int{stage.StageID}, itemIds, accountId, sourceCategory)
+		shimTrendQueryResult, err := c.TrendService.GetShimCustomizedTrendResults(ctx.UserContext(), query.Server, &startTime, intervalLength, intervalNum, []int{stage.StageID}, itemIds, accountId, sourceCategory, query.Region, query.Weighted)
 		if err != nil {
 			return nil, err
 		}
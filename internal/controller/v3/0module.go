@@ -7,11 +7,20 @@ import (
 func Module() fx.Option {
 	return fx.Module("controllers.v3", fx.Invoke(
 		RegisterItem,
+		RegisterAccount,
 		RegisterLive,
 		RegisterStage,
 		RegisterZone,
 		RegisterDataset,
 		RegisterInit,
 		RegisterIncremental,
+		RegisterExport,
+		RegisterPlanner,
+		RegisterSanityValue,
+		RegisterTrend,
+		RegisterSiteStats,
+		RegisterSearch,
+		RegisterNotice,
+		RegisterReport,
 	))
 }
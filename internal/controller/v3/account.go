@@ -0,0 +1,177 @@
+package v3
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
+	"go.uber.org/fx"
+
+	"exusiai.dev/backend-next/internal/model/types"
+	modelv3 "exusiai.dev/backend-next/internal/model/v3"
+	"exusiai.dev/backend-next/internal/pkg/cachectrl"
+	"exusiai.dev/backend-next/internal/pkg/flog"
+	"exusiai.dev/backend-next/internal/pkg/pgid"
+	"exusiai.dev/backend-next/internal/server/svr"
+	"exusiai.dev/backend-next/internal/service"
+	"exusiai.dev/backend-next/internal/util/rekuest"
+)
+
+type Account struct {
+	fx.In
+
+	AccountService       *service.Account
+	AccountReportService *service.AccountReport
+}
+
+func RegisterAccount(v3 *svr.V3, c Account) {
+	me := v3.Group("/me", func(ctx *fiber.Ctx) error {
+		// everything under /me is personal and must never be cached by intermediaries
+		cachectrl.OptOut(ctx)
+		return ctx.Next()
+	})
+	me.Get("/", c.GetAccount)
+	me.Get("/reports", c.GetReports)
+	me.Post("/reports/recall", c.RecallReports)
+	me.Post("/reports/delete", c.DeleteReports)
+	me.Post("/penguin-id/regenerate", newAccountLimiter(5, time.Hour), c.RegeneratePenguinId)
+	me.Get("/export", newAccountLimiter(5, time.Hour), c.ExportData)
+}
+
+func newAccountLimiter(max int, expiration time.Duration) fiber.Handler {
+	return limiter.New(limiter.Config{
+		LimitReached: func(c *fiber.Ctx) error {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"code":    "TOO_MANY_REQUESTS",
+				"message": "Your client is sending requests too frequently. Please try again later.",
+			})
+		},
+		Max:        max,
+		Expiration: expiration,
+	})
+}
+
+func (c *Account) GetAccount(ctx *fiber.Ctx) error {
+	account, err := c.AccountService.GetAccountFromRequest(ctx)
+	if err != nil {
+		return err
+	}
+
+	result, err := c.AccountReportService.GetAccount(ctx.UserContext(), account)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(result)
+}
+
+// GetReports lists the reports of the account, newest first.
+//
+// Query params:
+//   - cursor: nextCursor of the previous page; omit for the first page
+//   - limit: page size; defaults to 50 and at most 500
+func (c *Account) GetReports(ctx *fiber.Ctx) error {
+	account, err := c.AccountService.GetAccountFromRequest(ctx)
+	if err != nil {
+		return err
+	}
+
+	cursor := ctx.QueryInt("cursor", 0)
+	limit := ctx.QueryInt("limit", service.AccountReportsDefaultPageSize)
+
+	page, err := c.AccountReportService.GetReports(ctx.UserContext(), account, cursor, limit)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(page)
+}
+
+func (c *Account) RecallReports(ctx *fiber.Ctx) error {
+	account, err := c.AccountService.GetAccountFromRequest(ctx)
+	if err != nil {
+		return err
+	}
+
+	var request types.AccountReportsBulkRequest
+	if err := rekuest.ValidBody(ctx, &request); err != nil {
+		return err
+	}
+
+	affected, err := c.AccountReportService.RecallReports(ctx.UserContext(), account, request.ReportIDs)
+	if err != nil {
+		return err
+	}
+
+	flog.InfoFrom(ctx, "account.reports.recall").
+		Int("accountId", account.AccountID).
+		Int("requested", len(request.ReportIDs)).
+		Int64("affected", affected).
+		Msg("recalled reports of account")
+
+	return ctx.JSON(modelv3.AccountReportsBulkResult{Affected: affected})
+}
+
+func (c *Account) DeleteReports(ctx *fiber.Ctx) error {
+	account, err := c.AccountService.GetAccountFromRequest(ctx)
+	if err != nil {
+		return err
+	}
+
+	var request types.AccountReportsBulkRequest
+	if err := rekuest.ValidBody(ctx, &request); err != nil {
+		return err
+	}
+
+	affected, err := c.AccountReportService.DeleteReports(ctx.UserContext(), account, request.ReportIDs)
+	if err != nil {
+		return err
+	}
+
+	flog.InfoFrom(ctx, "account.reports.delete").
+		Int("accountId", account.AccountID).
+		Int("requested", len(request.ReportIDs)).
+		Int64("affected", affected).
+		Msg("deleted reports of account")
+
+	return ctx.JSON(modelv3.AccountReportsBulkResult{Affected: affected})
+}
+
+// RegeneratePenguinId assigns a new PenguinID to the account, keeping all of its reports. The new PenguinID
+// is injected into the response in the same way as logging in does.
+func (c *Account) RegeneratePenguinId(ctx *fiber.Ctx) error {
+	account, err := c.AccountService.GetAccountFromRequest(ctx)
+	if err != nil {
+		return err
+	}
+
+	regenerated, err := c.AccountService.RegeneratePenguinId(ctx.UserContext(), account)
+	if err != nil {
+		return err
+	}
+
+	flog.InfoFrom(ctx, "account.penguin_id.regenerate").
+		Int("accountId", account.AccountID).
+		Msg("regenerated PenguinID of account")
+
+	pgid.Inject(ctx, regenerated.PenguinID)
+
+	result, err := c.AccountReportService.GetAccount(ctx.UserContext(), regenerated)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(result)
+}
+
+func (c *Account) ExportData(ctx *fiber.Ctx) error {
+	account, err := c.AccountService.GetAccountFromRequest(ctx)
+	if err != nil {
+		return err
+	}
+
+	export, err := c.AccountReportService.ExportData(ctx.UserContext(), account)
+	if err != nil {
+		return err
+	}
+
+	ctx.Set(fiber.HeaderContentDisposition, `attachment; filename="penguin-stats_account_`+time.Now().UTC().Format("20060102")+`.json"`)
+	return ctx.JSON(export)
+}
@@ -11,8 +11,10 @@ import (
 
 	modelv2 "exusiai.dev/backend-next/internal/model/v2"
 	modelv3 "exusiai.dev/backend-next/internal/model/v3"
+	"exusiai.dev/backend-next/internal/pkg/pgerr"
 	"exusiai.dev/backend-next/internal/server/svr"
 	"exusiai.dev/backend-next/internal/service"
+	"exusiai.dev/backend-next/internal/util"
 	"exusiai.dev/backend-next/internal/util/rekuest"
 )
 
@@ -55,7 +57,25 @@ func (c Dataset) aggregateMatrix(ctx *fiber.Ctx) (*modelv2.DropMatrixQueryResult
 		accountId.Valid = true
 	}
 
-	return c.DropMatrixService.GetShimDropMatrix(ctx.UserContext(), server, true, "", "", accountId, category)
+	confidenceMethod := ctx.Query("confidence")
+	confidenceLevel, err := strconv.ParseFloat(ctx.Query("confidence_level", strconv.FormatFloat(util.DefaultConfidenceLevel, 'f', -1, 64)), 64)
+	if err != nil {
+		return nil, pgerr.ErrInvalidReq.Msg("invalid confidence_level: %s", err)
+	}
+	if err := rekuest.ValidConfidence(ctx, confidenceMethod, confidenceLevel); err != nil {
+		return nil, err
+	}
+
+	result, err := c.DropMatrixService.GetShimDropMatrix(ctx.UserContext(), server, true, "", "", accountId, category)
+	if err != nil {
+		return nil, err
+	}
+
+	if confidenceMethod != "" {
+		result = c.DropMatrixService.ApplyConfidenceIntervals(result, confidenceMethod, confidenceLevel)
+	}
+
+	return result, nil
 }
 
 func (c Dataset) aggregateTrend(ctx *fiber.Ctx) (*modelv2.TrendQueryResult, error) {
@@ -0,0 +1,158 @@
+package v3
+
+import (
+	"bufio"
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"exusiai.dev/gommon/constant"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
+	"github.com/rs/zerolog/log"
+	"go.uber.org/fx"
+
+	"exusiai.dev/backend-next/internal/model"
+	"exusiai.dev/backend-next/internal/pkg/pgerr"
+	"exusiai.dev/backend-next/internal/server/svr"
+	"exusiai.dev/backend-next/internal/service"
+	"exusiai.dev/backend-next/internal/util/rekuest"
+)
+
+const (
+	// exportMaxTimeRange limits the time range a single export could cover, to keep exports reasonably sized
+	exportMaxTimeRange     = time.Hour * 24 * 31
+	exportDefaultTimeRange = time.Hour * 24 * 7
+	exportStreamTimeout    = time.Minute * 30
+)
+
+type Export struct {
+	fx.In
+
+	ExportService *service.Export
+	StageService  *service.Stage
+	ItemService   *service.Item
+}
+
+func RegisterExport(v3 *svr.V3, c Export) {
+	v3.Get("/export/drop-reports", limiter.New(limiter.Config{
+		LimitReached: func(c *fiber.Ctx) error {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"code":    "TOO_MANY_REQUESTS",
+				"message": "Your client is sending requests too frequently. The Penguin Stats drop report export API is limited to 10 requests per hour.",
+			})
+		},
+		Max:        10,
+		Expiration: time.Hour,
+	}), c.ExportDropReports)
+}
+
+// ExportDropReports streams reliable drop reports in NDJSON, CSV or Parquet.
+//
+// Query params:
+//   - server: required
+//   - format: ndjson (default), csv or parquet
+//   - stageId, itemId: comma separated ark IDs; itemId selects stages which could drop any of the items
+//   - start, end: unix milliseconds; defaults to the last 7 days and should span at most 31 days
+//   - category: source category; defaults to all
+func (c *Export) ExportDropReports(ctx *fiber.Ctx) error {
+	server := ctx.Query("server")
+	if err := rekuest.ValidServer(ctx, server); err != nil {
+		return err
+	}
+
+	format := ctx.Query("format", service.ExportFormatNDJSON)
+	if format != service.ExportFormatNDJSON && format != service.ExportFormatCSV && format != service.ExportFormatParquet {
+		return pgerr.ErrInvalidReq.Msg("invalid format: must be one of ndjson, csv or parquet")
+	}
+
+	category := ctx.Query("category", constant.SourceCategoryAll)
+	if err := rekuest.ValidCategory(ctx, category); err != nil {
+		return err
+	}
+
+	endTime := time.Now()
+	if end := ctx.Query("end"); end != "" {
+		endMilli, err := strconv.ParseInt(end, 10, 64)
+		if err != nil {
+			return pgerr.ErrInvalidReq.Msg("invalid end: %s", err)
+		}
+		endTime = time.UnixMilli(endMilli)
+	}
+	startTime := endTime.Add(-exportDefaultTimeRange)
+	if start := ctx.Query("start"); start != "" {
+		startMilli, err := strconv.ParseInt(start, 10, 64)
+		if err != nil {
+			return pgerr.ErrInvalidReq.Msg("invalid start: %s", err)
+		}
+		startTime = time.UnixMilli(startMilli)
+	}
+	if !startTime.Before(endTime) {
+		return pgerr.ErrInvalidReq.Msg("start must be before end")
+	}
+	if endTime.Sub(startTime) > exportMaxTimeRange {
+		return pgerr.ErrInvalidReq.Msg("time range must not exceed 31 days")
+	}
+
+	filter := &model.DropReportExportFilter{
+		Server:         server,
+		StartTime:      &startTime,
+		EndTime:        &endTime,
+		SourceCategory: category,
+	}
+
+	for _, arkStageId := range splitQueryList(ctx.Query("stageId")) {
+		stage, err := c.StageService.GetStageByArkId(ctx.UserContext(), arkStageId)
+		if err != nil {
+			return err
+		}
+		filter.StageIDs = append(filter.StageIDs, stage.StageID)
+	}
+
+	for _, arkItemId := range splitQueryList(ctx.Query("itemId")) {
+		item, err := c.ItemService.GetItemByArkId(ctx.UserContext(), arkItemId)
+		if err != nil {
+			return err
+		}
+		filter.ItemIDs = append(filter.ItemIDs, item.ItemID)
+	}
+
+	filename := "penguin-stats_drop-reports_" + server + "_" + startTime.UTC().Format("20060102") + "-" + endTime.UTC().Format("20060102") + "." + format
+	ctx.Set(fiber.HeaderContentType, service.ExportContentType(format))
+	ctx.Set(fiber.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+
+	// the stream writer runs after the handler returns, when the request context has already been released
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		streamCtx, cancel := context.WithTimeout(context.Background(), exportStreamTimeout)
+		defer cancel()
+
+		if err := c.ExportService.StreamDropReports(streamCtx, filter, format, w); err != nil {
+			log.Error().
+				Err(err).
+				Str("evt.name", "export.drop_reports.stream").
+				Str("server", filter.Server).
+				Str("format", format).
+				Msg("failed to stream drop reports: response is truncated")
+		}
+		if err := w.Flush(); err != nil {
+			log.Debug().Err(err).Str("evt.name", "export.drop_reports.flush").Msg("failed to flush export stream")
+		}
+	})
+
+	return nil
+}
+
+func splitQueryList(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	var list []string
+	for _, el := range strings.Split(s, ",") {
+		if el = strings.TrimSpace(el); el != "" {
+			list = append(list, el)
+		}
+	}
+	return list
+}
@@ -1,78 +1,139 @@
 package v3
 
 import (
+	"time"
+
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+	"github.com/rs/zerolog/log"
+	"go.uber.org/fx"
+	"google.golang.org/protobuf/proto"
 
+	"exusiai.dev/backend-next/internal/model/pb"
 	"exusiai.dev/backend-next/internal/server/svr"
+	"exusiai.dev/backend-next/internal/service"
 )
 
-type LiveController struct{}
+const (
+	LiveSubprotocol = "v3.penguin-stats.live+proto"
+
+	livePingInterval = time.Second * 30
+	liveWriteTimeout = time.Second * 10
+)
 
-func RegisterLive(v3 *svr.V3) {
-	c := &LiveController{}
+type LiveController struct {
+	fx.In
 
-	v3.Get("/live", c.Live())
+	LiveService *service.Live
 }
 
-func (c *LiveController) Live() func(ctx *fiber.Ctx) error {
-	// return websocket.New(func(c *websocket.Conn) {
-	// 	defer c.Close()
-
-	// 	_, b, err := c.ReadMessage()
-	// 	if err != nil {
-	// 		log.Println(err)
-	// 		return
-	// 	}
-
-	// 	log.Println(hex.EncodeToString(b))
-
-	// 	elements := []*protos.MatrixUpdateMessage_Segment_Element{}
-	// 	for i := 0; i < 25; i++ {
-	// 		elements = append(elements, &protos.MatrixUpdateMessage_Segment_Element{
-	// 			Id: &protos.MatrixUpdateMessage_Segment_Element_StageId{
-	// 				StageId: int32(rand.Intn(100)),
-	// 			},
-	// 			Amount: int32(rand.Intn(1000000)),
-	// 		})
-	// 	}
-
-	// 	segments := []*protos.MatrixUpdateMessage_Segment{}
-	// 	for i := 0; i < 3; i++ {
-	// 		segments = append(segments, &protos.MatrixUpdateMessage_Segment{
-	// 			Bucket: &protos.MatrixUpdateMessage_Segment_ItemId{
-	// 				ItemId: int32(rand.Intn(500)),
-	// 			},
-	// 			Elements: elements,
-	// 		})
-	// 	}
-
-	// 	msg := protos.MatrixUpdateMessage{
-	// 		Header: &protos.Header{
-	// 			Type: protos.MessageType_MATRIX_ABSOLUTE_UPDATE_MESSAGE,
-	// 		},
-	// 		Segments: segments,
-	// 	}
-
-	// 	res, err := proto.Marshal(&msg)
-	// 	if err != nil {
-	// 		log.Println(err)
-	// 		return
-	// 	}
-
-	// 	c.WriteMessage(websocket.BinaryMessage, res)
-
-	// 	time.Sleep(time.Millisecond * 100)
-
-	// 	err = c.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second*5))
-	// 	if err != nil {
-	// 		log.Println(err)
-	// 		return
-	// 	}
-	// }, websocket.Config{
-	// 	Subprotocols:      []string{"v3.penguin-stats.live+proto"},
-	// 	EnableCompression: true,
-	// })
-	return func(ctx *fiber.Ctx) error {
+func RegisterLive(v3 *svr.V3, c LiveController) {
+	v3.Get("/live", c.Upgrade, websocket.New(c.Live, websocket.Config{
+		Subprotocols:      []string{LiveSubprotocol},
+		EnableCompression: true,
+	}))
+}
+
+func (c *LiveController) Upgrade(ctx *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(ctx) {
+		return fiber.ErrUpgradeRequired
+	}
+	return ctx.Next()
+}
+
+func (c *LiveController) Live(conn *websocket.Conn) {
+	sub := c.LiveService.NewSubscriber()
+	defer c.LiveService.RemoveSubscriber(sub)
+
+	// websocket connections do not support concurrent writers, therefore responses from
+	// the reader goroutine are handed over to the writer loop below
+	replies := make(chan proto.Message, 8)
+	done := make(chan struct{})
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go func() {
+		defer close(done)
+		for {
+			typ, b, err := conn.ReadMessage()
+			if err != nil {
+				if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+					log.Warn().Err(err).Str("evt.name", "live.read").Msg("unexpected websocket close")
+				}
+				return
+			}
+			if typ != websocket.BinaryMessage {
+				continue
+			}
+
+			reply := c.handleMessage(sub, b)
+			if reply == nil {
+				continue
+			}
+			select {
+			case replies <- reply:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	pingTicker := time.NewTicker(livePingInterval)
+	defer pingTicker.Stop()
+
+	for {
+		var err error
+		select {
+		case <-done:
+			return
+		case reply := <-replies:
+			err = c.write(conn, reply)
+		case update := <-sub.C:
+			err = c.write(conn, update)
+		case <-pingTicker.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(liveWriteTimeout))
+		}
+		if err != nil {
+			log.Debug().Err(err).Str("evt.name", "live.write").Msg("failed to write to websocket: closing connection")
+			return
+		}
+	}
+}
+
+func (c *LiveController) handleMessage(sub *service.LiveSubscriber, b []byte) proto.Message {
+	var skeleton pb.Skeleton
+	if err := proto.Unmarshal(b, &skeleton); err != nil {
+		return nil
+	}
+
+	switch skeleton.GetHeader().GetType() {
+	case pb.MessageType_MATRIX_UPDATE_SUBSCRIBE_REQ:
+		resp := &pb.MatrixUpdateSubscribeResp{
+			Header: &pb.Header{
+				Type: pb.MessageType_MATRIX_UPDATE_SUBSCRIBE_RESP,
+			},
+		}
+
+		var req pb.MatrixUpdateSubscribeReq
+		if err := proto.Unmarshal(b, &req); err != nil {
+			resp.Error = err.Error()
+		} else if err := sub.Subscribe(&req); err != nil {
+			resp.Error = err.Error()
+		}
+		return resp
+	default:
 		return nil
 	}
 }
+
+func (c *LiveController) write(conn *websocket.Conn, msg proto.Message) error {
+	b, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	if err := conn.SetWriteDeadline(time.Now().Add(liveWriteTimeout)); err != nil {
+		return err
+	}
+	return conn.WriteMessage(websocket.BinaryMessage, b)
+}
@@ -0,0 +1,56 @@
+package v3
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/fx"
+
+	"exusiai.dev/backend-next/internal/model"
+	"exusiai.dev/backend-next/internal/server/svr"
+	"exusiai.dev/backend-next/internal/service"
+	"exusiai.dev/backend-next/internal/util/rekuest"
+)
+
+type Notice struct {
+	fx.In
+
+	NoticeService *service.Notice
+}
+
+func RegisterNotice(v3 *svr.V3, c Notice) {
+	v3.Get("/notices", c.GetNotices)
+}
+
+// GetNotices returns the notices shown now to the client, most severe first. Clients are targeted by the
+// X-Penguin-Variant header along with the query params.
+//
+// Query params:
+//   - server: the server the client is requesting for
+//   - source: the source name the client reports with
+//   - lang: the language to render notices in; defaults to the best match of the Accept-Language header
+func (c *Notice) GetNotices(ctx *fiber.Ctx) error {
+	server := ctx.Query("server")
+	if server != "" {
+		if err := rekuest.ValidServer(ctx, server); err != nil {
+			return err
+		}
+	}
+	language := ctx.Query("lang")
+	if language != "" {
+		if err := rekuest.ValidVar(ctx, language, "oneof=en zh ja ko"); err != nil {
+			return err
+		}
+	} else if ctx.Get(fiber.HeaderAcceptLanguage) != "" {
+		language = ctx.AcceptsLanguages(service.NoticeLanguages...)
+	}
+
+	notices, err := c.NoticeService.GetActiveNotices(ctx.UserContext(), &model.NoticeTarget{
+		Server:  server,
+		Variant: ctx.Get("X-Penguin-Variant"),
+		Source:  ctx.Query("source"),
+	}, language)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(notices)
+}
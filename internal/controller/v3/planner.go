@@ -0,0 +1,37 @@
+package v3
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/fx"
+
+	"exusiai.dev/backend-next/internal/model/types"
+	"exusiai.dev/backend-next/internal/pkg/middlewares"
+	"exusiai.dev/backend-next/internal/server/svr"
+	"exusiai.dev/backend-next/internal/service"
+	"exusiai.dev/backend-next/internal/util/rekuest"
+)
+
+type Planner struct {
+	fx.In
+
+	PlannerService *service.Planner
+}
+
+func RegisterPlanner(v3 *svr.V3, c Planner) {
+	v3.Post("/planner", middlewares.MatrixLimiter(nil), c.Plan)
+}
+
+// Plan solves the sanity-minimizing farming plan for the needed items, using the global drop matrix of
+// the server and the crafting formula.
+func (c *Planner) Plan(ctx *fiber.Ctx) error {
+	var request types.PlannerRequest
+	if err := rekuest.ValidBody(ctx, &request); err != nil {
+		return err
+	}
+
+	result, err := c.PlannerService.Plan(ctx.UserContext(), &request)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(result)
+}
@@ -0,0 +1,37 @@
+package v3
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/fx"
+
+	"exusiai.dev/backend-next/internal/server/svr"
+	"exusiai.dev/backend-next/internal/service"
+	"exusiai.dev/backend-next/internal/util/rekuest"
+)
+
+type Report struct {
+	fx.In
+
+	ReportTaskStatusService *service.ReportTaskStatus
+}
+
+func RegisterReport(v3 *svr.V3, c Report) {
+	v3.Get("/reports/tasks/:taskId", c.GetReportTaskStatus)
+}
+
+// GetReportTaskStatus returns the processing status of a report task by the taskId returned on submission, along
+// with the resulting report IDs and the verifier which lowered the reliability of each report, if any. Why the
+// verifier did so is only exposed to admins. Statuses are kept for 24 hours after their last update.
+func (c *Report) GetReportTaskStatus(ctx *fiber.Ctx) error {
+	taskId := ctx.Params("taskId")
+	if err := rekuest.ValidVar(ctx, taskId, "required,max=128"); err != nil {
+		return err
+	}
+
+	status, err := c.ReportTaskStatusService.GetReportTaskStatus(ctx.UserContext(), taskId)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(status.Public())
+}
@@ -0,0 +1,54 @@
+package v3
+
+import (
+	"time"
+
+	"exusiai.dev/gommon/constant"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/fx"
+
+	"exusiai.dev/backend-next/internal/model/cache"
+	"exusiai.dev/backend-next/internal/pkg/cachectrl"
+	"exusiai.dev/backend-next/internal/server/svr"
+	"exusiai.dev/backend-next/internal/service"
+	"exusiai.dev/backend-next/internal/util/rekuest"
+)
+
+type SanityValue struct {
+	fx.In
+
+	SanityValueService *service.SanityValue
+}
+
+func RegisterSanityValue(v3 *svr.V3, c SanityValue) {
+	v3.Get("/sanity-value/:server", c.GetSanityValues)
+}
+
+// GetSanityValues returns the equivalent sanity value of items and the efficiency of currently open stages.
+//
+// Query params:
+//   - category: source category of the drop matrix; defaults to "all"
+func (c *SanityValue) GetSanityValues(ctx *fiber.Ctx) error {
+	server := ctx.Params("server")
+	if err := rekuest.ValidServer(ctx, server); err != nil {
+		return err
+	}
+	category := ctx.Query("category", constant.SourceCategoryAll)
+	if err := rekuest.ValidCategory(ctx, category); err != nil {
+		return err
+	}
+
+	result, err := c.SanityValueService.GetSanityValues(ctx.UserContext(), server, category)
+	if err != nil {
+		return err
+	}
+
+	key := server + constant.CacheSep + category
+	var lastModifiedTime time.Time
+	if err := cache.LastModifiedTime.Get("[sanityValues#server|sourceCategory:"+key+"]", &lastModifiedTime); err != nil {
+		lastModifiedTime = time.Now()
+	}
+	cachectrl.OptIn(ctx, lastModifiedTime)
+
+	return ctx.JSON(result)
+}
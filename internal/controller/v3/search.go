@@ -0,0 +1,48 @@
+package v3
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/fx"
+
+	"exusiai.dev/backend-next/internal/server/svr"
+	"exusiai.dev/backend-next/internal/service"
+	"exusiai.dev/backend-next/internal/util/rekuest"
+)
+
+type Search struct {
+	fx.In
+
+	SearchService *service.Search
+}
+
+func RegisterSearch(v3 *svr.V3, c Search) {
+	v3.Get("/search", c.Search)
+}
+
+// Search ranks items, stages and zones matching the query.
+//
+// Query params:
+//   - q: the query
+//   - type: comma-separated types to search for, out of "item", "stage" and "zone"; defaults to all
+//   - limit: maximum amount of hits; defaults to 10, at most 50
+func (c *Search) Search(ctx *fiber.Ctx) error {
+	query := ctx.Query("q")
+	if err := rekuest.ValidVar(ctx, query, "required,max=64"); err != nil {
+		return err
+	}
+	var types []string
+	if t := ctx.Query("type"); t != "" {
+		types = strings.Split(t, ",")
+		if err := rekuest.ValidVar(ctx, types, "dive,oneof=item stage zone"); err != nil {
+			return err
+		}
+	}
+
+	result, err := c.SearchService.Search(ctx.UserContext(), query, types, ctx.QueryInt("limit", service.SearchDefaultLimit))
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(result)
+}
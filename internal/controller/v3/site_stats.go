@@ -0,0 +1,52 @@
+package v3
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/fx"
+
+	"exusiai.dev/backend-next/internal/model/cache"
+	"exusiai.dev/backend-next/internal/pkg/cachectrl"
+	"exusiai.dev/backend-next/internal/server/svr"
+	"exusiai.dev/backend-next/internal/service"
+	"exusiai.dev/backend-next/internal/util/rekuest"
+)
+
+type SiteStats struct {
+	fx.In
+
+	SiteStatsService *service.SiteStats
+}
+
+func RegisterSiteStats(v3 *svr.V3, c SiteStats) {
+	v3.Get("/stats/:server", c.GetSiteStatsSeries)
+}
+
+// GetSiteStatsSeries returns the daily site stats of the server, oldest first.
+//
+// Query params:
+//   - days: amount of days, counting back from today; defaults to 30, at most 365
+func (c *SiteStats) GetSiteStatsSeries(ctx *fiber.Ctx) error {
+	server := ctx.Params("server")
+	if err := rekuest.ValidServer(ctx, server); err != nil {
+		return err
+	}
+	days := ctx.QueryInt("days", service.SiteStatsSeriesDefaultDays)
+	if err := rekuest.ValidVar(ctx, days, "min=1,max=365"); err != nil {
+		return err
+	}
+
+	series, err := c.SiteStatsService.GetSiteStatsSeries(ctx.UserContext(), server, days)
+	if err != nil {
+		return err
+	}
+
+	var lastModifiedTime time.Time
+	if err := cache.LastModifiedTime.Get("[siteStatsSeries#server:"+server+"]", &lastModifiedTime); err != nil {
+		lastModifiedTime = time.Now()
+	}
+	cachectrl.OptIn(ctx, lastModifiedTime)
+
+	return ctx.JSON(series)
+}
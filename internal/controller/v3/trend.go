@@ -0,0 +1,50 @@
+package v3
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/fx"
+
+	"exusiai.dev/backend-next/internal/model/types"
+	"exusiai.dev/backend-next/internal/server/svr"
+	"exusiai.dev/backend-next/internal/service"
+	"exusiai.dev/backend-next/internal/util/rekuest"
+)
+
+type Trend struct {
+	fx.In
+
+	TrendService *service.Trend
+}
+
+func RegisterTrend(v3 *svr.V3, c Trend) {
+	v3.Get("/trend/:server/stage/:stageId", c.GetStageTrend)
+}
+
+// GetStageTrend returns the drop rate trend of the items of a stage, with credible intervals and moving averages.
+// Results are cached for the worker interval, since they are calculated from the drop matrix elements.
+//
+// Query params:
+//   - item: only return the trend of this item
+//   - category: source category; defaults to "all"
+//   - window: amount of days, counting back from today; defaults to 60, at most 365
+//   - granularity: "day" (default), "week", or "range" for the max accumulable time ranges of the item
+//   - moving_average: amount of buckets the moving average is pooled over, counting buckets without drops as
+//     gaps; defaults to 7 for "day", 3 otherwise
+//   - confidence_level: confidence level of the credible intervals; defaults to 0.95
+func (c *Trend) GetStageTrend(ctx *fiber.Ctx) error {
+	server := ctx.Params("server")
+	if err := rekuest.ValidServer(ctx, server); err != nil {
+		return err
+	}
+
+	var request types.TrendRequest
+	if err := rekuest.ValidQuery(ctx, &request); err != nil {
+		return err
+	}
+
+	result, err := c.TrendService.GetRollingTrend(ctx.UserContext(), server, ctx.Params("stageId"), &request)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(result)
+}
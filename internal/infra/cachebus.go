@@ -0,0 +1,98 @@
+package infra
+
+import (
+	"context"
+
+	"github.com/goccy/go-json"
+	"github.com/nats-io/nats.go"
+	"github.com/rs/xid"
+	"github.com/rs/zerolog/log"
+	"go.uber.org/fx"
+
+	"exusiai.dev/backend-next/internal/app/appconfig"
+	"exusiai.dev/backend-next/internal/pkg/cache"
+)
+
+// cacheInvalidationMessage is a cache invalidation broadcast by the instance identified by Origin.
+type cacheInvalidationMessage struct {
+	cache.Invalidation
+
+	Origin string `json:"origin"`
+}
+
+// CacheInvalidationBusInit broadcasts invalidations of the process-local caches to the other instances over NATS,
+// and applies the invalidations broadcast by them, with side effect.
+func CacheInvalidationBusInit(lc fx.Lifecycle, conf *appconfig.Config, nc *nats.Conn) error {
+	if !conf.CacheInvalidationBusEnabled {
+		log.Warn().
+			Str("evt.name", "infra.cachebus.init").
+			Msg("Cache invalidation bus is disabled. Caches are only invalidated on the instance which invalidates them.")
+		return nil
+	}
+
+	origin := xid.New().String()
+	subject := conf.CacheInvalidationSubject
+
+	sub, err := nc.Subscribe(subject, func(msg *nats.Msg) {
+		var message cacheInvalidationMessage
+		if err := json.Unmarshal(msg.Data, &message); err != nil {
+			log.Warn().
+				Str("evt.name", "infra.cachebus.receive").
+				Err(err).
+				Msg("failed to decode cache invalidation")
+			return
+		}
+		if message.Origin == origin {
+			return
+		}
+
+		if !cache.Invalidate(message.Invalidation) {
+			log.Debug().
+				Str("evt.name", "infra.cachebus.receive").
+				Str("cache", message.Name).
+				Str("origin", message.Origin).
+				Msg("received invalidation of unknown cache: is the other instance running a different version?")
+		}
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("infra: cachebus: failed to subscribe to cache invalidations")
+		return err
+	}
+
+	cache.SetBroadcaster(func(invalidation cache.Invalidation) {
+		data, err := json.Marshal(cacheInvalidationMessage{
+			Invalidation: invalidation,
+			Origin:       origin,
+		})
+		if err != nil {
+			log.Error().
+				Str("evt.name", "infra.cachebus.publish").
+				Err(err).
+				Msg("failed to encode cache invalidation")
+			return
+		}
+
+		if err := nc.Publish(subject, data); err != nil {
+			log.Warn().
+				Str("evt.name", "infra.cachebus.publish").
+				Err(err).
+				Str("cache", invalidation.Name).
+				Msg("failed to broadcast cache invalidation: other instances may serve stale values until the entries expire")
+		}
+	})
+
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			cache.SetBroadcaster(nil)
+			return sub.Unsubscribe()
+		},
+	})
+
+	log.Info().
+		Str("evt.name", "infra.cachebus.init").
+		Str("origin", origin).
+		Str("subject", subject).
+		Msg("Cache invalidation bus initialized.")
+
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"exusiai.dev/backend-next/internal/app/appconfig"
+	"exusiai.dev/backend-next/internal/pkg/jetstream"
 )
 
 func NATS(conf *appconfig.Config) (*nats.Conn, nats.JetStreamContext, error) {
@@ -32,7 +33,7 @@ func NATS(conf *appconfig.Config) (*nats.Conn, nats.JetStreamContext, error) {
 	}
 
 	_, err = js.AddStream(&nats.StreamConfig{
-		Name: "penguin-reports",
+		Name: jetstream.ReportStream,
 		Subjects: []string{
 			"REPORT.*",
 		},
@@ -49,5 +50,21 @@ func NATS(conf *appconfig.Config) (*nats.Conn, nats.JetStreamContext, error) {
 		log.Warn().Err(err).Msg("infra: nats: failed to create jetstream stream: is it already created?")
 	}
 
+	// dead-lettered reports are kept (rather than consumed) until they are replayed or discarded by admins
+	_, err = js.AddStream(&nats.StreamConfig{
+		Name: jetstream.ReportDeadLetterStream,
+		Subjects: []string{
+			jetstream.ReportDeadLetterSubjectPrefix + "REPORT.*",
+		},
+		Retention: nats.LimitsPolicy,
+		Discard:   nats.DiscardOld,
+		Storage:   nats.FileStorage,
+		Replicas:  1,
+		MaxAge:    conf.ReportDeadLetterMaxAge,
+	})
+	if err != nil {
+		log.Warn().Err(err).Msg("infra: nats: failed to create jetstream dead letter stream: is it already created?")
+	}
+
 	return nc, js, nil
 }
@@ -6,6 +6,9 @@ import (
 	"github.com/uptrace/bun"
 )
 
+// AccountDefaultWeight is the weight of accounts whose weight has never been set (stored as 0).
+const AccountDefaultWeight = 1.0
+
 type Account struct {
 	bun.BaseModel `bun:"accounts"`
 
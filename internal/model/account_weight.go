@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// AccountWeightRecalculation summarizes a recalculation of account weights from the agreement of the reports of
+// each account with the consensus drop rates.
+type AccountWeightRecalculation struct {
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+	DryRun    bool      `json:"dryRun"`
+	// Evaluated is the amount of accounts with enough reliable reports to be evaluated.
+	Evaluated int `json:"evaluated"`
+	// Skipped is the amount of accounts with reliable reports within the window, but not enough to be evaluated.
+	Skipped    int     `json:"skipped"`
+	MeanWeight float64 `json:"meanWeight"`
+	// Weights is only returned for dry runs.
+	Weights []*AccountWeightResult `json:"weights,omitempty"`
+}
+
+type AccountWeightResult struct {
+	AccountID int `json:"accountId"`
+	Reports   int `json:"reports"`
+	// Agreement is in (0, 1], where 1 means the drops of the account are as close to the consensus drop rates
+	// as expected by chance.
+	Agreement float64 `json:"agreement"`
+	// Experience is in (0, 1], growing logarithmically with the amount of reports of the account.
+	Experience float64 `json:"experience"`
+	Weight     float64 `json:"weight"`
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/uptrace/bun"
+)
+
+const (
+	AdminAuditActionUpdate = "update"
+	AdminAuditActionDelete = "delete"
+)
+
+// AdminAuditLog records a change of game data made through the admin API.
+type AdminAuditLog struct {
+	bun.BaseModel `bun:"admin_audit_logs,alias:aal"`
+
+	LogID  int    `bun:",pk,autoincrement" json:"id"`
+	Action string `json:"action"`
+	// Entity is the table name of the changed game data, e.g. "stages", and EntityID its primary key.
+	Entity   string `json:"entity"`
+	EntityID int    `json:"entityId"`
+	// Before and After are the JSON representations of the game data before and after the change. After is
+	// null for deletions.
+	Before    json.RawMessage `bun:"type:jsonb" json:"before" swaggertype:"object"`
+	After     json.RawMessage `bun:"type:jsonb" json:"after" swaggertype:"object"`
+	IP        string          `json:"ip"`
+	CreatedAt *time.Time      `json:"createdAt"`
+}
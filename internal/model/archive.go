@@ -0,0 +1,72 @@
+package model
+
+import (
+	"github.com/goccy/go-json"
+)
+
+// DropReportArchiveRow is the parquet row of an archived DropReport.
+// Its schema is part of the archive format and must stay stable: only append new optional columns.
+type DropReportArchiveRow struct {
+	ReportID    int64   `parquet:"report_id"`
+	StageID     int64   `parquet:"stage_id"`
+	PatternID   int64   `parquet:"pattern_id"`
+	Times       int32   `parquet:"times"`
+	CreatedAt   int64   `parquet:"created_at,timestamp(microsecond)"`
+	Reliability int32   `parquet:"reliability"`
+	Server      string  `parquet:"server,dict"`
+	AccountID   int64   `parquet:"account_id"`
+	SourceName  *string `parquet:"source_name,optional,dict"`
+	Version     *string `parquet:"version,optional,dict"`
+}
+
+func NewDropReportArchiveRow(report *DropReport) *DropReportArchiveRow {
+	row := &DropReportArchiveRow{
+		ReportID:    int64(report.ReportID),
+		StageID:     int64(report.StageID),
+		PatternID:   int64(report.PatternID),
+		Times:       int32(report.Times),
+		Reliability: int32(report.Reliability),
+		Server:      report.Server,
+		AccountID:   int64(report.AccountID),
+	}
+	if report.CreatedAt != nil {
+		row.CreatedAt = report.CreatedAt.UnixMicro()
+	}
+	if report.SourceName != "" {
+		row.SourceName = &report.SourceName
+	}
+	if report.Version != "" {
+		row.Version = &report.Version
+	}
+	return row
+}
+
+// DropReportExtraArchiveRow is the parquet row of an archived DropReportExtra.
+// Metadata is kept as a JSON string so that the schema stays stable as metadata evolves.
+type DropReportExtraArchiveRow struct {
+	ReportID int64   `parquet:"report_id"`
+	IP       string  `parquet:"ip"`
+	Metadata *string `parquet:"metadata,optional,json"`
+	MD5      *string `parquet:"md5,optional"`
+	Country  *string `parquet:"country,optional"`
+}
+
+func NewDropReportExtraArchiveRow(extra *DropReportExtra) *DropReportExtraArchiveRow {
+	row := &DropReportExtraArchiveRow{
+		ReportID: int64(extra.ReportID),
+		IP:       extra.IP,
+	}
+	if extra.Metadata != nil {
+		if b, err := json.Marshal(extra.Metadata); err == nil {
+			metadata := string(b)
+			row.Metadata = &metadata
+		}
+	}
+	if extra.MD5.Valid {
+		row.MD5 = &extra.MD5.String
+	}
+	if extra.Country.Valid {
+		row.Country = &extra.Country.String
+	}
+	return row
+}
@@ -9,6 +9,7 @@ import (
 
 	"exusiai.dev/backend-next/internal/model"
 	modelv2 "exusiai.dev/backend-next/internal/model/v2"
+	modelv3 "exusiai.dev/backend-next/internal/model/v3"
 	"exusiai.dev/backend-next/internal/pkg/cache"
 )
 
@@ -24,10 +25,19 @@ var (
 
 	ShimGlobalDropMatrix *cache.Set[modelv2.DropMatrixQueryResult]
 	GlobalDropMatrix     *cache.Set[model.DropMatrixQueryResult]
+	WeightedDropMatrix   *cache.Set[model.DropMatrixQueryResult]
 
-	ShimTrend *cache.Set[modelv2.TrendQueryResult]
+	StageItemDropStats *cache.Set[map[int]*model.StageItemDropStats]
+
+	ShimTrend    *cache.Set[modelv2.TrendQueryResult]
+	RollingTrend *cache.Set[modelv3.TrendResult]
+
+	SanityValues *cache.Set[modelv3.SanityValues]
+
+	DropDriftReport *cache.Set[model.DropDriftReport]
 
 	ShimGlobalPatternMatrix *cache.Set[modelv2.PatternMatrixQueryResult]
+	WeightedPatternMatrix   *cache.Set[model.PatternMatrixQueryResult]
 
 	Formula *cache.Singular[json.RawMessage]
 
@@ -42,12 +52,16 @@ var (
 
 	RecruitTagMap *cache.Singular[map[string]string]
 
-	Notices *cache.Singular[[]*model.Notice]
+	Notices          *cache.Singular[[]*model.Notice]
+	ScheduledNotices *cache.Singular[[]*model.ScheduledNotice]
+
+	ShortLinksMapByWord *cache.Singular[map[string]*model.ShortLink]
 
 	Activities     *cache.Singular[[]*model.Activity]
 	ShimActivities *cache.Singular[[]*modelv2.Activity]
 
-	ShimSiteStats *cache.Set[modelv2.SiteStats]
+	ShimSiteStats   *cache.Set[modelv2.SiteStats]
+	SiteStatsSeries *cache.Set[modelv3.SiteStatsSeries]
 
 	Stages           *cache.Singular[[]*model.Stage]
 	StageByArkID     *cache.Set[model.Stage]
@@ -128,19 +142,42 @@ func initializeCaches() {
 	// drop_matrix
 	ShimGlobalDropMatrix = cache.NewSet[modelv2.DropMatrixQueryResult]("shimGlobalDropMatrix#server|showClosedZones|sourceCategory")
 	GlobalDropMatrix = cache.NewSet[model.DropMatrixQueryResult]("globalDropMatrix#server|sourceCategory")
+	WeightedDropMatrix = cache.NewSet[model.DropMatrixQueryResult]("weightedDropMatrix#server|sourceCategory")
 
 	SetMap["shimGlobalDropMatrix#server|showClosedZones|sourceCategory"] = ShimGlobalDropMatrix.Flush
 	SetMap["globalDropMatrix#server|sourceCategory"] = GlobalDropMatrix.Flush
+	SetMap["weightedDropMatrix#server|sourceCategory"] = WeightedDropMatrix.Flush
+
+	// sanity_value
+	SanityValues = cache.NewSet[modelv3.SanityValues]("sanityValues#server|sourceCategory")
+
+	SetMap["sanityValues#server|sourceCategory"] = SanityValues.Flush
+
+	// drop_drift
+	DropDriftReport = cache.NewSet[model.DropDriftReport]("dropDriftReport#server|sourceCategory")
+
+	SetMap["dropDriftReport#server|sourceCategory"] = DropDriftReport.Flush
+
+	// drop_matrix_element
+	StageItemDropStats = cache.NewSet[map[int]*model.StageItemDropStats]("stageItemDropStats#server|arkStageId")
+
+	SetMap["stageItemDropStats#server|arkStageId"] = StageItemDropStats.Flush
 
 	// trend
 	ShimTrend = cache.NewSet[modelv2.TrendQueryResult]("shimTrend#server")
 
 	SetMap["shimTrend#server"] = ShimTrend.Flush
 
+	RollingTrend = cache.NewSet[modelv3.TrendResult]("rollingTrend#server|arkStageId|window|category|granularity|movingAverage|confidenceLevel|arkItemId")
+
+	SetMap["rollingTrend#server|arkStageId|window|category|granularity|movingAverage|confidenceLevel|arkItemId"] = RollingTrend.Flush
+
 	// pattern_matrix
 	ShimGlobalPatternMatrix = cache.NewSet[modelv2.PatternMatrixQueryResult]("shimGlobalPatternMatrix#server|sourceCategory|showAllPatterns")
+	WeightedPatternMatrix = cache.NewSet[model.PatternMatrixQueryResult]("weightedPatternMatrix#server|sourceCategory")
 
 	SetMap["shimGlobalPatternMatrix#server|sourceCategory|showAllPatterns"] = ShimGlobalPatternMatrix.Flush
+	SetMap["weightedPatternMatrix#server|sourceCategory"] = WeightedPatternMatrix.Flush
 
 	// formula
 	Formula = cache.NewSingular[json.RawMessage]("formula")
@@ -171,8 +208,15 @@ func initializeCaches() {
 
 	// notice
 	Notices = cache.NewSingular[[]*model.Notice]("notices")
+	ScheduledNotices = cache.NewSingular[[]*model.ScheduledNotice]("scheduledNotices")
 
 	SingularFlusherMap["notices"] = Notices.Delete
+	SingularFlusherMap["scheduledNotices"] = ScheduledNotices.Delete
+
+	// short_link
+	ShortLinksMapByWord = cache.NewSingular[map[string]*model.ShortLink]("shortLinksMapByWord")
+
+	SingularFlusherMap["shortLinksMapByWord"] = ShortLinksMapByWord.Delete
 
 	// activity
 	Activities = cache.NewSingular[[]*model.Activity]("activities")
@@ -186,6 +230,10 @@ func initializeCaches() {
 
 	SetMap["shimSiteStats#server"] = ShimSiteStats.Flush
 
+	SiteStatsSeries = cache.NewSet[modelv3.SiteStatsSeries]("siteStatsSeries#server")
+
+	SetMap["siteStatsSeries#server"] = SiteStatsSeries.Flush
+
 	// stage
 	Stages = cache.NewSingular[[]*model.Stage]("stages")
 	StageByArkID = cache.NewSet[model.Stage]("stage#arkStageId")
@@ -0,0 +1,37 @@
+package model
+
+// DropDriftReport lists the stage/item pairs whose drop rate in the current time range differs significantly from
+// the one in the previous time range of the same stage.
+type DropDriftReport struct {
+	Server         string  `json:"server"`
+	SourceCategory string  `json:"sourceCategory"`
+	Threshold      float64 `json:"threshold"`
+	MinTimes       int     `json:"minTimes"`
+	// Compared is the amount of stage/item pairs that had enough samples in both time ranges to be compared.
+	Compared     int          `json:"compared"`
+	Drifts       []*DropDrift `json:"drifts"`
+	CalculatedAt int64        `json:"calculatedAt"`
+}
+
+type DropDrift struct {
+	StageID    int    `json:"stageId"`
+	ArkStageID string `json:"arkStageId"`
+	ItemID     int    `json:"itemId"`
+	ArkItemID  string `json:"arkItemId"`
+	// Accumulable is the accumulable flag of the current drop info. A significant drift between accumulable time
+	// ranges is unexpected, and usually means a silent game-side change or a bad drop info import.
+	Accumulable bool              `json:"accumulable"`
+	Previous    *DropDriftSegment `json:"previous"`
+	Current     *DropDriftSegment `json:"current"`
+	TScore      float64           `json:"tScore"`
+}
+
+type DropDriftSegment struct {
+	RangeID   int     `json:"rangeId"`
+	StartTime int64   `json:"startTime"`
+	EndTime   int64   `json:"endTime"`
+	Times     int     `json:"times"`
+	Quantity  int     `json:"quantity"`
+	Rate      float64 `json:"rate"`
+	StdDev    float64 `json:"stdDev"`
+}
@@ -17,3 +17,15 @@ type AllQuantityBucketsResultForGlobalDropMatrix struct {
 	ItemID          int         `json:"itemId" bun:"item_id"`
 	QuantityBuckets map[int]int `json:"quantityBuckets" bun:"type:jsonb"`
 }
+
+// StageItemDropStats is the aggregated drop statistics of an item in a stage, used to tell
+// how plausible a sequence of reported drops is.
+type StageItemDropStats struct {
+	ItemID   int `json:"itemId"`
+	Times    int `json:"times"`
+	Quantity int `json:"quantity"`
+	// QuantitySquareSum is the sum of the squared per-run quantities, derived from quantity buckets.
+	// It is only meaningful when HasBuckets is true.
+	QuantitySquareSum int  `json:"quantitySquareSum"`
+	HasBuckets        bool `json:"hasBuckets"`
+}
@@ -10,8 +10,12 @@ import (
 type DropReportExtra struct {
 	bun.BaseModel `bun:"drop_report_extras,alias:dre"`
 
-	ReportID int                          `bun:",pk,autoincrement" json:"id"`
+	ReportID int `bun:",pk,autoincrement" json:"id"`
+	// IP is erased (set to an empty string) after DropReportIPRetentionDays.
 	IP       string                       `json:"ip"`
 	Metadata *types.ReportRequestMetadata `json:"metadata"`
 	MD5      null.String                  `json:"md5" swaggertype:"string"`
+	// Country is the ISO code of the country IP resolves to. It is null for reports submitted while
+	// DropReportCountryAttributionEnabled is off, or whose IP resolves to no country.
+	Country null.String `json:"country" swaggertype:"string"`
 }
@@ -15,6 +15,10 @@ type DropReportQueryContext struct {
 	SourceCategory     string         `json:"sourceCategory"`
 	ExcludeNonOneTimes bool           `json:"excludeNonOneTimes"`
 	Times              null.Int       `json:"times"`
+	// Country filters reports by the country their IP resolves to; see DropReportExtra.Country.
+	Country string `json:"country"`
+	// Weighted weights each report by the weight of its account; see Account.Weight.
+	Weighted bool `json:"weighted"`
 }
 
 func (queryCtx *DropReportQueryContext) GetStageIds() []int {
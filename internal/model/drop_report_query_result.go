@@ -41,6 +41,9 @@ type OneDropMatrixElement struct {
 	Quantity  int        `json:"quantity"`
 	StdDev    float64    `json:"stdDev"`
 	TimeRange *TimeRange `json:"timeRange"`
+
+	// QuantityBuckets is kept for confidence interval calculations and is not exposed
+	QuantityBuckets map[int]int `json:"-"`
 }
 
 // DropPattern
@@ -114,3 +117,20 @@ type ItemTrend struct {
 	MinGroupID int        `json:"-"`
 	MaxGroupID int        `json:"-"`
 }
+
+// AccountWeight
+type AccountStageTimesResult struct {
+	Server      string `json:"server" bun:"server"`
+	AccountID   int    `json:"accountId" bun:"account_id"`
+	StageID     int    `json:"stageId" bun:"stage_id"`
+	TotalTimes  int    `json:"totalTimes" bun:"total_times"`
+	ReportCount int    `json:"reportCount" bun:"report_count"`
+}
+
+type AccountStageItemQuantityResult struct {
+	Server        string `json:"server" bun:"server"`
+	AccountID     int    `json:"accountId" bun:"account_id"`
+	StageID       int    `json:"stageId" bun:"stage_id"`
+	ItemID        int    `json:"itemId" bun:"item_id"`
+	TotalQuantity int    `json:"totalQuantity" bun:"total_quantity"`
+}
@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 type ExportDropReportsAndPatternsResult struct {
 	DropReports  []*DropReportForExport  `json:"drop_reports"`
 	DropPatterns []*DropPatternForExport `json:"drop_patterns"`
@@ -20,6 +22,41 @@ type DropPatternForExport struct {
 }
 
 type DropPatternElementForExport struct {
-	ArkItemID string `json:"itemId"`
-	Quantity  int    `json:"quantity"`
+	ArkItemID string `json:"itemId" parquet:"item_id,dict"`
+	Quantity  int    `json:"quantity" parquet:"quantity"`
+}
+
+// DropReportExportFilter describes the drop reports to be streamed by a public export.
+type DropReportExportFilter struct {
+	Server         string
+	StageIDs       []int
+	ItemIDs        []int
+	StartTime      *time.Time
+	EndTime        *time.Time
+	SourceCategory string
+}
+
+// DropReportExportRow is a single drop report read from the database for a streaming export.
+type DropReportExportRow struct {
+	ReportID   int       `bun:"report_id"`
+	CreatedAt  time.Time `bun:"created_at"`
+	Server     string    `bun:"server"`
+	ArkStageID string    `bun:"ark_stage_id"`
+	PatternID  int       `bun:"pattern_id"`
+	Times      int       `bun:"times"`
+	SourceName string    `bun:"source_name"`
+	Version    string    `bun:"version"`
+}
+
+// DropReportForStreamExport is the public representation of a drop report in a streaming export.
+// It intentionally contains no account or IP information. Its parquet schema is considered stable.
+type DropReportForStreamExport struct {
+	ReportID   int64                         `json:"reportId" parquet:"report_id"`
+	CreatedAt  int64                         `json:"createdAt" parquet:"created_at,timestamp(millisecond)"`
+	Server     string                        `json:"server" parquet:"server,dict"`
+	StageID    string                        `json:"stageId" parquet:"stage_id,dict"`
+	Times      int32                         `json:"times" parquet:"times"`
+	SourceName string                        `json:"sourceName" parquet:"source_name,dict"`
+	Version    string                        `json:"version" parquet:"version,dict"`
+	Drops      []DropPatternElementForExport `json:"drops" parquet:"drops,list"`
 }
@@ -0,0 +1,26 @@
+package model
+
+// FormulaEntry is a single crafting formula within the formula property, producing one item of ID.
+type FormulaEntry struct {
+	ID           string                 `json:"id"`
+	Name         string                 `json:"name"`
+	GoldCost     int                    `json:"goldCost"`
+	Costs        []*FormulaCost         `json:"costs"`
+	ExtraOutcome []*FormulaExtraOutcome `json:"extraOutcome"`
+	TotalWeight  float64                `json:"totalWeight"`
+}
+
+type FormulaCost struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// FormulaExtraOutcome is a possible byproduct of a crafting, which is produced with the probability of
+// Weight / FormulaEntry.TotalWeight when a byproduct is produced.
+type FormulaExtraOutcome struct {
+	ID     string  `json:"id"`
+	Name   string  `json:"name"`
+	Count  int     `json:"count"`
+	Weight float64 `json:"weight"`
+}
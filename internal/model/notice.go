@@ -1,6 +1,10 @@
 package model
 
 import (
+	"path"
+	"slices"
+	"time"
+
 	"github.com/goccy/go-json"
 
 	"github.com/uptrace/bun"
@@ -15,3 +19,72 @@ type Notice struct {
 	Severity  null.Int        `json:"severity" swaggertype:"integer"`
 	Content   json.RawMessage `json:"content_i18n"`
 }
+
+const (
+	ScheduledNoticeSeverityInfo = iota
+	ScheduledNoticeSeverityWarning
+	ScheduledNoticeSeverityCritical
+)
+
+// ScheduledNotice is a v3 notice, shown within its time window to the clients it targets only.
+type ScheduledNotice struct {
+	bun.BaseModel `bun:"scheduled_notices,alias:sn"`
+
+	NoticeID int `bun:",pk,autoincrement" json:"id"`
+	Severity int `json:"severity"`
+	// Title and Content map languages to the title and the Markdown content in the language.
+	Title   map[string]string `bun:"type:jsonb" json:"title"`
+	Content map[string]string `bun:"type:jsonb" json:"content"`
+	// StartTime and EndTime bound the time window the notice is shown within; either may be nil for an
+	// open-ended window.
+	StartTime *time.Time `json:"startTime"`
+	EndTime   *time.Time `json:"endTime"`
+	// Servers, Variants and Sources target the notice to clients requesting for any of the servers, having an
+	// X-Penguin-Variant header matching any of the variant patterns (see path.Match), and reporting with any of
+	// the source names. An empty list matches all clients.
+	Servers   []string   `bun:"type:jsonb" json:"servers"`
+	Variants  []string   `bun:"type:jsonb" json:"variants"`
+	Sources   []string   `bun:"type:jsonb" json:"sources"`
+	Enabled   bool       `json:"enabled"`
+	CreatedAt *time.Time `json:"createdAt"`
+	UpdatedAt *time.Time `json:"updatedAt"`
+}
+
+// NoticeTarget describes the client notices are requested for. Empty fields only match notices not targeted
+// by them.
+type NoticeTarget struct {
+	Server  string
+	Variant string
+	Source  string
+}
+
+// ShownAt reports whether the notice is enabled and t is within its time window.
+func (n *ScheduledNotice) ShownAt(t time.Time) bool {
+	if !n.Enabled {
+		return false
+	}
+	if n.StartTime != nil && t.Before(*n.StartTime) {
+		return false
+	}
+	if n.EndTime != nil && !t.Before(*n.EndTime) {
+		return false
+	}
+	return true
+}
+
+// Targets reports whether the notice targets the client.
+func (n *ScheduledNotice) Targets(target *NoticeTarget) bool {
+	if len(n.Servers) > 0 && !slices.Contains(n.Servers, target.Server) {
+		return false
+	}
+	if len(n.Sources) > 0 && !slices.Contains(n.Sources, target.Source) {
+		return false
+	}
+	if len(n.Variants) > 0 {
+		return slices.ContainsFunc(n.Variants, func(pattern string) bool {
+			matched, err := path.Match(pattern, target.Variant)
+			return err == nil && matched && target.Variant != ""
+		})
+	}
+	return true
+}
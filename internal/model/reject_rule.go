@@ -16,3 +16,19 @@ type RejectRule struct {
 	Expr            string     `bun:"expr" json:"expr"`
 	WithReliability int        `bun:"with_reliability" json:"with_reliability"`
 }
+
+// RejectRuleRevision is an immutable snapshot of a reject rule, recorded every time the rule is saved.
+// Revisions recorded by saving the rule carry the result of the dry run the rule has been saved with.
+type RejectRuleRevision struct {
+	bun.BaseModel `bun:"reject_rule_revisions"`
+
+	RevisionID           int        `bun:",pk,autoincrement" json:"id"`
+	RuleID               int        `bun:"rule_id" json:"rule_id"`
+	Revision             int        `bun:"revision" json:"revision"`
+	CreatedAt            *time.Time `bun:"created_at" json:"created_at"`
+	Status               int        `bun:"status" json:"status"`
+	Expr                 string     `bun:"expr" json:"expr"`
+	WithReliability      int        `bun:"with_reliability" json:"with_reliability"`
+	DryRunEvaluatedCount *int       `bun:"dry_run_evaluated_count" json:"dry_run_evaluated_count"`
+	DryRunRejectedCount  *int       `bun:"dry_run_rejected_count" json:"dry_run_rejected_count"`
+}
@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+
+	"exusiai.dev/backend-next/internal/model/types"
+)
+
+// ReportDeadLetter is a report task which failed to be processed by the report worker after all of its
+// deliveries, as kept in the dead letter stream.
+type ReportDeadLetter struct {
+	// Sequence is the sequence of the message in the dead letter stream.
+	Sequence uint64 `json:"sequence"`
+	// Subject is the subject the task was originally published to, which it is replayed to.
+	Subject        string    `json:"subject"`
+	Deliveries     int       `json:"deliveries"`
+	Error          string    `json:"error"`
+	DeadLetteredAt time.Time `json:"deadLetteredAt"`
+	// Task is nil if the message could not be decoded into a report task, in which case Raw is set instead.
+	Task *types.ReportTask `json:"task,omitempty"`
+	Raw  string            `json:"raw,omitempty"`
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// ShortLink is a custom short link managed by admins, e.g. for event campaigns. It takes precedence over the
+// built-in short URL resolution of the same word.
+type ShortLink struct {
+	bun.BaseModel `bun:"short_links,alias:sl"`
+
+	LinkID int `bun:",pk,autoincrement" json:"id"`
+	// Word is the path segment the link is visited by, i.e. /short/{word}. It is unique and stored in lower case.
+	Word string `json:"word"`
+	// Targets maps locales (e.g. "zh", "en") to the target of the link, which is either a path of the frontend
+	// (starting with a slash; the host is chosen by the region of the visitor) or an absolute URL. The "default"
+	// target is used when no locale accepted by the visitor matches.
+	Targets   map[string]string `bun:"type:jsonb" json:"targets"`
+	ExpiresAt *time.Time        `json:"expiresAt,omitempty"`
+	Comment   string            `json:"comment"`
+	CreatedAt *time.Time        `json:"createdAt"`
+	UpdatedAt *time.Time        `json:"updatedAt"`
+}
+
+func (l *ShortLink) Expired(t time.Time) bool {
+	return l.ExpiresAt != nil && !l.ExpiresAt.After(t)
+}
+
+// ShortLinkClick is the aggregated amount of clicks of a short link from a country within a day (in UTC).
+// Rows are unique by (link_id, date, country).
+type ShortLinkClick struct {
+	bun.BaseModel `bun:"short_link_clicks,alias:slc"`
+
+	LinkID int       `json:"linkId"`
+	Date   time.Time `bun:"type:date" json:"date"`
+	// Country is the ISO code of the country of the visitor, or an empty string if unknown.
+	Country string `json:"country"`
+	Clicks  int    `json:"clicks"`
+}
+
+// ShortLinkClickStats is the clicks of a short link since a date, aggregated by country and by day.
+type ShortLinkClickStats struct {
+	LinkID    int                      `json:"linkId"`
+	Since     string                   `json:"since"`
+	Total     int                      `json:"total"`
+	ByCountry []*ShortLinkCountryClick `json:"byCountry"`
+	ByDay     []*ShortLinkDayClick     `json:"byDay"`
+}
+
+type ShortLinkCountryClick struct {
+	Country string `json:"country"`
+	Clicks  int    `json:"clicks"`
+}
+
+type ShortLinkDayClick struct {
+	Date   string `json:"date"`
+	Clicks int    `json:"clicks"`
+}
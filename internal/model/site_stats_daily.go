@@ -0,0 +1,46 @@
+package model
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// SiteStatsDaily is the persisted site statistics of a single day of a server. Rows are unique by (server, day_num),
+// and the row of the current day is updated as the day goes on.
+type SiteStatsDaily struct {
+	bun.BaseModel `bun:"site_stats_daily,alias:ssd"`
+
+	StatsID        int                        `bun:",pk,autoincrement" json:"id"`
+	Server         string                     `json:"server"`
+	DayNum         int                        `json:"dayNum"`
+	StartTime      *time.Time                 `json:"startTime"`
+	UniqueAccounts int                        `json:"uniqueAccounts"`
+	Reports        int                        `json:"reports"`
+	Times          int                        `json:"times"`
+	Sources        []*SiteStatsDailySource    `bun:"type:jsonb" json:"sources"`
+	Rejections     []*SiteStatsDailyRejection `bun:"type:jsonb" json:"rejections"`
+	UpdatedAt      *time.Time                 `json:"updatedAt"`
+}
+
+type SiteStatsDailySource struct {
+	SourceName string `json:"sourceName"`
+	Version    string `json:"version"`
+	Reports    int    `json:"reports"`
+	Times      int    `json:"times"`
+	Rejected   int    `json:"rejected"`
+}
+
+type SiteStatsDailyRejection struct {
+	Verifier string `json:"verifier"`
+	Reports  int    `json:"reports"`
+}
+
+// SiteStatsDailyReportCount is the amount of reports of a day, grouped by source, version and reliability.
+type SiteStatsDailyReportCount struct {
+	SourceName  string `bun:"source_name"`
+	Version     string `bun:"version"`
+	Reliability int    `bun:"reliability"`
+	Reports     int    `bun:"reports"`
+	Times       int    `bun:"times"`
+}
@@ -58,6 +58,12 @@ type CloneFromCNRequest struct {
 type ArchiveDropReportRequest struct {
 	Date               string `json:"date" validate:"required" required:"true"`
 	DeleteAfterArchive bool   `json:"deleteAfterArchive" validate:"required" required:"true"`
+	// Formats overrides the configured archive formats when non-empty
+	Formats []string `json:"formats" validate:"dive,oneof=jsonl parquet"`
+}
+
+type RestoreDropReportRequest struct {
+	Date string `json:"date" validate:"required" required:"true"`
 }
 
 type ForeignTimeRange struct {
@@ -70,3 +76,71 @@ type ForeignTimeRangeString struct {
 	Start string `json:"start"`
 	End   string `json:"end"`
 }
+
+type RejectRuleSaveRequest struct {
+	Expr            string `json:"expr" validate:"required" required:"true"`
+	WithReliability int    `json:"withReliability" validate:"required" required:"true"`
+	// DryRunHours is the amount of hours of most recent reports the rule is evaluated against
+	// before it is saved. Defaults to 24 hours.
+	DryRunHours int `json:"dryRunHours" validate:"omitempty,min=1,max=168"`
+}
+
+// RejectRuleActivateRequest confirms the dry run result of the latest revision of a reject rule, as returned
+// when the rule was saved.
+type RejectRuleActivateRequest struct {
+	Revision            int  `json:"revision" validate:"required" required:"true"`
+	DryRunRejectedCount *int `json:"dryRunRejectedCount" validate:"required,min=0" required:"true"`
+}
+
+type RejectRuleDryRunRequest struct {
+	Expr        string `json:"expr" validate:"required" required:"true"`
+	DryRunHours int    `json:"dryRunHours" validate:"omitempty,min=1,max=168"`
+}
+
+type ShortLinkSaveRequest struct {
+	Word string `json:"word" validate:"required,max=64" required:"true"`
+	// Targets maps locales to targets; see model.ShortLink. The "default" target is required.
+	Targets   map[string]string `json:"targets" validate:"required,dive,keys,oneof=default zh en ja ko,endkeys,required,max=2048" required:"true"`
+	ExpiresAt *time.Time        `json:"expiresAt"`
+	Comment   string            `json:"comment" validate:"max=512"`
+}
+
+type ShortLinkClicksRequest struct {
+	// Days is the amount of most recent days (in UTC, including today) clicks are aggregated within. Defaults to 30.
+	Days int `query:"days" validate:"omitempty,min=1,max=365"`
+}
+
+type ScheduledNoticeSaveRequest struct {
+	Severity int `json:"severity" validate:"min=0,max=2"`
+	// Title and Content map languages to the title and the Markdown content in the language.
+	Title     map[string]string `json:"title" validate:"dive,keys,oneof=en zh ja ko,endkeys,max=256"`
+	Content   map[string]string `json:"content" validate:"required,min=1,dive,keys,oneof=en zh ja ko,endkeys,required,max=65536" required:"true"`
+	StartTime *time.Time        `json:"startTime"`
+	EndTime   *time.Time        `json:"endTime"`
+	Servers   []string          `json:"servers" validate:"dive,arkserver"`
+	Variants  []string          `json:"variants" validate:"dive,required,max=128"`
+	Sources   []string          `json:"sources" validate:"dive,required,max=128"`
+	Enabled   bool              `json:"enabled"`
+}
+
+type AdminAuditLogsRequest struct {
+	Entity   string `query:"entity" validate:"omitempty,oneof=zones stages time_ranges drop_infos"`
+	EntityID int    `query:"entityId" validate:"omitempty,min=1"`
+	Limit    int    `query:"limit" validate:"omitempty,min=1,max=1000"`
+}
+
+type ReportDeadLettersRequest struct {
+	// After is the stream sequence dead letters are listed after, for pagination.
+	After uint64 `query:"after"`
+	Limit int    `query:"limit" validate:"omitempty,min=1,max=500"`
+}
+
+type AccountWeightUpdateRequest struct {
+	// Weight is the new weight of the account. 0 resets it to model.AccountDefaultWeight.
+	Weight float64 `json:"weight" validate:"min=0,max=100"`
+}
+
+type AccountWeightsRecalculateRequest struct {
+	// DryRun only calculates the weights without saving them, and returns the weight of every account.
+	DryRun bool `json:"dryRun"`
+}
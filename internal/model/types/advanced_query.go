@@ -15,4 +15,8 @@ type AdvancedQuery struct {
 	StartTime      int64     `json:"start" swaggertype:"integer"`
 	EndTime        int64     `json:"end" validate:"omitempty,gtfield=StartTime" swaggertype:"integer"`
 	Interval       null.Int  `json:"interval" swaggertype:"integer"`
+	// Region only counts reports whose IP resolves to the country of the ISO code.
+	Region string `json:"region" validate:"omitempty,iso3166_1_alpha2"`
+	// Weighted weights each report by the weight of its account.
+	Weighted bool `json:"weighted"`
 }
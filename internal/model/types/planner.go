@@ -0,0 +1,17 @@
+package types
+
+type PlannerRequest struct {
+	Server string `json:"server" validate:"required,arkserver" required:"true" example:"CN"`
+	// Owned maps ark item IDs to the amount of the item already owned.
+	Owned map[string]int `json:"owned" validate:"dive,keys,required,printascii,endkeys,min=0"`
+	// Needed maps ark item IDs to the total amount of the item needed, including the owned ones.
+	Needed map[string]int `json:"needed" validate:"required,min=1,dive,keys,required,printascii,endkeys,min=0" required:"true"`
+	// ExcludeCrafting disables crafting items in the workshop, so that all items are obtained from stages.
+	ExcludeCrafting bool `json:"excludeCrafting"`
+	// Byproduct takes the expected byproducts of crafting into account.
+	Byproduct bool `json:"byproduct"`
+	// ExcludeStages lists ark stage IDs which should not be farmed.
+	ExcludeStages []string `json:"excludeStages" validate:"dive,printascii"`
+	// MinTimes is the minimum amount of samples of a drop matrix element for it to be trusted. Defaults to 100.
+	MinTimes int `json:"minTimes" validate:"min=0"`
+}
@@ -55,3 +55,8 @@ type BatchReportError struct {
 type SingularReportRecallRequest struct {
 	ReportHash string `json:"reportHash" validate:"required,printascii" example:"cahbuch1eqliv7dopen0-5ejlUrfzNMXNHY6Q"`
 }
+
+// account reports bulk recall & deletion
+type AccountReportsBulkRequest struct {
+	ReportIDs []int `json:"reportIds" validate:"required,min=1,max=1000,dive,min=1"`
+}
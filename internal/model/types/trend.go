@@ -0,0 +1,13 @@
+package types
+
+type TrendRequest struct {
+	// ItemID optionally filters the trend to a single item, by its ark item ID.
+	ItemID   string `query:"item"`
+	Category string `query:"category" validate:"omitempty,oneof=all automated manual"`
+	// Window is the amount of days, counting back from today, the trend covers.
+	Window      int    `query:"window" validate:"omitempty,min=1,max=365"`
+	Granularity string `query:"granularity" validate:"omitempty,oneof=day week range"`
+	// MovingAverage is the amount of buckets the moving average is pooled over.
+	MovingAverage   int     `query:"moving_average" validate:"omitempty,min=1,max=60"`
+	ConfidenceLevel float64 `query:"confidence_level" validate:"omitempty,gt=0,lt=1"`
+}
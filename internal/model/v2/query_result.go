@@ -15,6 +15,20 @@ type OneDropMatrixElement struct {
 	StdDev    float64  `json:"stdDev" example:"0.114514"`
 	StartTime int64    `json:"start" example:"1556676000000"`
 	EndTime   null.Int `json:"end,omitempty" swaggertype:"integer"`
+
+	// DropRateCI is the confidence interval of the probability that the item drops at least once in a run.
+	// Only present when confidence intervals are requested.
+	DropRateCI *ConfidenceInterval `json:"dropRateCI,omitempty"`
+	// QuantityCI is the credible interval of the expected quantity of the item per run.
+	// Only present when confidence intervals are requested.
+	QuantityCI *ConfidenceInterval `json:"quantityCI,omitempty"`
+
+	QuantityBuckets map[int]int `json:"-"`
+}
+
+type ConfidenceInterval struct {
+	Lower float64 `json:"lower" example:"0.1871"`
+	Upper float64 `json:"upper" example:"0.8129"`
 }
 
 // DropPattern
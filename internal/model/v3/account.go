@@ -0,0 +1,49 @@
+package v3
+
+import (
+	"exusiai.dev/backend-next/internal/model"
+	"exusiai.dev/backend-next/internal/model/types"
+)
+
+type Account struct {
+	PenguinID   string `json:"penguinId"`
+	CreatedAt   int64  `json:"createdAt"`
+	ReportCount int    `json:"reportCount"`
+}
+
+type AccountReport struct {
+	ReportID    int                                  `json:"reportId"`
+	StageID     string                               `json:"stageId"`
+	Server      string                               `json:"server"`
+	Times       int                                  `json:"times"`
+	Drops       []*model.DropPatternElementForExport `json:"drops"`
+	Reliability int                                  `json:"reliability"`
+	SourceName  string                               `json:"sourceName"`
+	Version     string                               `json:"version"`
+	CreatedAt   int64                                `json:"createdAt"`
+}
+
+type AccountReportsPage struct {
+	Reports []*AccountReport `json:"reports"`
+	// NextCursor is the cursor to fetch the next (older) page with; 0 if there is none.
+	NextCursor int `json:"nextCursor"`
+}
+
+type AccountReportExtra struct {
+	ReportID int                          `json:"reportId"`
+	IP       string                       `json:"ip"`
+	Metadata *types.ReportRequestMetadata `json:"metadata"`
+	Country  string                       `json:"country,omitempty"`
+}
+
+// AccountDataExport is everything stored about an account, for the account owner to download.
+type AccountDataExport struct {
+	Account      *Account              `json:"account"`
+	Reports      []*AccountReport      `json:"reports"`
+	ReportExtras []*AccountReportExtra `json:"reportExtras"`
+	ExportedAt   int64                 `json:"exportedAt"`
+}
+
+type AccountReportsBulkResult struct {
+	Affected int64 `json:"affected"`
+}
@@ -0,0 +1,15 @@
+package v3
+
+// Notice is a scheduled notice rendered in a single language.
+type Notice struct {
+	ID       int `json:"id"`
+	Severity int `json:"severity"`
+	// Language is the language Title and Content are in, which may differ from the requested one if the notice
+	// is not available in it.
+	Language string `json:"language"`
+	Title    string `json:"title"`
+	// Content is in Markdown.
+	Content   string `json:"content"`
+	StartTime int64  `json:"startTime,omitempty"`
+	EndTime   int64  `json:"endTime,omitempty"`
+}
@@ -0,0 +1,24 @@
+package v3
+
+type PlannerResult struct {
+	Server string `json:"server"`
+	// TotalSanity is the total cost of the plan in sanity, including the sanity worth of TotalGold.
+	TotalSanity float64 `json:"totalSanity"`
+	// TotalGold is the total gold spent on crafting.
+	TotalGold float64             `json:"totalGold"`
+	Stages    []*PlannerStage     `json:"stages"`
+	Syntheses []*PlannerSynthesis `json:"syntheses"`
+}
+
+type PlannerStage struct {
+	StageID string `json:"stageId"`
+	// Runs is the (fractional) amount of runs of the stage. Clients would typically round it up.
+	Runs   float64 `json:"runs"`
+	Sanity float64 `json:"sanity"`
+}
+
+type PlannerSynthesis struct {
+	ItemID string  `json:"itemId"`
+	Count  float64 `json:"count"`
+	Gold   float64 `json:"gold"`
+}
@@ -0,0 +1,43 @@
+package v3
+
+const (
+	ReportTaskStatusQueued    = "queued"
+	ReportTaskStatusProcessed = "processed"
+	ReportTaskStatusFailed    = "failed"
+)
+
+// ReportTaskStatus is the processing status of a submitted report task.
+type ReportTaskStatus struct {
+	TaskID string `json:"taskId"`
+	// Status is queued until the task is processed by the report worker, including while it is being retried, and
+	// failed if the task has been moved to the dead letter stream after all of its retries.
+	Status string `json:"status"`
+	// Reports are the results of the reports in the task, in the order they were submitted. Only set once the task
+	// has been processed.
+	Reports []*ReportTaskResult `json:"reports,omitempty"`
+	// UpdatedAt is in milliseconds since the epoch.
+	UpdatedAt int64 `json:"updatedAt"`
+}
+
+type ReportTaskResult struct {
+	ReportID int `json:"reportId"`
+	// Reliability is 0 if the report is accepted into the statistics.
+	Reliability int `json:"reliability"`
+	// Violation is the name of the verifier that lowered the reliability of the report, with Message describing
+	// why. Both are empty if the report is accepted. Message is only exposed to admins, as it could include the
+	// thresholds of the verifier.
+	Violation string `json:"violation,omitempty"`
+	Message   string `json:"message,omitempty"`
+}
+
+// Public returns a copy of the status without the messages of the results, for it to be exposed publicly.
+func (s *ReportTaskStatus) Public() *ReportTaskStatus {
+	public := *s
+	public.Reports = make([]*ReportTaskResult, len(s.Reports))
+	for i, result := range s.Reports {
+		r := *result
+		r.Message = ""
+		public.Reports[i] = &r
+	}
+	return &public
+}
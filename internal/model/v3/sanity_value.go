@@ -0,0 +1,24 @@
+package v3
+
+// SanityValues holds the "equivalent sanity" value of items, and the efficiency of stages derived from them.
+type SanityValues struct {
+	Server         string                   `json:"server"`
+	SourceCategory string                   `json:"sourceCategory"`
+	Items          []*ItemSanityValue       `json:"items"`
+	Stages         []*StageSanityEfficiency `json:"stages"`
+	UpdatedAt      int64                    `json:"updatedAt"`
+}
+
+type ItemSanityValue struct {
+	ItemID string  `json:"itemId"`
+	Value  float64 `json:"value"`
+}
+
+type StageSanityEfficiency struct {
+	StageID string `json:"stageId"`
+	Sanity  int    `json:"sanity"`
+	// Value is the expected total sanity value of the drops of a single run.
+	Value float64 `json:"value"`
+	// Efficiency is Value divided by Sanity. The most efficient stages are at 1.
+	Efficiency float64 `json:"efficiency"`
+}
@@ -0,0 +1,23 @@
+package v3
+
+import "github.com/goccy/go-json"
+
+type SearchResult struct {
+	Query string       `json:"query"`
+	Hits  []*SearchHit `json:"hits"`
+}
+
+type SearchHit struct {
+	// Type is one of "item", "stage" and "zone".
+	Type string `json:"type"`
+	// ID is the ark ID of the item, stage or zone.
+	ID string `json:"id"`
+	// Name is the multilingual name of the item or zone, or the multilingual code of the stage.
+	Name json.RawMessage `json:"name"`
+	// ZoneID is the ark ID of the zone of a stage, or of the zone itself.
+	ZoneID string `json:"zoneId,omitempty"`
+	// Score is in (0, 1]; 1 means an exact match.
+	Score float64 `json:"score"`
+	// Matched is the normalized text the query matched.
+	Matched string `json:"matched"`
+}
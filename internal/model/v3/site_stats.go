@@ -0,0 +1,32 @@
+package v3
+
+type SiteStatsSeries struct {
+	Server string          `json:"server"`
+	Days   []*SiteStatsDay `json:"days"`
+}
+
+type SiteStatsDay struct {
+	// StartTime is the start of the day in the timezone of the server.
+	StartTime      int64 `json:"startTime"`
+	UniqueAccounts int   `json:"uniqueAccounts"`
+	Reports        int   `json:"reports"`
+	Times          int   `json:"times"`
+	// RejectionRatio is the ratio of reports marked by any of the verifiers.
+	RejectionRatio float64              `json:"rejectionRatio"`
+	Sources        []*SiteStatsSource   `json:"sources"`
+	Rejections     []*SiteStatsRejected `json:"rejections"`
+}
+
+type SiteStatsSource struct {
+	SourceName string `json:"sourceName"`
+	Version    string `json:"version"`
+	Reports    int    `json:"reports"`
+	Times      int    `json:"times"`
+	Rejected   int    `json:"rejected"`
+}
+
+type SiteStatsRejected struct {
+	Verifier string  `json:"verifier"`
+	Reports  int     `json:"reports"`
+	Ratio    float64 `json:"ratio"`
+}
@@ -0,0 +1,33 @@
+package v3
+
+type TrendResult struct {
+	Server          string  `json:"server"`
+	StageID         string  `json:"stageId"`
+	SourceCategory  string  `json:"sourceCategory"`
+	Granularity     string  `json:"granularity"`
+	Window          int     `json:"window"`
+	MovingAverage   int     `json:"movingAverage"`
+	ConfidenceLevel float64 `json:"confidenceLevel"`
+
+	Items []*ItemTrend `json:"items"`
+}
+
+type ItemTrend struct {
+	ItemID string `json:"itemId"`
+	// Buckets are sorted by time. Buckets without any run are omitted.
+	Buckets []*TrendBucket `json:"buckets"`
+}
+
+type TrendBucket struct {
+	StartTime int64 `json:"startTime"`
+	EndTime   int64 `json:"endTime"`
+	Times     int   `json:"times"`
+	Quantity  int   `json:"quantity"`
+	// Rate is the expected quantity per run within the bucket.
+	Rate float64 `json:"rate"`
+	// Lower and Upper are the bounds of the credible interval of Rate at the requested confidence level.
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+	// MovingAverage is the rate pooled over this bucket and the preceding ones, up to the requested amount of buckets.
+	MovingAverage float64 `json:"movingAverage"`
+}
@@ -5,18 +5,17 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"time"
 
 	"exusiai.dev/gommon/constant"
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/aws/aws-sdk-go-v2/service/s3/types"
-	"github.com/aws/smithy-go"
+	"github.com/parquet-go/parquet-go"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/errgroup"
 )
 
 const (
@@ -24,20 +23,53 @@ const (
 	FileExtParquet         = ".parquet"
 	LocalTempDirPattern    = "penguin_stats-archiver-*"
 	ArchiverChanBufferSize = 1000
+	// ParquetRowGroupSize is the amount of rows buffered in memory before they are flushed as a row group of
+	// parquet archives, so that a whole day of reports is never held in memory.
+	ParquetRowGroupSize = 10000
 )
 
-var ErrFileAlreadyExists = errors.New("file already exists")
+const (
+	FormatJsonlGzip = "jsonl"
+	FormatParquet   = "parquet"
+)
+
+var (
+	ErrFileAlreadyExists = errors.New("file already exists")
+	ErrFileNotFound      = errors.New("file not found")
+	ErrUnknownFormat     = errors.New("unknown archive format")
+	ErrParquetNotEnabled = errors.New("parquet format requires ParquetSchema and ParquetRowFunc")
+)
+
+// FileExt returns the file extension of the archive format.
+func FileExt(format string) (string, error) {
+	switch format {
+	case FormatJsonlGzip:
+		return FileExtJsonlGzip, nil
+	case FormatParquet:
+		return FileExtParquet, nil
+	default:
+		return "", errors.Wrap(ErrUnknownFormat, format)
+	}
+}
 
 type Archiver struct {
-	S3Client *s3.Client
-	S3Bucket string
+	Storage Storage
 
-	// S3Prefix is for the files in the bucket with no leading slash but optionally (typically) with trailing slash
+	// Prefix is for the files in the storage with no leading slash but optionally (typically) with trailing slash
 	// e.g. "v1/" or simply "" (empty string)
-	S3Prefix string
+	Prefix string
 
 	RealmName string
 
+	// Formats is the list of formats to archive into, e.g. []string{FormatJsonlGzip, FormatParquet}.
+	// Defaults to FormatJsonlGzip only when left empty.
+	Formats []string
+
+	// ParquetSchema and ParquetRowFunc are required for FormatParquet. ParquetRowFunc converts items sent to
+	// WriterCh() into rows conforming to ParquetSchema.
+	ParquetSchema  *parquet.Schema
+	ParquetRowFunc func(item any) any
+
 	date         time.Time
 	localTempDir string
 	writerCh     chan interface{}
@@ -54,6 +86,13 @@ func (a *Archiver) initLogger() {
 	}
 }
 
+func (a *Archiver) formats() []string {
+	if len(a.Formats) == 0 {
+		return []string{FormatJsonlGzip}
+	}
+	return a.Formats
+}
+
 func (a *Archiver) canonicalFilePath(fileExt string) string {
 	loc := constant.LocMap["CN"] // we use CN server's day start time as the day start time for all servers for archive
 	localT := a.date.In(loc)
@@ -71,13 +110,23 @@ func (a *Archiver) Prepare(ctx context.Context, date time.Time) error {
 	a.date = date
 	a.writerCh = make(chan interface{}, ArchiverChanBufferSize)
 
-	if err := a.assertS3FileNonExistence(ctx); err != nil {
-		return errors.Wrap(err, "failed to assertFileNonExistence")
+	for _, format := range a.formats() {
+		fileExt, err := FileExt(format)
+		if err != nil {
+			return err
+		}
+		if format == FormatParquet && (a.ParquetSchema == nil || a.ParquetRowFunc == nil) {
+			return ErrParquetNotEnabled
+		}
+
+		if err := a.assertFileNonExistence(ctx, fileExt); err != nil {
+			return errors.Wrap(err, "failed to assertFileNonExistence")
+		}
+		a.logger.Debug().
+			Str("evt.name", "archiver.prepare.assertFileNonExistence").
+			Str("canonicalFilePath", a.canonicalFilePath(fileExt)).
+			Msg("asserted file non-existence")
 	}
-	a.logger.Debug().
-		Str("evt.name", "archiver.prepare.assertFileNonExistence").
-		Str("canonicalFilePath", a.canonicalFilePath(FileExtJsonlGzip)).
-		Msg("asserted S3 file non-existence")
 
 	if err := a.createLocalTempDir(); err != nil {
 		return errors.Wrap(err, "failed to createLocalTempDir")
@@ -90,23 +139,16 @@ func (a *Archiver) Prepare(ctx context.Context, date time.Time) error {
 	return nil
 }
 
-func (a *Archiver) assertS3FileNonExistence(ctx context.Context) error {
-	key := a.S3Prefix + a.canonicalFilePath(FileExtJsonlGzip)
-	input := &s3.HeadObjectInput{
-		Bucket: aws.String(a.S3Bucket),
-		Key:    aws.String(key),
-	}
-	object, err := a.S3Client.HeadObject(ctx, input)
+func (a *Archiver) assertFileNonExistence(ctx context.Context, fileExt string) error {
+	key := a.Prefix + a.canonicalFilePath(fileExt)
+	object, err := a.Storage.Stat(ctx, key)
 	if err != nil {
-		var ae smithy.APIError
-		if errors.As(err, &ae) {
-			if ae.ErrorCode() == "NotFound" {
-				return nil
-			}
+		if errors.Is(err, ErrFileNotFound) {
+			return nil
 		}
-		return errors.Wrap(err, "failed to invoke HeadObject")
+		return errors.Wrap(err, "failed to stat file")
 	}
-	return errors.Wrap(ErrFileAlreadyExists, fmt.Sprintf("file \"%s\" already exists in s3 with LastModified \"%s\"", key, object.LastModified))
+	return errors.Wrap(ErrFileAlreadyExists, fmt.Sprintf("file \"%s\" already exists in storage with LastModified \"%s\"", key, object.LastModified))
 }
 
 func (a *Archiver) createLocalTempDir() error {
@@ -147,12 +189,19 @@ func (a *Archiver) Collect(ctx context.Context) error {
 		Str("evt.name", "archiver.collect.archiveToLocalFile").
 		Msg("archived to local file")
 
-	if err := a.uploadToS3(ctx); err != nil {
-		return errors.Wrap(err, "failed to uploadToS3")
+	for _, format := range a.formats() {
+		fileExt, err := FileExt(format)
+		if err != nil {
+			return err
+		}
+		if err := a.upload(ctx, fileExt); err != nil {
+			return errors.Wrap(err, "failed to upload")
+		}
+		a.logger.Debug().
+			Str("evt.name", "archiver.collect.upload").
+			Str("format", format).
+			Msg("uploaded to storage")
 	}
-	a.logger.Debug().
-		Str("evt.name", "archiver.collect.uploadToS3").
-		Msg("uploaded to S3")
 
 	if err := a.Cleanup(); err != nil {
 		return errors.Wrap(err, "failed to Cleanup")
@@ -161,20 +210,43 @@ func (a *Archiver) Collect(ctx context.Context) error {
 }
 
 func (a *Archiver) archiveToLocalFile(ctx context.Context) error {
-	jsonlGzipCh := make(chan any, ArchiverChanBufferSize)
-	go a.archiveToLocalJsonlGzipFile(ctx, jsonlGzipCh)
+	eg := errgroup.Group{}
+
+	formatChs := make([]chan any, 0, len(a.formats()))
+	for _, format := range a.formats() {
+		ch := make(chan any, ArchiverChanBufferSize)
+		formatChs = append(formatChs, ch)
+
+		var writeFn func(context.Context, <-chan any) error
+		switch format {
+		case FormatJsonlGzip:
+			writeFn = a.archiveToLocalJsonlGzipFile
+		case FormatParquet:
+			writeFn = a.archiveToLocalParquetFile
+		default:
+			return errors.Wrap(ErrUnknownFormat, format)
+		}
 
-	// parquetCh := make(chan any, ArchiverChanBufferSize)
-	// go a.archiveToLocalParquetFile(ctx, parquetCh)
+		eg.Go(func() error {
+			// keep draining the channel after a failure so that the fan-out below never blocks
+			defer func() {
+				for range ch {
+				}
+			}()
+			return writeFn(ctx, ch)
+		})
+	}
 
 	for item := range a.writerCh {
-		jsonlGzipCh <- item
-		// parquetCh <- item
+		for _, ch := range formatChs {
+			ch <- item
+		}
+	}
+	for _, ch := range formatChs {
+		close(ch)
 	}
-	close(jsonlGzipCh)
-	// close(parquetCh)
 
-	return nil
+	return eg.Wait()
 }
 
 func (a *Archiver) archiveToLocalJsonlGzipFile(ctx context.Context, itemCh <-chan any) error {
@@ -218,27 +290,136 @@ func (a *Archiver) archiveToLocalJsonlGzipFile(ctx context.Context, itemCh <-cha
 	}
 }
 
-func (a *Archiver) uploadToS3(ctx context.Context) error {
-	localTempFilePath := path.Join(a.localTempDir, a.canonicalFilePath(FileExtJsonlGzip))
+func (a *Archiver) archiveToLocalParquetFile(ctx context.Context, itemCh <-chan any) error {
+	localTempFilePath := path.Join(a.localTempDir, a.canonicalFilePath(FileExtParquet))
+	if err := a.ensureFileBaseDir(localTempFilePath); err != nil {
+		return errors.Wrap(err, "failed to ensureFileBaseDir")
+	}
+
+	parquetFile, err := os.OpenFile(localTempFilePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return errors.Wrap(err, "failed to open file")
+	}
+	defer parquetFile.Close()
+	a.logger.Debug().
+		Str("evt.name", "archiver.collect.archiveToLocalParquetFile.openFile").
+		Str("localTempFilePath", localTempFilePath).Msg("opened file, ready to write parquet stream")
+
+	parquetWriter := parquet.NewWriter(parquetFile, a.ParquetSchema, parquet.Compression(&parquet.Zstd), parquet.MaxRowsPerRowGroup(ParquetRowGroupSize))
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case item, ok := <-itemCh:
+			if !ok {
+				a.logger.Debug().
+					Str("evt.name", "archiver.collect.archiveToLocalParquetFile.itemChClosed").
+					Msg("itemCh closed, exiting archiveToLocalParquetFile (closing parquetWriter and file)")
+				return errors.Wrap(parquetWriter.Close(), "failed to close parquet writer")
+			}
+			if err := parquetWriter.Write(a.ParquetRowFunc(item)); err != nil {
+				return errors.Wrap(err, "failed to write parquet row")
+			}
+		}
+	}
+}
+
+func (a *Archiver) upload(ctx context.Context, fileExt string) error {
+	localTempFilePath := path.Join(a.localTempDir, a.canonicalFilePath(fileExt))
 	file, err := os.Open(localTempFilePath)
 	if err != nil {
 		return errors.Wrap(err, "failed to open file")
 	}
 	defer file.Close()
 
-	key := a.S3Prefix + a.canonicalFilePath(FileExtJsonlGzip)
-	if _, err := a.S3Client.PutObject(ctx, &s3.PutObjectInput{
-		Bucket:            aws.String(a.S3Bucket),
-		Key:               aws.String(key),
-		Body:              file,
-		StorageClass:      types.StorageClassGlacierIr,
-		ChecksumAlgorithm: types.ChecksumAlgorithmSha256,
-	}); err != nil {
-		return errors.Wrap(err, "failed to invoke PutObject")
+	key := a.Prefix + a.canonicalFilePath(fileExt)
+	return a.Storage.Put(ctx, key, file)
+}
+
+// PrepareRestore downloads the jsonl.gz archive of the date from the storage into a local temp directory, so that
+// it could be read (possibly multiple times) with ReadJsonlGzip afterwards. Caller MUST call Cleanup when done.
+func (a *Archiver) PrepareRestore(ctx context.Context, date time.Time) error {
+	a.initLogger()
+
+	a.logger.Info().
+		Str("evt.name", "archiver.prepare_restore").
+		Str("date", date.Format("2006-01-02")).
+		Msg("preparing archiver for restore")
+
+	a.date = date
+
+	if err := a.createLocalTempDir(); err != nil {
+		return errors.Wrap(err, "failed to createLocalTempDir")
+	}
+
+	if err := a.download(ctx, FileExtJsonlGzip); err != nil {
+		return errors.Wrap(err, "failed to download")
 	}
+	a.logger.Debug().
+		Str("evt.name", "archiver.prepare_restore.download").
+		Str("canonicalFilePath", a.canonicalFilePath(FileExtJsonlGzip)).
+		Msg("downloaded from storage")
+
 	return nil
 }
 
+func (a *Archiver) download(ctx context.Context, fileExt string) error {
+	key := a.Prefix + a.canonicalFilePath(fileExt)
+	body, err := a.Storage.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	localTempFilePath := path.Join(a.localTempDir, a.canonicalFilePath(fileExt))
+	if err := a.ensureFileBaseDir(localTempFilePath); err != nil {
+		return errors.Wrap(err, "failed to ensureFileBaseDir")
+	}
+
+	file, err := os.OpenFile(localTempFilePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return errors.Wrap(err, "failed to open file")
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, body); err != nil {
+		return errors.Wrap(err, "failed to write file")
+	}
+	return nil
+}
+
+// ReadJsonlGzip decodes every line of the jsonl.gz archive downloaded by PrepareRestore into T and calls fn with it,
+// in the order they have been archived. Reading stops at the first error returned by fn.
+func ReadJsonlGzip[T any](a *Archiver, fn func(item *T) error) error {
+	localTempFilePath := path.Join(a.localTempDir, a.canonicalFilePath(FileExtJsonlGzip))
+	file, err := os.Open(localTempFilePath)
+	if err != nil {
+		return errors.Wrap(err, "failed to open file")
+	}
+	defer file.Close()
+
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return errors.Wrap(err, "failed to open gzip stream")
+	}
+	defer gzipReader.Close()
+
+	decoder := json.NewDecoder(gzipReader)
+	for line := 1; ; line++ {
+		var item T
+		if err := decoder.Decode(&item); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return errors.Wrapf(err, "failed to decode item on line %d", line)
+		}
+		if err := fn(&item); err != nil {
+			return err
+		}
+	}
+}
+
 func (a *Archiver) Cleanup() error {
 	if err := os.RemoveAll(a.localTempDir); err != nil {
 		return errors.Wrap(err, "failed to remove temporary directory")
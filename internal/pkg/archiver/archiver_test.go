@@ -0,0 +1,117 @@
+package archiver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/pkg/errors"
+)
+
+type testItem struct {
+	ID int `json:"id"`
+}
+
+func TestArchiveAndRestoreWithLocalStorage(t *testing.T) {
+	ctx := context.Background()
+	date := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	a := &Archiver{
+		Storage:   &LocalStorage{Dir: t.TempDir()},
+		Prefix:    "v1/",
+		RealmName: "test_items",
+	}
+
+	if err := a.Prepare(ctx, date); err != nil {
+		t.Fatalf("failed to prepare: %v", err)
+	}
+	go func() {
+		for i := 1; i <= 3; i++ {
+			a.WriterCh() <- &testItem{ID: i}
+		}
+		close(a.WriterCh())
+	}()
+	if err := a.Collect(ctx); err != nil {
+		t.Fatalf("failed to collect: %v", err)
+	}
+
+	if err := a.Prepare(ctx, date); !errors.Is(err, ErrFileAlreadyExists) {
+		t.Errorf("expected ErrFileAlreadyExists on the second archive, got %v", err)
+	}
+
+	if err := a.PrepareRestore(ctx, date); err != nil {
+		t.Fatalf("failed to prepare restore: %v", err)
+	}
+	defer a.Cleanup()
+
+	var ids []int
+	if err := ReadJsonlGzip(a, func(item *testItem) error {
+		ids = append(ids, item.ID)
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+	if len(ids) != 3 || ids[0] != 1 || ids[2] != 3 {
+		t.Errorf("expected items [1 2 3], got %v", ids)
+	}
+
+	if err := a.PrepareRestore(ctx, date.AddDate(0, 0, 1)); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("expected ErrFileNotFound for a date never archived, got %v", err)
+	}
+}
+
+type testParquetRow struct {
+	ID int64 `parquet:"id"`
+}
+
+func TestArchiveParquetInBoundedRowGroups(t *testing.T) {
+	ctx := context.Background()
+	date := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+	a := &Archiver{
+		Storage:       &LocalStorage{Dir: dir},
+		Prefix:        "v1/",
+		RealmName:     "test_items",
+		Formats:       []string{FormatParquet},
+		ParquetSchema: parquet.SchemaOf(testParquetRow{}),
+		ParquetRowFunc: func(item any) any {
+			return &testParquetRow{ID: int64(item.(*testItem).ID)}
+		},
+	}
+
+	rows := ParquetRowGroupSize + 1
+	if err := a.Prepare(ctx, date); err != nil {
+		t.Fatalf("failed to prepare: %v", err)
+	}
+	go func() {
+		for i := 1; i <= rows; i++ {
+			a.WriterCh() <- &testItem{ID: i}
+		}
+		close(a.WriterCh())
+	}()
+	if err := a.Collect(ctx); err != nil {
+		t.Fatalf("failed to collect: %v", err)
+	}
+
+	f, err := os.Open(filepath.Join(dir, a.Prefix+a.canonicalFilePath(FileExtParquet)))
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		t.Fatalf("failed to stat archive: %v", err)
+	}
+	pf, err := parquet.OpenFile(f, stat.Size())
+	if err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+	if pf.NumRows() != int64(rows) {
+		t.Errorf("expected %d rows, got %d", rows, pf.NumRows())
+	}
+	if len(pf.RowGroups()) != 2 {
+		t.Errorf("expected rows to be flushed in 2 row groups, got %d", len(pf.RowGroups()))
+	}
+}
@@ -0,0 +1,186 @@
+package archiver
+
+import (
+	"context"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/pkg/errors"
+)
+
+var ErrInvalidKey = errors.New("invalid object key")
+
+// ObjectInfo describes an object already existing in a Storage.
+type ObjectInfo struct {
+	Size         int64
+	LastModified time.Time
+}
+
+// Storage is where the archives are stored. Keys are slash separated paths relative to the root of the storage,
+// e.g. "v1/drop_reports/drop_reports_2023-01-01.jsonl.gz".
+type Storage interface {
+	// Stat returns ErrFileNotFound if the object does not exist.
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	// Put uploads the object, overwriting an existing one with the same key.
+	Put(ctx context.Context, key string, body io.Reader) error
+	// Get returns ErrFileNotFound if the object does not exist. Caller MUST close the returned reader.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// S3Storage stores archives in an S3 bucket. It works with S3-compatible services (e.g. MinIO) as well,
+// as long as Client is configured with the endpoint of the service.
+type S3Storage struct {
+	Client *s3.Client
+	Bucket string
+
+	// StorageClass of the uploaded objects. Leave empty to use the default storage class of the bucket, which
+	// is required by most S3-compatible services since they do not support AWS specific storage classes.
+	StorageClass types.StorageClass
+}
+
+// ensure S3Storage conforms to Storage
+var _ Storage = (*S3Storage)(nil)
+
+func (s *S3Storage) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	object, err := s.Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if isS3NotFound(err) {
+			return nil, errors.Wrapf(ErrFileNotFound, "file \"%s\" does not exist in s3", key)
+		}
+		return nil, errors.Wrap(err, "failed to invoke HeadObject")
+	}
+
+	info := &ObjectInfo{
+		Size: aws.ToInt64(object.ContentLength),
+	}
+	if object.LastModified != nil {
+		info.LastModified = *object.LastModified
+	}
+	return info, nil
+}
+
+func (s *S3Storage) Put(ctx context.Context, key string, body io.Reader) error {
+	if _, err := s.Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:            aws.String(s.Bucket),
+		Key:               aws.String(key),
+		Body:              body,
+		StorageClass:      s.StorageClass,
+		ChecksumAlgorithm: types.ChecksumAlgorithmSha256,
+	}); err != nil {
+		return errors.Wrap(err, "failed to invoke PutObject")
+	}
+	return nil
+}
+
+func (s *S3Storage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	object, err := s.Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if isS3NotFound(err) {
+			return nil, errors.Wrapf(ErrFileNotFound, "file \"%s\" does not exist in s3", key)
+		}
+		return nil, errors.Wrap(err, "failed to invoke GetObject")
+	}
+	return object.Body, nil
+}
+
+func isS3NotFound(err error) bool {
+	var ae smithy.APIError
+	if errors.As(err, &ae) {
+		return ae.ErrorCode() == "NotFound" || ae.ErrorCode() == "NoSuchKey"
+	}
+	return false
+}
+
+// LocalStorage stores archives in a directory of the local filesystem, mainly for self-hosted deployments and CI.
+type LocalStorage struct {
+	Dir string
+}
+
+// ensure LocalStorage conforms to Storage
+var _ Storage = (*LocalStorage)(nil)
+
+func (s *LocalStorage) filePath(key string) (string, error) {
+	cleaned := path.Clean("/" + key)
+	if cleaned == "/" || strings.HasSuffix(key, "/") {
+		return "", errors.Wrap(ErrInvalidKey, key)
+	}
+	return filepath.Join(s.Dir, filepath.FromSlash(cleaned)), nil
+}
+
+func (s *LocalStorage) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	filePath, err := s.filePath(key)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.Wrapf(ErrFileNotFound, "file \"%s\" does not exist in local storage", key)
+		}
+		return nil, errors.Wrap(err, "failed to stat file")
+	}
+	return &ObjectInfo{
+		Size:         stat.Size(),
+		LastModified: stat.ModTime(),
+	}, nil
+}
+
+func (s *LocalStorage) Put(ctx context.Context, key string, body io.Reader) error {
+	filePath, err := s.filePath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return errors.Wrap(err, "failed to create directory")
+	}
+
+	// write to a temporary file first so that a partially written archive never shows up under the key
+	tempFile, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".*")
+	if err != nil {
+		return errors.Wrap(err, "failed to create temporary file")
+	}
+	defer os.Remove(tempFile.Name())
+
+	if _, err := io.Copy(tempFile, body); err != nil {
+		tempFile.Close()
+		return errors.Wrap(err, "failed to write file")
+	}
+	if err := tempFile.Close(); err != nil {
+		return errors.Wrap(err, "failed to close file")
+	}
+	if err := os.Rename(tempFile.Name(), filePath); err != nil {
+		return errors.Wrap(err, "failed to rename file")
+	}
+	return nil
+}
+
+func (s *LocalStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	filePath, err := s.filePath(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.Wrapf(ErrFileNotFound, "file \"%s\" does not exist in local storage", key)
+		}
+		return nil, errors.Wrap(err, "failed to open file")
+	}
+	return file, nil
+}
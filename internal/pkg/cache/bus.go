@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"exusiai.dev/backend-next/internal/pkg/observability"
+)
+
+// Invalidation is the eviction of an entry of a cache, or of all of its entries if Flush, which is broadcast to
+// other instances so that they evict the same entries from their own process-local caches.
+type Invalidation struct {
+	// Name is the prefix of a Set, or the key of a Singular.
+	Name  string `json:"name"`
+	Key   string `json:"key,omitempty"`
+	Flush bool   `json:"flush,omitempty"`
+}
+
+var (
+	registryMu sync.RWMutex
+	// registry maps cache names to functions evicting a key of the cache, or all keys if flush
+	registry = make(map[string]func(key string, flush bool))
+
+	broadcasterMu sync.RWMutex
+	broadcaster   func(Invalidation)
+)
+
+// SetBroadcaster sets the function every local invalidation is broadcast with. Invalidations are not broadcast
+// until it is set.
+func SetBroadcaster(fn func(Invalidation)) {
+	broadcasterMu.Lock()
+	defer broadcasterMu.Unlock()
+	broadcaster = fn
+}
+
+// Invalidate applies an invalidation received from another instance to the local cache, without broadcasting it
+// again. It returns false if there is no cache with the name.
+func Invalidate(invalidation Invalidation) bool {
+	registryMu.RLock()
+	evict, ok := registry[invalidation.Name]
+	registryMu.RUnlock()
+	if !ok {
+		return false
+	}
+
+	evict(invalidation.Key, invalidation.Flush)
+	observability.CacheInvalidations.WithLabelValues(invalidation.Name, "remote").Inc()
+	return true
+}
+
+func register(name string, evict func(key string, flush bool)) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = evict
+}
+
+func broadcast(invalidation Invalidation) {
+	observability.CacheInvalidations.WithLabelValues(invalidation.Name, "local").Inc()
+
+	broadcasterMu.RLock()
+	fn := broadcaster
+	broadcasterMu.RUnlock()
+	if fn != nil {
+		fn(invalidation)
+	}
+}
+
+// metrics holds the hit and miss counters of a cache, resolved once to keep lookups cheap.
+type metrics struct {
+	hits   prometheus.Counter
+	misses prometheus.Counter
+}
+
+func newMetrics(name string) metrics {
+	return metrics{
+		hits:   observability.CacheRequests.WithLabelValues(name, "hit"),
+		misses: observability.CacheRequests.WithLabelValues(name, "miss"),
+	}
+}
+
+func (m metrics) observe(hit bool) {
+	if hit {
+		m.hits.Inc()
+	} else {
+		m.misses.Inc()
+	}
+}
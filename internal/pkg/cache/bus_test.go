@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBroadcastAndInvalidate(t *testing.T) {
+	var broadcasted []Invalidation
+	SetBroadcaster(func(invalidation Invalidation) {
+		broadcasted = append(broadcasted, invalidation)
+	})
+	defer SetBroadcaster(nil)
+
+	set := NewSet[int]("testBus#key")
+	set.Set("a", 1, time.Minute)
+	set.Set("b", 2, time.Minute)
+
+	if err := set.Delete("a"); err != nil {
+		t.Fatalf("Expected no error deleting key, got %v", err)
+	}
+	if len(broadcasted) != 1 || broadcasted[0] != (Invalidation{Name: "testBus#key", Key: "a"}) {
+		t.Fatalf("Expected deletion of key 'a' to be broadcast, got %+v", broadcasted)
+	}
+
+	// applying a remote invalidation evicts the entry without broadcasting it again
+	if !Invalidate(Invalidation{Name: "testBus#key", Key: "b"}) {
+		t.Fatalf("Expected invalidation of a registered cache to be applied")
+	}
+	var v int
+	if err := set.Get("b", &v); err != ErrNotFound {
+		t.Errorf("Expected key 'b' to be evicted, got value %d", v)
+	}
+	if len(broadcasted) != 1 {
+		t.Errorf("Expected remote invalidation not to be broadcast, got %+v", broadcasted)
+	}
+
+	singular := NewSingular[int]("testBusSingular")
+	singular.Set(3, time.Minute)
+	if !Invalidate(Invalidation{Name: "testBusSingular", Flush: true}) {
+		t.Fatalf("Expected invalidation of a registered singular to be applied")
+	}
+	if err := singular.Get(&v); err != ErrNotFound {
+		t.Errorf("Expected singular to be evicted, got value %d", v)
+	}
+
+	if Invalidate(Invalidation{Name: "testBus#unknown", Flush: true}) {
+		t.Errorf("Expected invalidation of an unknown cache not to be applied")
+	}
+}
@@ -10,19 +10,26 @@ import (
 )
 
 func NewSet[T any](prefix string) *Set[T] {
-	return &Set[T]{
-		prefix: prefix + ":",
-		c:      cache.New(cache.NoExpiration, time.Minute*10),
+	s := &Set[T]{
+		name:    prefix,
+		prefix:  prefix + ":",
+		c:       cache.New(cache.NoExpiration, time.Minute*10),
+		metrics: newMetrics(prefix),
 	}
+	register(prefix, s.evict)
+	return s
 }
 
 type Set[T any] struct {
 	// m is a mutex for MutexGetSet for concurrent prevention
 	m sync.Mutex
 
+	name   string
 	prefix string
 
 	c *cache.Cache
+
+	metrics metrics
 }
 
 func (c *Set[T]) key(key string) string {
@@ -30,6 +37,12 @@ func (c *Set[T]) key(key string) string {
 }
 
 func (c *Set[T]) Get(key string, dest *T) error {
+	err := c.get(key, dest)
+	c.metrics.observe(err == nil)
+	return err
+}
+
+func (c *Set[T]) get(key string, dest *T) error {
 	key = c.key(key)
 	result, ok := c.c.Get(key)
 	if !ok {
@@ -75,7 +88,7 @@ func (c *Set[T]) MutexGetSet(key string, dest *T, valueFunc func() (*T, error),
 func (c *Set[T]) slowMutexGetSet(key string, dest *T, valueFunc func() (*T, error), expire time.Duration) error {
 	c.m.Lock()
 	defer c.m.Unlock()
-	err := c.Get(key, dest)
+	err := c.get(key, dest)
 
 	if err == nil {
 		return nil
@@ -105,17 +118,28 @@ func (c *Set[T]) slowMutexGetSet(key string, dest *T, valueFunc func() (*T, erro
 	return nil
 }
 
+// Delete deletes the key from the cache, on this and, once the broadcaster is set, every other instance.
 func (c *Set[T]) Delete(key string) error {
-	key = c.key(key)
-	if l := log.Trace(); l.Enabled() {
-		l.Str("key", key).Msg("deleting value from cache")
-	}
-	c.c.Delete(key)
-
+	c.evict(key, false)
+	broadcast(Invalidation{Name: c.name, Key: key})
 	return nil
 }
 
+// Flush deletes all keys from the cache, on this and, once the broadcaster is set, every other instance.
 func (c *Set[T]) Flush() error {
-	c.c.Flush()
+	c.evict("", true)
+	broadcast(Invalidation{Name: c.name, Flush: true})
 	return nil
 }
+
+func (c *Set[T]) evict(key string, flush bool) {
+	if flush {
+		c.c.Flush()
+		return
+	}
+	key = c.key(key)
+	if l := log.Trace(); l.Enabled() {
+		l.Str("key", key).Msg("deleting value from cache")
+	}
+	c.c.Delete(key)
+}
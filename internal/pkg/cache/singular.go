@@ -10,10 +10,13 @@ import (
 )
 
 func NewSingular[T any](key string) *Singular[T] {
-	return &Singular[T]{
-		key: key,
-		c:   cache.New(cache.NoExpiration, time.Minute*10),
+	s := &Singular[T]{
+		key:     key,
+		c:       cache.New(cache.NoExpiration, time.Minute*10),
+		metrics: newMetrics(key),
 	}
+	register(key, s.evict)
+	return s
 }
 
 type Singular[T any] struct {
@@ -23,9 +26,17 @@ type Singular[T any] struct {
 	key string
 
 	c *cache.Cache
+
+	metrics metrics
 }
 
 func (c *Singular[T]) Get(dest *T) error {
+	err := c.get(dest)
+	c.metrics.observe(err == nil)
+	return err
+}
+
+func (c *Singular[T]) get(dest *T) error {
 	result, ok := c.c.Get(c.key)
 	if !ok {
 		return ErrNotFound
@@ -63,7 +74,7 @@ func (c *Singular[T]) MutexGetSet(dest *T, valueFunc func() (T, error), expire t
 func (c *Singular[T]) slowMutexGetSet(dest *T, valueFunc func() (T, error), expire time.Duration) error {
 	c.m.Lock()
 	defer c.m.Unlock()
-	err := c.Get(dest)
+	err := c.get(dest)
 
 	if err == nil {
 		return nil
@@ -90,7 +101,13 @@ func (c *Singular[T]) slowMutexGetSet(dest *T, valueFunc func() (T, error), expi
 	return nil
 }
 
+// Delete deletes the value from the cache, on this and, once the broadcaster is set, every other instance.
 func (c *Singular[T]) Delete() error {
-	c.c.Flush()
+	c.evict("", true)
+	broadcast(Invalidation{Name: c.key, Flush: true})
 	return nil
 }
+
+func (c *Singular[T]) evict(_ string, _ bool) {
+	c.c.Flush()
+}
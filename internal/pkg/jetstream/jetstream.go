@@ -6,6 +6,20 @@ import (
 	"github.com/nats-io/nats.go"
 )
 
+const (
+	ReportStream = "penguin-reports"
+
+	// ReportDeadLetterStream keeps the report tasks that failed to be processed after all of their deliveries.
+	// Its subjects are the original subjects prefixed with ReportDeadLetterSubjectPrefix.
+	ReportDeadLetterStream        = "penguin-reports-dlq"
+	ReportDeadLetterSubjectPrefix = "DLQ."
+
+	// headers set on dead-lettered messages
+	HeaderOriginalSubject = "Penguin-Original-Subject"
+	HeaderDeliveries      = "Penguin-Deliveries"
+	HeaderError           = "Penguin-Error"
+)
+
 func MessageID(pair nats.SequencePair) string {
 	return "seq:" + strconv.FormatUint(pair.Consumer, 10)
 }
@@ -0,0 +1,25 @@
+package middlewares
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
+)
+
+// MatrixLimiter limits requests of the endpoints computing results from the drop matrices, which are updated
+// periodically and should not be requested too frequently. Requests for which next returns true are not
+// limited; all requests are limited if next is nil.
+func MatrixLimiter(next func(c *fiber.Ctx) bool) fiber.Handler {
+	return limiter.New(limiter.Config{
+		Next: next,
+		LimitReached: func(c *fiber.Ctx) error {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"code":    "TOO_MANY_REQUESTS",
+				"message": "Your client is sending requests too frequently. The Penguin Stats result matrix are updated periodically and should not be requested too frequently.",
+			})
+		},
+		Max:        300,
+		Expiration: time.Minute * 5,
+	})
+}
@@ -29,8 +29,36 @@ var (
 		Name: prometheus.BuildFQName(ServiceName, "report", "reliability"),
 		Help: "Reliability distribution of report consumption",
 	}, []string{"reliability", "source_name"})
+	ReportRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: prometheus.BuildFQName(ServiceName, "report", "retries_total"),
+		Help: "Amount of report tasks which failed to be processed and were scheduled to be redelivered",
+	}, []string{})
+	ReportDeadLettered = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: prometheus.BuildFQName(ServiceName, "report", "dead_lettered_total"),
+		Help: "Amount of report tasks which were moved to the dead letter stream",
+	}, []string{})
 	WorkerCalcDuration = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Name: prometheus.BuildFQName(ServiceName, "worker", "calc_duration_seconds"),
 		Help: "Duration of last worker calculation in seconds",
 	}, []string{"service", "server"})
+	DropDriftSignificant = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: prometheus.BuildFQName(ServiceName, "drop", "drift_significant"),
+		Help: "Amount of stage/item pairs whose drop rate drifted significantly from the previous time range",
+	}, []string{"server", "source_category"})
+	DropDriftTScore = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: prometheus.BuildFQName(ServiceName, "drop", "drift_t_score"),
+		Help: "T-score of the drop rate drift of stage/item pairs whose drift is significant",
+	}, []string{"server", "source_category", "stage", "item"})
+	MatrixReconcileDriftedStages = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: prometheus.BuildFQName(ServiceName, "matrix", "reconcile_drifted_stages"),
+		Help: "Amount of stages whose incrementally maintained matrix elements drifted from the drop reports at the last reconciliation",
+	}, []string{"server", "matrix"})
+	CacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: prometheus.BuildFQName(ServiceName, "cache", "requests_total"),
+		Help: "Amount of cache lookups by cache and result (hit or miss)",
+	}, []string{"cache", "result"})
+	CacheInvalidations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: prometheus.BuildFQName(ServiceName, "cache", "invalidations_total"),
+		Help: "Amount of cache invalidations by cache and origin (local or remote)",
+	}, []string{"cache", "origin"})
 )
@@ -16,6 +16,8 @@ func Module() fx.Option {
 		NewDropInfo,
 		NewProperty,
 		NewSnapshot,
+		NewSiteStatsDaily,
+		NewShortLink,
 		NewTimeRange,
 		NewDropReport,
 		NewRejectRule,
@@ -63,6 +63,72 @@ func (r *Account) CreateAccountWithRandomPenguinId(ctx context.Context) (*model.
 	return nil, pgerr.ErrInternalError.Msg("failed to create account")
 }
 
+// RegeneratePenguinId assigns a new random PenguinID to the account. Everything else of the account, including
+// its reports, is kept as-is since they reference the account by its id.
+func (r *Account) RegeneratePenguinId(ctx context.Context, accountId int) (*model.Account, error) {
+	// retry if the PenguinID has been taken by another account
+	for i := 0; i < AccountMaxRetries; i++ {
+		account := &model.Account{
+			AccountID: accountId,
+			PenguinID: pgid.New(),
+		}
+
+		res, err := r.db.NewUpdate().
+			Model(account).
+			Column("penguin_id").
+			WherePK().
+			Returning("*").
+			Exec(ctx)
+		if err != nil {
+			log.Warn().
+				Str("evt.name", "account.regenerate.retry").
+				Err(err).
+				Int("retry", i).
+				Msg("failed to regenerate PenguinID. retrying...")
+			continue
+		}
+		if rowsAffected, err := res.RowsAffected(); err == nil && rowsAffected == 0 {
+			return nil, pgerr.ErrNotFound
+		}
+
+		return account, nil
+	}
+
+	return nil, pgerr.ErrInternalError.Msg("failed to regenerate PenguinID")
+}
+
+// UpdateAccountWeight sets the weight of the account.
+func (r *Account) UpdateAccountWeight(ctx context.Context, accountId int, weight float64) (*model.Account, error) {
+	account := &model.Account{
+		AccountID: accountId,
+		Weight:    weight,
+	}
+
+	res, err := r.db.NewUpdate().
+		Model(account).
+		Column("weight").
+		WherePK().
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected, err := res.RowsAffected(); err == nil && rowsAffected == 0 {
+		return nil, pgerr.ErrNotFound
+	}
+	return account, nil
+}
+
+// UpdateAccountWeights sets the weights of the accounts, identified by their AccountID, in bulk.
+func (r *Account) UpdateAccountWeights(ctx context.Context, db bun.IDB, accounts []*model.Account) error {
+	_, err := db.NewUpdate().
+		Model(&accounts).
+		Column("weight").
+		Bulk().
+		Exec(ctx)
+	return err
+}
+
 func (r *Account) GetAccountById(ctx context.Context, accountId string) (*model.Account, error) {
 	return r.sel.SelectOne(ctx, func(q *bun.SelectQuery) *bun.SelectQuery {
 		return q.Where("account_id = ?", accountId)
@@ -2,10 +2,13 @@ package repo
 
 import (
 	"context"
+	"database/sql"
 
+	"github.com/pkg/errors"
 	"github.com/uptrace/bun"
 
 	"exusiai.dev/backend-next/internal/model"
+	"exusiai.dev/backend-next/internal/pkg/pgerr"
 )
 
 type Admin struct {
@@ -55,3 +58,66 @@ func (r *Admin) SaveDropInfos(ctx context.Context, tx bun.Tx, dropInfos *[]*mode
 		Exec(ctx)
 	return err
 }
+
+// GetGameDataForUpdate loads the game data of the primary key set in m, and locks it until tx ends.
+func (r *Admin) GetGameDataForUpdate(ctx context.Context, tx bun.Tx, m any) error {
+	err := tx.NewSelect().
+		Model(m).
+		WherePK().
+		For("UPDATE").
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return pgerr.ErrNotFound
+	}
+	return err
+}
+
+func (r *Admin) UpdateGameData(ctx context.Context, tx bun.Tx, m any) error {
+	_, err := tx.NewUpdate().
+		Model(m).
+		WherePK().
+		Exec(ctx)
+	return err
+}
+
+func (r *Admin) DeleteGameData(ctx context.Context, tx bun.Tx, m any) error {
+	_, err := tx.NewDelete().
+		Model(m).
+		WherePK().
+		Exec(ctx)
+	return err
+}
+
+// IsGameDataReferenced reports whether any row of table references id by column.
+func (r *Admin) IsGameDataReferenced(ctx context.Context, tx bun.Tx, table string, column string, id int) (bool, error) {
+	return tx.NewSelect().
+		TableExpr("?", bun.Ident(table)).
+		Where("? = ?", bun.Ident(column), id).
+		Exists(ctx)
+}
+
+func (r *Admin) CreateAdminAuditLog(ctx context.Context, tx bun.Tx, auditLog *model.AdminAuditLog) error {
+	_, err := tx.NewInsert().
+		Model(auditLog).
+		Exec(ctx)
+	return err
+}
+
+// GetAdminAuditLogs returns the most recent audit logs first. entity and entityId are optional filters.
+func (r *Admin) GetAdminAuditLogs(ctx context.Context, entity string, entityId int, limit int) ([]*model.AdminAuditLog, error) {
+	auditLogs := make([]*model.AdminAuditLog, 0)
+	query := r.db.NewSelect().
+		Model(&auditLogs).
+		Order("log_id DESC").
+		Limit(limit)
+	if entity != "" {
+		query = query.Where("entity = ?", entity)
+	}
+	if entityId > 0 {
+		query = query.Where("entity_id = ?", entityId)
+	}
+	if err := query.Scan(ctx); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	return auditLogs, nil
+}
@@ -58,6 +58,72 @@ func (s *DropMatrixElement) GetElementsByServerAndSourceCategoryAndDayNumRange(
 	return elements, nil
 }
 
+func (s *DropMatrixElement) GetElementsByServerAndStageIdAndTimeRange(
+	ctx context.Context, server string, stageId int, timeRange *model.TimeRange, sourceCategory string,
+) ([]*model.DropMatrixElement, error) {
+	var elements []*model.DropMatrixElement
+	err := s.db.NewSelect().Model(&elements).
+		Where("server = ?", server).
+		Where("source_category = ?", sourceCategory).
+		Where("stage_id = ?", stageId).
+		Where("start_time >= timestamp with time zone ?", timeRange.StartTime.Format(time.RFC3339)).
+		Where("end_time <= timestamp with time zone ?", timeRange.EndTime.Format(time.RFC3339)).
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return elements, nil
+}
+
+// GetElementsByServerAndDayNum returns the elements of all source categories on the day, of the stages only if
+// stageIds is not empty.
+func (s *DropMatrixElement) GetElementsByServerAndDayNum(ctx context.Context, db bun.IDB, server string, dayNum int, stageIds []int) ([]*model.DropMatrixElement, error) {
+	elements := make([]*model.DropMatrixElement, 0)
+	query := db.NewSelect().
+		Model(&elements).
+		Where("server = ?", server).
+		Where("day_num = ?", dayNum)
+	if len(stageIds) > 0 {
+		query = query.Where("stage_id IN (?)", bun.In(stageIds))
+	}
+	if err := query.Scan(ctx); err != nil {
+		return nil, err
+	}
+	return elements, nil
+}
+
+func (s *DropMatrixElement) CreateElements(ctx context.Context, db bun.IDB, elements []*model.DropMatrixElement) error {
+	_, err := db.NewInsert().Model(&elements).Exec(ctx)
+	return err
+}
+
+// UpdateElementCounters updates the counters of the elements by their IDs.
+func (s *DropMatrixElement) UpdateElementCounters(ctx context.Context, db bun.IDB, elements []*model.DropMatrixElement) error {
+	_, err := db.NewUpdate().
+		Model(&elements).
+		Column("quantity", "times", "quantity_buckets").
+		Bulk().
+		Exec(ctx)
+	return err
+}
+
+func (s *DropMatrixElement) DeleteElementsByIds(ctx context.Context, db bun.IDB, elementIds []int) error {
+	_, err := db.NewDelete().Model((*model.DropMatrixElement)(nil)).Where("element_id IN (?)", bun.In(elementIds)).Exec(ctx)
+	return err
+}
+
+func (s *DropMatrixElement) DeleteByServerAndDayNumAndStageIds(ctx context.Context, db bun.IDB, server string, dayNum int, stageIds []int) error {
+	_, err := db.NewDelete().
+		Model((*model.DropMatrixElement)(nil)).
+		Where("server = ?", server).
+		Where("day_num = ?", dayNum).
+		Where("stage_id IN (?)", bun.In(stageIds)).
+		Exec(ctx)
+	return err
+}
+
 func (s *DropMatrixElement) IsExistByServerAndDayNum(ctx context.Context, server string, dayNum int) (bool, error) {
 	exists, err := s.db.NewSelect().Model((*model.DropMatrixElement)(nil)).Where("server = ?", server).Where("day_num = ?", dayNum).Exists(ctx)
 	if err != nil {
@@ -38,8 +38,36 @@ func (r *DropReport) CreateDropReport(ctx context.Context, tx bun.Tx, dropReport
 	return err
 }
 
-func (r *DropReport) DeleteDropReport(ctx context.Context, reportId int) error {
-	_, err := r.db.NewUpdate().
+// GetDropReportsForUpdate returns the drop reports of reportIds, locking them until tx ends.
+func (r *DropReport) GetDropReportsForUpdate(ctx context.Context, tx bun.Tx, reportIds []int) ([]*model.DropReport, error) {
+	results := make([]*model.DropReport, 0, len(reportIds))
+	if err := tx.NewSelect().
+		Model(&results).
+		Where("report_id IN (?)", bun.In(reportIds)).
+		For("UPDATE").
+		Scan(ctx); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// GetDropReportsByAccountIdForUpdate is GetDropReportsForUpdate for the reports of reportIds which belong to the
+// account only, so that callers acting on behalf of an account never lock reports of other accounts.
+func (r *DropReport) GetDropReportsByAccountIdForUpdate(ctx context.Context, tx bun.Tx, accountId int, reportIds []int) ([]*model.DropReport, error) {
+	results := make([]*model.DropReport, 0, len(reportIds))
+	if err := tx.NewSelect().
+		Model(&results).
+		Where("account_id = ?", accountId).
+		Where("report_id IN (?)", bun.In(reportIds)).
+		For("UPDATE").
+		Scan(ctx); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (r *DropReport) DeleteDropReport(ctx context.Context, tx bun.Tx, reportId int) error {
+	_, err := tx.NewUpdate().
 		Model((*model.DropReport)(nil)).
 		Set("reliability = ?", -1).
 		Where("report_id = ?", reportId).
@@ -56,6 +84,131 @@ func (r *DropReport) UpdateDropReportReliability(ctx context.Context, tx bun.Tx,
 	return err
 }
 
+// GetDropReportsByAccountId returns the non-deleted drop reports of the account in descending order of report id.
+// Only reports with report id less than beforeId are returned when beforeId is positive.
+func (r *DropReport) GetDropReportsByAccountId(ctx context.Context, accountId int, beforeId int, limit int) ([]*model.DropReport, error) {
+	results := make([]*model.DropReport, 0, limit)
+	query := r.db.NewSelect().
+		Model(&results).
+		Where("account_id = ?", accountId).
+		Where("reliability >= 0").
+		Order("report_id DESC").
+		Limit(limit)
+	if beforeId > 0 {
+		query = query.Where("report_id < ?", beforeId)
+	}
+	if err := query.Scan(ctx); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// GetAllDropReportsByAccountId is the same as GetDropReportsByAccountId, but includes the reports of any
+// reliability, e.g. the recalled and the rejected ones.
+func (r *DropReport) GetAllDropReportsByAccountId(ctx context.Context, accountId int, beforeId int, limit int) ([]*model.DropReport, error) {
+	results := make([]*model.DropReport, 0, limit)
+	query := r.db.NewSelect().
+		Model(&results).
+		Where("account_id = ?", accountId).
+		Order("report_id DESC").
+		Limit(limit)
+	if beforeId > 0 {
+		query = query.Where("report_id < ?", beforeId)
+	}
+	if err := query.Scan(ctx); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (r *DropReport) CountDropReportsByAccountId(ctx context.Context, accountId int) (int, error) {
+	return r.db.NewSelect().
+		Model((*model.DropReport)(nil)).
+		Where("account_id = ?", accountId).
+		Where("reliability >= 0").
+		Count(ctx)
+}
+
+// RecallDropReportsByAccountId marks the given drop reports of the account as recalled, in the same way as
+// DeleteDropReport does. Reports not belonging to the account are left untouched.
+// returns the report ids actually recalled and error
+func (r *DropReport) RecallDropReportsByAccountId(ctx context.Context, tx bun.Tx, accountId int, reportIds []int) ([]int, error) {
+	var recalledIds []int
+	err := tx.NewUpdate().
+		Model((*model.DropReport)(nil)).
+		Set("reliability = ?", -1).
+		Where("account_id = ?", accountId).
+		Where("report_id IN (?)", bun.In(reportIds)).
+		Where("reliability >= 0").
+		Returning("report_id").
+		Scan(ctx, &recalledIds)
+	if err != nil {
+		return nil, err
+	}
+	return recalledIds, nil
+}
+
+// DeleteDropReportsByAccountId permanently deletes the given drop reports of the account. Reports not
+// belonging to the account are left untouched.
+// returns the report ids actually deleted and error
+func (r *DropReport) DeleteDropReportsByAccountId(ctx context.Context, tx bun.Tx, accountId int, reportIds []int) ([]int, error) {
+	var deletedIds []int
+	err := tx.NewDelete().
+		Model((*model.DropReport)(nil)).
+		Where("account_id = ?", accountId).
+		Where("report_id IN (?)", bun.In(reportIds)).
+		Returning("report_id").
+		Scan(ctx, &deletedIds)
+	if err != nil {
+		return nil, err
+	}
+	return deletedIds, nil
+}
+
+// StreamDropReportsForExport reads reliable drop reports matching the filter in the order of report id,
+// and calls fn for each of them as soon as it is read from the database, so that the whole result is never
+// held in memory. Item filter matches all reports of stages that could drop any of the items.
+func (r *DropReport) StreamDropReportsForExport(ctx context.Context, filter *model.DropReportExportFilter, fn func(row *model.DropReportExportRow) error) error {
+	query := r.db.NewSelect().
+		TableExpr("drop_reports AS dr").
+		ColumnExpr("dr.report_id, dr.created_at, dr.server, st.ark_stage_id, dr.pattern_id, dr.times, dr.source_name, dr.version").
+		Join("JOIN stages AS st ON st.stage_id = dr.stage_id").
+		Where("dr.reliability = 0").
+		Order("dr.report_id ASC")
+
+	r.handleServer(query, filter.Server)
+	r.handleCreatedAtWithTime(query, filter.StartTime, filter.EndTime)
+	r.handleSourceName(query, filter.SourceCategory)
+	if len(filter.StageIDs) > 0 {
+		query = query.Where("dr.stage_id IN (?)", bun.In(filter.StageIDs))
+	}
+	if len(filter.ItemIDs) > 0 {
+		query = query.Where("dr.stage_id IN (?)", r.db.NewSelect().
+			TableExpr("drop_infos AS di").
+			ColumnExpr("DISTINCT di.stage_id").
+			Where("di.server = ?", filter.Server).
+			Where("di.item_id IN (?)", bun.In(filter.ItemIDs)))
+	}
+
+	rows, err := query.Rows(ctx)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row model.DropReportExportRow
+		if err := r.db.ScanRow(ctx, rows, &row); err != nil {
+			return err
+		}
+		if err := fn(&row); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
 // only filtered by stage_id, not item_id, needs post-filtering
 func (r *DropReport) CalcQuantityUniqCount(
 	ctx context.Context, queryCtx *model.DropReportQueryContext,
@@ -76,11 +229,13 @@ func (r *DropReport) CalcQuantityUniqCount(
 	r.handleCreatedAtWithTime(subq1, queryCtx.StartTime, queryCtx.EndTime)
 	r.handleServer(subq1, queryCtx.Server)
 	r.handleStages(subq1, queryCtx.GetStageIds())
+	r.handleCountry(subq1, queryCtx.Country)
+	r.handleWeight(subq1, queryCtx.Weighted)
 
 	mainq := r.db.NewSelect().
 		TableExpr("(?) AS a", subq1).
 		Column("stage_id", "item_id", "quantity").
-		ColumnExpr("COUNT(*) AS count")
+		ColumnExpr(weightedCount(queryCtx.Weighted) + " AS count")
 	r.handleSourceName(mainq, queryCtx.SourceCategory)
 
 	if err := mainq.
@@ -109,11 +264,13 @@ func (r *DropReport) CalcTotalTimes(
 	if len(stageIds) > 0 {
 		r.handleStages(subq1, stageIds)
 	}
+	r.handleCountry(subq1, queryCtx.Country)
+	r.handleWeight(subq1, queryCtx.Weighted)
 
 	mainq := r.db.NewSelect().
 		TableExpr("(?) AS a", subq1).
 		Column("stage_id").
-		ColumnExpr("SUM(times) AS total_times")
+		ColumnExpr(weightedSum("times", queryCtx.Weighted) + " AS total_times")
 	r.handleSourceName(mainq, queryCtx.SourceCategory)
 
 	if err := mainq.
@@ -140,11 +297,12 @@ func (r *DropReport) CalcTotalQuantityForPatternMatrix(
 	r.handleServer(subq1, queryCtx.Server)
 	r.handleStages(subq1, queryCtx.GetStageIds())
 	r.handleTimes(subq1, 1)
+	r.handleWeight(subq1, queryCtx.Weighted)
 
 	mainq := r.db.NewSelect().
 		TableExpr("(?) AS a", subq1).
 		Column("stage_id", "pattern_id").
-		ColumnExpr("COUNT(*) AS total_quantity")
+		ColumnExpr(weightedCount(queryCtx.Weighted) + " AS total_quantity")
 	r.handleSourceName(mainq, queryCtx.SourceCategory)
 
 	if err := mainq.
@@ -155,8 +313,54 @@ func (r *DropReport) CalcTotalQuantityForPatternMatrix(
 	return results, nil
 }
 
+// CalcAccountStageTimes returns the total times and the amount of reliable reports of every account in every
+// stage of every server within the time range.
+func (r *DropReport) CalcAccountStageTimes(
+	ctx context.Context, startTime *time.Time, endTime *time.Time,
+) ([]*model.AccountStageTimesResult, error) {
+	results := make([]*model.AccountStageTimesResult, 0)
+
+	query := r.db.NewSelect().
+		TableExpr("drop_reports AS dr").
+		Column("dr.server", "dr.account_id", "dr.stage_id").
+		ColumnExpr("SUM(dr.times) AS total_times").
+		ColumnExpr("COUNT(*) AS report_count")
+	r.handleAccountAndReliability(query, null.NewInt(0, false))
+	r.handleCreatedAtWithTime(query, startTime, endTime)
+
+	if err := query.
+		Group("dr.server", "dr.account_id", "dr.stage_id").
+		Scan(ctx, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// CalcAccountStageItemQuantities returns the total quantity of every item dropped to every account in every
+// stage of every server within the time range, counting reliable reports only.
+func (r *DropReport) CalcAccountStageItemQuantities(
+	ctx context.Context, startTime *time.Time, endTime *time.Time,
+) ([]*model.AccountStageItemQuantityResult, error) {
+	results := make([]*model.AccountStageItemQuantityResult, 0)
+
+	query := r.db.NewSelect().
+		TableExpr("drop_reports AS dr").
+		Column("dr.server", "dr.account_id", "dr.stage_id", "dpe.item_id").
+		ColumnExpr("SUM(dpe.quantity) AS total_quantity").
+		Join("JOIN drop_pattern_elements AS dpe ON dpe.drop_pattern_id = dr.pattern_id")
+	r.handleAccountAndReliability(query, null.NewInt(0, false))
+	r.handleCreatedAtWithTime(query, startTime, endTime)
+
+	if err := query.
+		Group("dr.server", "dr.account_id", "dr.stage_id", "dpe.item_id").
+		Scan(ctx, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
 func (r *DropReport) CalcTotalQuantityForTrend(
-	ctx context.Context, server string, startTime *time.Time, intervalLength time.Duration, intervalNum int, stageIdItemIdMap map[int][]int, accountId null.Int, sourceCategory string,
+	ctx context.Context, server string, startTime *time.Time, intervalLength time.Duration, intervalNum int, stageIdItemIdMap map[int][]int, accountId null.Int, sourceCategory string, country string, weighted bool,
 ) ([]*model.TotalQuantityResultForTrend, error) {
 	results := make([]*model.TotalQuantityResultForTrend, 0)
 	if len(stageIdItemIdMap) == 0 {
@@ -177,11 +381,13 @@ func (r *DropReport) CalcTotalQuantityForTrend(
 	r.handleCreatedAtWithTime(subq1, &gameDayStart, &lastDayEnd)
 	r.handleServer(subq1, server)
 	r.handleStagesAndItems(subq1, stageIdItemIdMap)
+	r.handleCountry(subq1, country)
+	r.handleWeight(subq1, weighted)
 
 	mainq := r.db.NewSelect().
 		TableExpr("(?) AS a", subq1).
 		Column("group_id", "interval_start", "interval_end", "stage_id", "item_id").
-		ColumnExpr("SUM(quantity) AS total_quantity")
+		ColumnExpr(weightedSum("quantity", weighted) + " AS total_quantity")
 	r.handleSourceName(mainq, sourceCategory)
 
 	if err := mainq.
@@ -193,7 +399,7 @@ func (r *DropReport) CalcTotalQuantityForTrend(
 }
 
 func (r *DropReport) CalcTotalTimesForTrend(
-	ctx context.Context, server string, startTime *time.Time, intervalLength time.Duration, intervalNum int, stageIds []int, accountId null.Int, sourceCategory string,
+	ctx context.Context, server string, startTime *time.Time, intervalLength time.Duration, intervalNum int, stageIds []int, accountId null.Int, sourceCategory string, country string, weighted bool,
 ) ([]*model.TotalTimesResultForTrend, error) {
 	results := make([]*model.TotalTimesResultForTrend, 0)
 	if len(stageIds) == 0 {
@@ -213,11 +419,13 @@ func (r *DropReport) CalcTotalTimesForTrend(
 	r.handleCreatedAtWithTime(subq1, &gameDayStart, &lastDayEnd)
 	r.handleServer(subq1, server)
 	r.handleStages(subq1, stageIds)
+	r.handleCountry(subq1, country)
+	r.handleWeight(subq1, weighted)
 
 	mainq := r.db.NewSelect().
 		TableExpr("(?) AS a", subq1).
 		Column("group_id", "interval_start", "interval_end", "stage_id").
-		ColumnExpr("SUM(times) AS total_times")
+		ColumnExpr(weightedSum("times", weighted) + " AS total_times")
 	r.handleSourceName(mainq, sourceCategory)
 
 	if err := mainq.
@@ -348,6 +556,36 @@ func (r *DropReport) DeleteDropReportsForArchive(ctx context.Context, tx bun.Tx,
 	return res.RowsAffected()
 }
 
+// RestoreDropReportsFromArchive inserts archived drop reports with their original IDs. Reports
+// which already exist are left untouched, so restoring is idempotent.
+// returns number of rows inserted and error
+func (r *DropReport) RestoreDropReportsFromArchive(ctx context.Context, tx bun.Tx, reports []*model.DropReport) (int64, error) {
+	if len(reports) == 0 {
+		return 0, nil
+	}
+	res, err := tx.NewInsert().
+		Model(&reports).
+		On("CONFLICT (report_id) DO NOTHING").
+		Exec(ctx)
+	if err != nil {
+		return -1, err
+	}
+	return res.RowsAffected()
+}
+
+// CountDropReportsForArchive counts drop reports of the archive date with report_id between idInclusiveStart and idInclusiveEnd.
+func (r *DropReport) CountDropReportsForArchive(ctx context.Context, tx bun.Tx, date time.Time, idInclusiveStart int, idInclusiveEnd int) (int, error) {
+	start := time.UnixMilli(util.GetDayStartTime(&date, "CN")) // we use CN server's day start time across all servers for archive
+	end := start.Add(time.Hour * 24)
+	return tx.NewSelect().
+		Model((*model.DropReport)(nil)).
+		Where("created_at >= to_timestamp(?)", start.Unix()).
+		Where("created_at < to_timestamp(?)", end.Unix()).
+		Where("report_id >= ?", idInclusiveStart).
+		Where("report_id <= ?", idInclusiveEnd).
+		Count(ctx)
+}
+
 func (r *DropReport) handleStagesAndItems(query *bun.SelectQuery, stageIdItemIdMap map[int][]int) {
 	stageConditions := make([]string, 0)
 	for stageId, itemIds := range stageIdItemIdMap {
@@ -421,6 +659,43 @@ func (r *DropReport) handleTimes(query *bun.SelectQuery, times int) {
 	query = query.Where("dr.times = ?", times)
 }
 
+// handleCountry filters reports by the country of their IP. Reports without a resolved country never match.
+func (r *DropReport) handleCountry(query *bun.SelectQuery, country string) {
+	if country == "" {
+		return
+	}
+	query = query.Where("EXISTS (SELECT 1 FROM drop_report_extras AS dre WHERE dre.report_id = dr.report_id AND dre.country = ?)", country)
+}
+
+// handleWeight selects the weight of the account of each report as column weight, if weighted. Accounts whose
+// weight has never been set count as model.AccountDefaultWeight.
+func (r *DropReport) handleWeight(query *bun.SelectQuery, weighted bool) {
+	if !weighted {
+		return
+	}
+	query = query.
+		Join("LEFT JOIN accounts AS acc ON acc.account_id = dr.account_id").
+		ColumnExpr("COALESCE(NULLIF(acc.weight, 0), ?) AS weight", model.AccountDefaultWeight)
+}
+
+// weightedCount counts the rows selected with handleWeight, weighted if weighted. Weighted counts are rounded to
+// integers, so that they can be used in place of plain counts; they are not sample sizes though, so no confidence
+// interval should be calculated from them.
+func weightedCount(weighted bool) string {
+	if !weighted {
+		return "COUNT(*)"
+	}
+	return "ROUND(SUM(weight))::int"
+}
+
+// weightedSum is weightedCount for the sum of the column.
+func weightedSum(column string, weighted bool) string {
+	if !weighted {
+		return "SUM(" + column + ")"
+	}
+	return "ROUND(SUM(" + column + " * weight))::int"
+}
+
 func (r *DropReport) handleSourceName(query *bun.SelectQuery, sourceCategory string) {
 	if sourceCategory == constant.SourceCategoryManual {
 		query = query.Where("source_name IN (?)", bun.In(constant.ManualSources))
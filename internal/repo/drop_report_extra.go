@@ -2,6 +2,7 @@ package repo
 
 import (
 	"context"
+	"time"
 
 	"github.com/uptrace/bun"
 
@@ -70,6 +71,51 @@ func (c *DropReportExtra) DeleteDropReportExtrasForArchive(ctx context.Context,
 	return r.RowsAffected()
 }
 
+// RestoreDropReportExtrasFromArchive inserts archived drop report extras. Extras which already exist are left
+// untouched, so restoring is idempotent.
+// Returns the number of rows inserted and an error if any.
+func (c *DropReportExtra) RestoreDropReportExtrasFromArchive(ctx context.Context, tx bun.Tx, extras []*model.DropReportExtra) (int64, error) {
+	if len(extras) == 0 {
+		return 0, nil
+	}
+	r, err := tx.NewInsert().
+		Model(&extras).
+		On("CONFLICT (report_id) DO NOTHING").
+		Exec(ctx)
+	if err != nil {
+		return -1, err
+	}
+
+	return r.RowsAffected()
+}
+
+// CountDropReportExtrasForArchive counts drop report extras with report_id between idInclusiveStart and idInclusiveEnd.
+func (c *DropReportExtra) CountDropReportExtrasForArchive(ctx context.Context, tx bun.Tx, idInclusiveStart int, idInclusiveEnd int) (int, error) {
+	return tx.NewSelect().
+		Model((*model.DropReportExtra)(nil)).
+		Where("report_id >= ?", idInclusiveStart).
+		Where("report_id <= ?", idInclusiveEnd).
+		Count(ctx)
+}
+
+func (c *DropReportExtra) GetDropReportExtrasByIds(ctx context.Context, ids []int) ([]*model.DropReportExtra, error) {
+	return c.sel.SelectMany(ctx, func(q *bun.SelectQuery) *bun.SelectQuery {
+		return q.Where("report_id IN (?)", bun.In(ids)).Order("report_id")
+	}, selector.OptionUseZeroLenSliceOnNull)
+}
+
+func (c *DropReportExtra) DeleteDropReportExtrasByIds(ctx context.Context, tx bun.Tx, ids []int) (int64, error) {
+	r, err := tx.NewDelete().
+		Model((*model.DropReportExtra)(nil)).
+		Where("report_id IN (?)", bun.In(ids)).
+		Exec(ctx)
+	if err != nil {
+		return -1, err
+	}
+
+	return r.RowsAffected()
+}
+
 func (c *DropReportExtra) IsDropReportExtraMD5Exist(ctx context.Context, md5 string) bool {
 	var dropReportExtra model.DropReportExtra
 
@@ -92,3 +138,27 @@ func (r *DropReportExtra) CreateDropReportExtra(ctx context.Context, tx bun.Tx,
 
 	return err
 }
+
+// EraseDropReportExtraIPs erases the IPs of at most limit drop report extras whose reports were created before
+// the given time.
+// Returns the number of rows affected and an error if any.
+func (c *DropReportExtra) EraseDropReportExtraIPs(ctx context.Context, before time.Time, limit int) (int64, error) {
+	subq := c.db.NewSelect().
+		TableExpr("drop_reports AS dr").
+		Column("dr.report_id").
+		Join("JOIN drop_report_extras AS dre2 ON dre2.report_id = dr.report_id").
+		Where("dr.created_at < to_timestamp(?)", before.Unix()).
+		Where("dre2.ip != ''").
+		Limit(limit)
+
+	r, err := c.db.NewUpdate().
+		Model((*model.DropReportExtra)(nil)).
+		Set("ip = ''").
+		Where("report_id IN (?)", subq).
+		Exec(ctx)
+	if err != nil {
+		return -1, err
+	}
+
+	return r.RowsAffected()
+}
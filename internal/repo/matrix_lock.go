@@ -0,0 +1,32 @@
+package repo
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/uptrace/bun"
+)
+
+// Matrix elements of a day are updated both incrementally by the report worker and by the reconciliation of the
+// matrix jobs. Incremental updates hold the day lock shared and the lock of the stage they update exclusively,
+// while reconciliations hold the day lock exclusively. All locks are released when the transaction ends.
+
+func matrixLockKey(server string, dayNum int) string {
+	return "matrix|" + server + "|" + strconv.Itoa(dayNum)
+}
+
+// LockMatrixDay locks the matrix elements of the day of server, either shared or exclusively.
+func LockMatrixDay(ctx context.Context, tx bun.Tx, server string, dayNum int, shared bool) error {
+	fn := "pg_advisory_xact_lock"
+	if shared {
+		fn = "pg_advisory_xact_lock_shared"
+	}
+	_, err := tx.ExecContext(ctx, "SELECT "+fn+"(hashtext(?)::bigint)", matrixLockKey(server, dayNum))
+	return err
+}
+
+// LockMatrixStage exclusively locks the matrix elements of the stage on the day of server.
+func LockMatrixStage(ctx context.Context, tx bun.Tx, server string, dayNum int, stageId int) error {
+	_, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext(?), ?)", matrixLockKey(server, dayNum), stageId)
+	return err
+}
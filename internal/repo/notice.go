@@ -2,10 +2,13 @@ package repo
 
 import (
 	"context"
+	"database/sql"
 
+	"github.com/pkg/errors"
 	"github.com/uptrace/bun"
 
 	"exusiai.dev/backend-next/internal/model"
+	"exusiai.dev/backend-next/internal/pkg/pgerr"
 	"exusiai.dev/backend-next/internal/repo/selector"
 )
 
@@ -23,3 +26,54 @@ func (r *Notice) GetNotices(ctx context.Context) ([]*model.Notice, error) {
 		return q.Order("notice_id ASC")
 	}, selector.OptionUseZeroLenSliceOnNull)
 }
+
+func (r *Notice) GetScheduledNotices(ctx context.Context) ([]*model.ScheduledNotice, error) {
+	notices := make([]*model.ScheduledNotice, 0)
+	err := r.db.NewSelect().
+		Model(&notices).
+		Order("notice_id ASC").
+		Scan(ctx)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	return notices, nil
+}
+
+func (r *Notice) GetScheduledNotice(ctx context.Context, noticeId int) (*model.ScheduledNotice, error) {
+	var notice model.ScheduledNotice
+	err := r.db.NewSelect().
+		Model(&notice).
+		Where("notice_id = ?", noticeId).
+		Scan(ctx)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, pgerr.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &notice, nil
+}
+
+func (r *Notice) SaveScheduledNotice(ctx context.Context, notice *model.ScheduledNotice) error {
+	_, err := r.db.NewInsert().
+		Model(notice).
+		On("CONFLICT (notice_id) DO UPDATE").
+		Returning("*").
+		Exec(ctx)
+	return err
+}
+
+func (r *Notice) DeleteScheduledNotice(ctx context.Context, noticeId int) error {
+	res, err := r.db.NewDelete().
+		Model((*model.ScheduledNotice)(nil)).
+		Where("notice_id = ?", noticeId).
+		Exec(ctx)
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return pgerr.ErrNotFound
+	}
+	return nil
+}
@@ -32,6 +32,53 @@ func (s *PatternMatrixElement) DeleteByServerAndDayNum(ctx context.Context, serv
 	return err
 }
 
+// GetElementsByServerAndDayNum returns the elements of all source categories on the day, of the stages only if
+// stageIds is not empty.
+func (s *PatternMatrixElement) GetElementsByServerAndDayNum(ctx context.Context, db bun.IDB, server string, dayNum int, stageIds []int) ([]*model.PatternMatrixElement, error) {
+	elements := make([]*model.PatternMatrixElement, 0)
+	query := db.NewSelect().
+		Model(&elements).
+		Where("server = ?", server).
+		Where("day_num = ?", dayNum)
+	if len(stageIds) > 0 {
+		query = query.Where("stage_id IN (?)", bun.In(stageIds))
+	}
+	if err := query.Scan(ctx); err != nil {
+		return nil, err
+	}
+	return elements, nil
+}
+
+func (s *PatternMatrixElement) CreateElements(ctx context.Context, db bun.IDB, elements []*model.PatternMatrixElement) error {
+	_, err := db.NewInsert().Model(&elements).Exec(ctx)
+	return err
+}
+
+// UpdateElementCounters updates the counters of the elements by their IDs.
+func (s *PatternMatrixElement) UpdateElementCounters(ctx context.Context, db bun.IDB, elements []*model.PatternMatrixElement) error {
+	_, err := db.NewUpdate().
+		Model(&elements).
+		Column("quantity", "times").
+		Bulk().
+		Exec(ctx)
+	return err
+}
+
+func (s *PatternMatrixElement) DeleteElementsByIds(ctx context.Context, db bun.IDB, elementIds []int) error {
+	_, err := db.NewDelete().Model((*model.PatternMatrixElement)(nil)).Where("element_id IN (?)", bun.In(elementIds)).Exec(ctx)
+	return err
+}
+
+func (s *PatternMatrixElement) DeleteByServerAndDayNumAndStageIds(ctx context.Context, db bun.IDB, server string, dayNum int, stageIds []int) error {
+	_, err := db.NewDelete().
+		Model((*model.PatternMatrixElement)(nil)).
+		Where("server = ?", server).
+		Where("day_num = ?", dayNum).
+		Where("stage_id IN (?)", bun.In(stageIds)).
+		Exec(ctx)
+	return err
+}
+
 func (s *PatternMatrixElement) IsExistByServerAndDayNum(ctx context.Context, server string, dayNum int) (bool, error) {
 	exists, err := s.db.NewSelect().Model((*model.PatternMatrixElement)(nil)).Where("server = ?", server).Where("day_num = ?", dayNum).Exists(ctx)
 	if err != nil {
@@ -3,6 +3,7 @@ package repo
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/uptrace/bun"
@@ -12,7 +13,8 @@ import (
 )
 
 const (
-	RejectRuleActiveStatus = 1
+	RejectRuleInactiveStatus = 0
+	RejectRuleActiveStatus   = 1
 )
 
 type RejectRule struct {
@@ -55,3 +57,135 @@ func (r *RejectRule) GetAllActiveRejectRules(ctx context.Context) ([]*model.Reje
 
 	return rejectRule, nil
 }
+
+func (r *RejectRule) GetAllRejectRules(ctx context.Context) ([]*model.RejectRule, error) {
+	var rejectRules []*model.RejectRule
+	err := r.db.NewSelect().
+		Model(&rejectRules).
+		Order("rule_id ASC").
+		Scan(ctx)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, pgerr.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return rejectRules, nil
+}
+
+func (r *RejectRule) GetRejectRuleRevisions(ctx context.Context, ruleId int) ([]*model.RejectRuleRevision, error) {
+	var revisions []*model.RejectRuleRevision
+	err := r.db.NewSelect().
+		Model(&revisions).
+		Where("rule_id = ?", ruleId).
+		Order("revision DESC").
+		Scan(ctx)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, pgerr.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+func (r *RejectRule) GetLatestRejectRuleRevision(ctx context.Context, ruleId int) (*model.RejectRuleRevision, error) {
+	var revision model.RejectRuleRevision
+	err := r.db.NewSelect().
+		Model(&revision).
+		Where("rule_id = ?", ruleId).
+		Order("revision DESC").
+		Limit(1).
+		Scan(ctx)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, pgerr.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &revision, nil
+}
+
+// SaveRejectRule upserts the reject rule and records a new revision of it, along with the result of the dry run
+// the rule has been saved with, within the same transaction.
+func (r *RejectRule) SaveRejectRule(ctx context.Context, rejectRule *model.RejectRule, dryRunEvaluatedCount, dryRunRejectedCount int) (*model.RejectRuleRevision, error) {
+	var revision *model.RejectRuleRevision
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewInsert().
+			Model(rejectRule).
+			On("CONFLICT (rule_id) DO UPDATE").
+			Returning("*").
+			Exec(ctx); err != nil {
+			return err
+		}
+
+		var err error
+		revision, err = r.createRejectRuleRevision(ctx, tx, rejectRule, &dryRunEvaluatedCount, &dryRunRejectedCount)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return revision, nil
+}
+
+// UpdateRejectRuleStatus only updates the status of the reject rule and records a new revision of it. Unlike
+// SaveRejectRule, the rule is updated as-is, so that rules which no longer validate can still be switched off.
+func (r *RejectRule) UpdateRejectRuleStatus(ctx context.Context, ruleId int, status int, updatedAt time.Time) (*model.RejectRule, error) {
+	var rejectRule model.RejectRule
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if err := tx.NewUpdate().
+			Model(&rejectRule).
+			Set("status = ?", status).
+			Set("updated_at = ?", updatedAt).
+			Where("rule_id = ?", ruleId).
+			Returning("*").
+			Scan(ctx); err != nil {
+			return err
+		}
+
+		_, err := r.createRejectRuleRevision(ctx, tx, &rejectRule, nil, nil)
+		return err
+	})
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, pgerr.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &rejectRule, nil
+}
+
+func (r *RejectRule) createRejectRuleRevision(ctx context.Context, tx bun.Tx, rejectRule *model.RejectRule, dryRunEvaluatedCount, dryRunRejectedCount *int) (*model.RejectRuleRevision, error) {
+	var latestRevision int
+	if err := tx.NewSelect().
+		Model((*model.RejectRuleRevision)(nil)).
+		ColumnExpr("COALESCE(MAX(revision), 0)").
+		Where("rule_id = ?", rejectRule.RuleID).
+		Scan(ctx, &latestRevision); err != nil {
+		return nil, err
+	}
+
+	revision := &model.RejectRuleRevision{
+		RuleID:               rejectRule.RuleID,
+		Revision:             latestRevision + 1,
+		CreatedAt:            rejectRule.UpdatedAt,
+		Status:               rejectRule.Status,
+		Expr:                 rejectRule.Expr,
+		WithReliability:      rejectRule.WithReliability,
+		DryRunEvaluatedCount: dryRunEvaluatedCount,
+		DryRunRejectedCount:  dryRunRejectedCount,
+	}
+	if _, err := tx.NewInsert().
+		Model(revision).
+		Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	return revision, nil
+}
@@ -0,0 +1,113 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/uptrace/bun"
+
+	"exusiai.dev/backend-next/internal/model"
+	"exusiai.dev/backend-next/internal/pkg/pgerr"
+)
+
+type ShortLink struct {
+	db *bun.DB
+}
+
+func NewShortLink(db *bun.DB) *ShortLink {
+	return &ShortLink{db: db}
+}
+
+func (r *ShortLink) GetShortLinks(ctx context.Context) ([]*model.ShortLink, error) {
+	shortLinks := make([]*model.ShortLink, 0)
+	err := r.db.NewSelect().
+		Model(&shortLinks).
+		Order("link_id ASC").
+		Scan(ctx)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	return shortLinks, nil
+}
+
+func (r *ShortLink) GetShortLink(ctx context.Context, linkId int) (*model.ShortLink, error) {
+	var shortLink model.ShortLink
+	err := r.db.NewSelect().
+		Model(&shortLink).
+		Where("link_id = ?", linkId).
+		Scan(ctx)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, pgerr.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &shortLink, nil
+}
+
+func (r *ShortLink) IsWordTaken(ctx context.Context, word string, excludeLinkId int) (bool, error) {
+	return r.db.NewSelect().
+		Model((*model.ShortLink)(nil)).
+		Where("word = ?", word).
+		Where("link_id != ?", excludeLinkId).
+		Exists(ctx)
+}
+
+func (r *ShortLink) CreateShortLink(ctx context.Context, shortLink *model.ShortLink) error {
+	_, err := r.db.NewInsert().
+		Model(shortLink).
+		Returning("*").
+		Exec(ctx)
+	return err
+}
+
+func (r *ShortLink) UpdateShortLink(ctx context.Context, shortLink *model.ShortLink) error {
+	_, err := r.db.NewUpdate().
+		Model(shortLink).
+		Column("word", "targets", "expires_at", "comment", "updated_at").
+		WherePK().
+		Exec(ctx)
+	return err
+}
+
+func (r *ShortLink) DeleteShortLink(ctx context.Context, linkId int) error {
+	res, err := r.db.NewDelete().
+		Model((*model.ShortLink)(nil)).
+		Where("link_id = ?", linkId).
+		Exec(ctx)
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return pgerr.ErrNotFound
+	}
+	return nil
+}
+
+// IncrShortLinkClicks adds clicks to the aggregated clicks of their links from their countries on their dates.
+// clicks must not contain more than one row of the same (link_id, date, country).
+func (r *ShortLink) IncrShortLinkClicks(ctx context.Context, clicks []*model.ShortLinkClick) error {
+	_, err := r.db.NewInsert().
+		Model(&clicks).
+		On("CONFLICT (link_id, date, country) DO UPDATE").
+		Set("clicks = slc.clicks + EXCLUDED.clicks").
+		Exec(ctx)
+	return err
+}
+
+func (r *ShortLink) GetShortLinkClicks(ctx context.Context, linkId int, since time.Time) ([]*model.ShortLinkClick, error) {
+	clicks := make([]*model.ShortLinkClick, 0)
+	err := r.db.NewSelect().
+		Model(&clicks).
+		Where("link_id = ?", linkId).
+		Where("date >= ?", since.Format(time.DateOnly)).
+		Order("date ASC", "country ASC").
+		Scan(ctx)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	return clicks, nil
+}
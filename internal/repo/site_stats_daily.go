@@ -0,0 +1,74 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"exusiai.dev/backend-next/internal/model"
+	"exusiai.dev/backend-next/internal/repo/selector"
+)
+
+type SiteStatsDaily struct {
+	db  *bun.DB
+	sel selector.S[model.SiteStatsDaily]
+}
+
+func NewSiteStatsDaily(db *bun.DB) *SiteStatsDaily {
+	return &SiteStatsDaily{
+		db:  db,
+		sel: selector.New[model.SiteStatsDaily](db),
+	}
+}
+
+// GetSiteStatsDailyByServer returns the stats of the server since the given day, ordered by day.
+func (r *SiteStatsDaily) GetSiteStatsDailyByServer(ctx context.Context, server string, sinceDayNum int) ([]*model.SiteStatsDaily, error) {
+	return r.sel.SelectMany(ctx, func(q *bun.SelectQuery) *bun.SelectQuery {
+		return q.Where("server = ?", server).
+			Where("day_num >= ?", sinceDayNum).
+			Order("day_num")
+	}, selector.OptionUseZeroLenSliceOnNull)
+}
+
+func (r *SiteStatsDaily) UpsertSiteStatsDaily(ctx context.Context, stats *model.SiteStatsDaily) error {
+	_, err := r.db.NewInsert().
+		Model(stats).
+		On("CONFLICT (server, day_num) DO UPDATE").
+		Set("unique_accounts = EXCLUDED.unique_accounts").
+		Set("reports = EXCLUDED.reports").
+		Set("times = EXCLUDED.times").
+		Set("sources = EXCLUDED.sources").
+		Set("rejections = EXCLUDED.rejections").
+		Set("updated_at = EXCLUDED.updated_at").
+		Exec(ctx)
+	return err
+}
+
+// CalcUniqueAccountsForSiteStatsDaily counts the accounts which submitted at least one report within the time range.
+func (r *SiteStatsDaily) CalcUniqueAccountsForSiteStatsDaily(ctx context.Context, server string, start, end time.Time) (int, error) {
+	var count int
+	err := r.db.NewSelect().
+		TableExpr("drop_reports AS dr").
+		ColumnExpr("COUNT(DISTINCT dr.account_id)").
+		Where("dr.server = ?", server).
+		Where("dr.created_at >= ?", start).
+		Where("dr.created_at < ?", end).
+		Scan(ctx, &count)
+	return count, err
+}
+
+func (r *SiteStatsDaily) CalcReportCountsForSiteStatsDaily(ctx context.Context, server string, start, end time.Time) ([]*model.SiteStatsDailyReportCount, error) {
+	results := make([]*model.SiteStatsDailyReportCount, 0)
+	err := r.db.NewSelect().
+		TableExpr("drop_reports AS dr").
+		Column("dr.source_name", "dr.version", "dr.reliability").
+		ColumnExpr("COUNT(*) AS reports").
+		ColumnExpr("SUM(dr.times) AS times").
+		Where("dr.server = ?", server).
+		Where("dr.created_at >= ?", start).
+		Where("dr.created_at < ?", end).
+		Group("dr.source_name", "dr.version", "dr.reliability").
+		Scan(ctx, &results)
+	return results, err
+}
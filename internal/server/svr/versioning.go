@@ -40,7 +40,10 @@ func CreateEndpointGroups(app *fiber.App, conf *appconfig.Config) (*V2, *V3, *Ad
 		c.Set("X-Penguin-Notes", msg)
 
 		accepts := c.Get(fiber.HeaderAccept)
-		if !strings.Contains(accepts, "application/vnd.penguin.v3+json") {
+		// browsers are unable to set the Accept header for websocket connections; a penguin-stats
+		// subprotocol negotiated in the handshake is considered as an explicit opt-in as well.
+		subprotocols := c.Get(fiber.HeaderSecWebSocketProtocol)
+		if !strings.Contains(accepts, "application/vnd.penguin.v3+json") && !strings.Contains(subprotocols, "penguin-stats") {
 			return pgerr.ErrInvalidReq.Msg(msg + " To use the v3 API, please use the application/vnd.penguin.v3+json Accept header to explicitly opt-in to the alpha version of API.")
 		}
 
@@ -18,6 +18,8 @@ func Module() fx.Option {
 		NewNotice,
 		NewReport,
 		NewAccount,
+		NewAccountReport,
+		NewAccountWeight,
 		NewFormula,
 		NewActivity,
 		NewDropInfo,
@@ -29,6 +31,14 @@ func Module() fx.Option {
 		NewDropMatrix,
 		NewDropReport,
 		NewPatternMatrix,
+		NewPlanner,
+		NewSanityValue,
+		NewDrift,
+		NewSearch,
+		NewShortLink,
+		NewReportDeadLetter,
+		NewReportTaskStatus,
+		NewMatrixDelta,
 		NewFrontendConfig,
 		NewDropMatrixElement,
 		NewDropPatternElement,
@@ -36,5 +46,7 @@ func Module() fx.Option {
 		NewExport,
 		NewDropReportExtra,
 		NewArchive,
+		NewLive,
+		NewRejectRule,
 	))
 }
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -60,6 +61,23 @@ func (s *Account) GetAccountByPenguinId(ctx context.Context, penguinId string) (
 	return dbAccount, nil
 }
 
+// RegeneratePenguinId replaces the PenguinID of the account with a new random one, while keeping all of its
+// reports. The old PenguinID stops working immediately.
+func (s *Account) RegeneratePenguinId(ctx context.Context, account *model.Account) (*model.Account, error) {
+	regenerated, err := s.AccountRepo.RegeneratePenguinId(ctx, account.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cache.AccountByPenguinID.Delete(account.PenguinID); err != nil {
+		return nil, err
+	}
+	if err := cache.AccountByID.Delete(strconv.Itoa(account.AccountID)); err != nil {
+		return nil, err
+	}
+	return regenerated, nil
+}
+
 func (s *Account) IsAccountExistWithId(ctx context.Context, accountId int) bool {
 	return s.AccountRepo.IsAccountExistWithId(ctx, accountId)
 }
@@ -0,0 +1,304 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/uptrace/bun"
+
+	"exusiai.dev/backend-next/internal/model"
+	modelv3 "exusiai.dev/backend-next/internal/model/v3"
+	"exusiai.dev/backend-next/internal/repo"
+)
+
+const (
+	AccountReportsDefaultPageSize = 50
+	AccountReportsMaxPageSize     = 500
+
+	accountDataExportBatchSize = 1000
+)
+
+// AccountReport serves the self-service management of the reports submitted by an account.
+type AccountReport struct {
+	DB                        *bun.DB
+	DropReportRepo            *repo.DropReport
+	DropReportExtraRepo       *repo.DropReportExtra
+	DropPatternElementService *DropPatternElement
+	StageService              *Stage
+	ItemService               *Item
+	MatrixDelta               *MatrixDelta
+}
+
+func NewAccountReport(
+	db *bun.DB,
+	dropReportRepo *repo.DropReport,
+	dropReportExtraRepo *repo.DropReportExtra,
+	dropPatternElementService *DropPatternElement,
+	stageService *Stage,
+	itemService *Item,
+	matrixDelta *MatrixDelta,
+) *AccountReport {
+	return &AccountReport{
+		DB:                        db,
+		DropReportRepo:            dropReportRepo,
+		DropReportExtraRepo:       dropReportExtraRepo,
+		DropPatternElementService: dropPatternElementService,
+		StageService:              stageService,
+		ItemService:               itemService,
+		MatrixDelta:               matrixDelta,
+	}
+}
+
+func (s *AccountReport) GetAccount(ctx context.Context, account *model.Account) (*modelv3.Account, error) {
+	count, err := s.DropReportRepo.CountDropReportsByAccountId(ctx, account.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	return &modelv3.Account{
+		PenguinID:   account.PenguinID,
+		CreatedAt:   account.CreatedAt.UnixMilli(),
+		ReportCount: count,
+	}, nil
+}
+
+// GetReports returns a page of the reports of the account, newest first. cursor is the NextCursor of the
+// previous page, or 0 for the first page.
+func (s *AccountReport) GetReports(ctx context.Context, account *model.Account, cursor int, limit int) (*modelv3.AccountReportsPage, error) {
+	if limit <= 0 {
+		limit = AccountReportsDefaultPageSize
+	} else if limit > AccountReportsMaxPageSize {
+		limit = AccountReportsMaxPageSize
+	}
+
+	dropReports, err := s.DropReportRepo.GetDropReportsByAccountId(ctx, account.AccountID, cursor, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	conv, err := s.newReportConverter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reports, err := conv.convert(ctx, dropReports)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &modelv3.AccountReportsPage{
+		Reports: reports,
+	}
+	if len(dropReports) == limit {
+		page.NextCursor = dropReports[len(dropReports)-1].ReportID
+	}
+	return page, nil
+}
+
+// RecallReports marks the reports of the account as recalled, so that they are no longer counted in any
+// statistics but are kept for auditing.
+func (s *AccountReport) RecallReports(ctx context.Context, account *model.Account, reportIds []int) (int64, error) {
+	var recalled int64
+	var servers []string
+	err := s.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		reports, err := s.DropReportRepo.GetDropReportsByAccountIdForUpdate(ctx, tx, account.AccountID, reportIds)
+		if err != nil {
+			return err
+		}
+		recalledIds, err := s.DropReportRepo.RecallDropReportsByAccountId(ctx, tx, account.AccountID, reportIds)
+		if err != nil {
+			return errors.Wrap(err, "failed to recall drop reports")
+		}
+		servers, err = s.MatrixDelta.ApplyReliabilityChanges(ctx, tx, reports, removedReliabilities(recalledIds))
+		if err != nil {
+			return errors.Wrap(err, "failed to update matrix elements")
+		}
+		recalled = int64(len(recalledIds))
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	s.MatrixDelta.SchedulePurge(servers...)
+	return recalled, nil
+}
+
+// DeleteReports permanently deletes the reports of the account, along with their extras.
+func (s *AccountReport) DeleteReports(ctx context.Context, account *model.Account, reportIds []int) (int64, error) {
+	var deleted int64
+	var servers []string
+	err := s.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		reports, err := s.DropReportRepo.GetDropReportsByAccountIdForUpdate(ctx, tx, account.AccountID, reportIds)
+		if err != nil {
+			return err
+		}
+		deletedIds, err := s.DropReportRepo.DeleteDropReportsByAccountId(ctx, tx, account.AccountID, reportIds)
+		if err != nil {
+			return errors.Wrap(err, "failed to delete drop reports")
+		}
+		if len(deletedIds) == 0 {
+			return nil
+		}
+		// extras are only deleted for reports which do belong to the account
+		if _, err := s.DropReportExtraRepo.DeleteDropReportExtrasByIds(ctx, tx, deletedIds); err != nil {
+			return errors.Wrap(err, "failed to delete drop report extras")
+		}
+		servers, err = s.MatrixDelta.ApplyReliabilityChanges(ctx, tx, reports, removedReliabilities(deletedIds))
+		if err != nil {
+			return errors.Wrap(err, "failed to update matrix elements")
+		}
+		deleted = int64(len(deletedIds))
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	s.MatrixDelta.SchedulePurge(servers...)
+	return deleted, nil
+}
+
+// removedReliabilities maps the reports removed from the statistics to a negative reliability, as expected by
+// MatrixDelta.ApplyReliabilityChanges.
+func removedReliabilities(reportIds []int) map[int]int {
+	reliabilities := make(map[int]int, len(reportIds))
+	for _, reportId := range reportIds {
+		reliabilities[reportId] = -1
+	}
+	return reliabilities
+}
+
+// ExportData collects all personal data stored about the account, including the reports which have been
+// recalled or rejected and are therefore no longer listed by GetReports.
+func (s *AccountReport) ExportData(ctx context.Context, account *model.Account) (*modelv3.AccountDataExport, error) {
+	exportAccount, err := s.GetAccount(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+
+	conv, err := s.newReportConverter(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &modelv3.AccountDataExport{
+		Account:      exportAccount,
+		Reports:      make([]*modelv3.AccountReport, 0, exportAccount.ReportCount),
+		ReportExtras: make([]*modelv3.AccountReportExtra, 0, exportAccount.ReportCount),
+	}
+
+	cursor := 0
+	for {
+		dropReports, err := s.DropReportRepo.GetAllDropReportsByAccountId(ctx, account.AccountID, cursor, accountDataExportBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(dropReports) == 0 {
+			break
+		}
+
+		reports, err := conv.convert(ctx, dropReports)
+		if err != nil {
+			return nil, err
+		}
+		export.Reports = append(export.Reports, reports...)
+
+		reportIds := make([]int, 0, len(dropReports))
+		for _, dropReport := range dropReports {
+			reportIds = append(reportIds, dropReport.ReportID)
+		}
+		extras, err := s.DropReportExtraRepo.GetDropReportExtrasByIds(ctx, reportIds)
+		if err != nil {
+			return nil, err
+		}
+		for _, extra := range extras {
+			export.ReportExtras = append(export.ReportExtras, &modelv3.AccountReportExtra{
+				ReportID: extra.ReportID,
+				IP:       extra.IP,
+				Metadata: extra.Metadata,
+				Country:  extra.Country.String,
+			})
+		}
+
+		cursor = dropReports[len(dropReports)-1].ReportID
+	}
+
+	export.ExportedAt = time.Now().UnixMilli()
+	return export, nil
+}
+
+// accountReportConverter converts drop reports into their public representation, memoizing drop patterns
+// since they are highly repetitive across the reports of an account.
+type accountReportConverter struct {
+	s         *AccountReport
+	stagesMap map[int]*model.Stage
+	itemsMap  map[int]*model.Item
+	patterns  map[int][]*model.DropPatternElementForExport
+}
+
+func (s *AccountReport) newReportConverter(ctx context.Context) (*accountReportConverter, error) {
+	stagesMap, err := s.StageService.GetStagesMapById(ctx)
+	if err != nil {
+		return nil, err
+	}
+	itemsMap, err := s.ItemService.GetItemsMapById(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &accountReportConverter{
+		s:         s,
+		stagesMap: stagesMap,
+		itemsMap:  itemsMap,
+		patterns:  make(map[int][]*model.DropPatternElementForExport),
+	}, nil
+}
+
+func (c *accountReportConverter) convert(ctx context.Context, dropReports []*model.DropReport) ([]*modelv3.AccountReport, error) {
+	reports := make([]*modelv3.AccountReport, 0, len(dropReports))
+	for _, dropReport := range dropReports {
+		drops, err := c.drops(ctx, dropReport.PatternID)
+		if err != nil {
+			return nil, err
+		}
+
+		report := &modelv3.AccountReport{
+			ReportID:    dropReport.ReportID,
+			Server:      dropReport.Server,
+			Times:       dropReport.Times,
+			Drops:       drops,
+			Reliability: dropReport.Reliability,
+			SourceName:  dropReport.SourceName,
+			Version:     dropReport.Version,
+		}
+		if stage, ok := c.stagesMap[dropReport.StageID]; ok {
+			report.StageID = stage.ArkStageID
+		}
+		if dropReport.CreatedAt != nil {
+			report.CreatedAt = dropReport.CreatedAt.UnixMilli()
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+func (c *accountReportConverter) drops(ctx context.Context, patternId int) ([]*model.DropPatternElementForExport, error) {
+	if drops, ok := c.patterns[patternId]; ok {
+		return drops, nil
+	}
+
+	elements, err := c.s.DropPatternElementService.GetDropPatternElementsByPatternId(ctx, patternId)
+	if err != nil {
+		return nil, err
+	}
+	drops := make([]*model.DropPatternElementForExport, 0, len(elements))
+	for _, element := range elements {
+		item, ok := c.itemsMap[element.ItemID]
+		if !ok {
+			continue
+		}
+		drops = append(drops, &model.DropPatternElementForExport{
+			ArkItemID: item.ArkItemID,
+			Quantity:  element.Quantity,
+		})
+	}
+	c.patterns[patternId] = drops
+	return drops, nil
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/uptrace/bun"
+
+	"exusiai.dev/backend-next/internal/app/appconfig"
+	"exusiai.dev/backend-next/internal/model"
+	"exusiai.dev/backend-next/internal/model/cache"
+	"exusiai.dev/backend-next/internal/repo"
+)
+
+// accountWeightMinExpected is the minimum expected quantity of an item for an account in a stage before the
+// quantity is scored, below which the poisson approximation of its variance is too rough.
+const accountWeightMinExpected = 1.0
+
+// AccountWeight assigns weights to accounts, which are used by the weighted modes of the drop matrix, pattern
+// matrix and advanced query to let trusted accounts count more than throwaway ones.
+type AccountWeight struct {
+	Config         *appconfig.Config
+	DB             *bun.DB
+	AccountRepo    *repo.Account
+	DropReportRepo *repo.DropReport
+}
+
+func NewAccountWeight(config *appconfig.Config, db *bun.DB, accountRepo *repo.Account, dropReportRepo *repo.DropReport) *AccountWeight {
+	return &AccountWeight{
+		Config:         config,
+		DB:             db,
+		AccountRepo:    accountRepo,
+		DropReportRepo: dropReportRepo,
+	}
+}
+
+// UpdateAccountWeight sets the weight of the account. A weight of 0 resets it to model.AccountDefaultWeight.
+func (s *AccountWeight) UpdateAccountWeight(ctx context.Context, accountId int, weight float64) (*model.Account, error) {
+	account, err := s.AccountRepo.UpdateAccountWeight(ctx, accountId, weight)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cache.AccountByPenguinID.Delete(account.PenguinID); err != nil {
+		return nil, err
+	}
+	if err := cache.AccountByID.Delete(strconv.Itoa(account.AccountID)); err != nil {
+		return nil, err
+	}
+	if err := flushWeightedMatrixCaches(); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// accountWeightStage identifies a stage of a server. Accounts are compared with the consensus drop rates of the
+// server they have reported in, as drop rates of the same stage may differ between servers.
+type accountWeightStage struct {
+	server  string
+	stageId int
+}
+
+// RecalculateAccountWeights assigns weights to the accounts with enough reliable reports within
+// AccountWeightLookbackWindow, by the agreement of their drops with the consensus drop rates and by their
+// experience:
+//
+//	weight = AccountWeightMin + (AccountWeightMax - AccountWeightMin) * agreement * experience
+//
+// Accounts are weighted once across all servers, since the weight is stored per account: agreement is scored over
+// the stages of every server the account has reported in, and experience counts the reports of all of them. The
+// consensus drop rates an account is compared with exclude the reports of the account itself. Weights are only
+// calculated but not saved if dryRun.
+func (s *AccountWeight) RecalculateAccountWeights(ctx context.Context, dryRun bool) (*model.AccountWeightRecalculation, error) {
+	endTime := time.Now()
+	startTime := endTime.Add(-s.Config.AccountWeightLookbackWindow)
+
+	timesResults, err := s.DropReportRepo.CalcAccountStageTimes(ctx, &startTime, &endTime)
+	if err != nil {
+		return nil, err
+	}
+	quantityResults, err := s.DropReportRepo.CalcAccountStageItemQuantities(ctx, &startTime, &endTime)
+	if err != nil {
+		return nil, err
+	}
+
+	// consensus totals by stage, and by stage and item
+	stageTimes := make(map[accountWeightStage]int)
+	stageItemQuantities := make(map[accountWeightStage]map[int]int)
+	// per-account totals by stage, and by stage and item
+	accountStageTimes := make(map[int]map[accountWeightStage]int)
+	accountStageItemQuantities := make(map[int]map[accountWeightStage]map[int]int)
+	accountReports := make(map[int]int)
+	for _, result := range timesResults {
+		stage := accountWeightStage{server: result.Server, stageId: result.StageID}
+		stageTimes[stage] += result.TotalTimes
+		if _, ok := accountStageTimes[result.AccountID]; !ok {
+			accountStageTimes[result.AccountID] = make(map[accountWeightStage]int)
+		}
+		accountStageTimes[result.AccountID][stage] = result.TotalTimes
+		accountReports[result.AccountID] += result.ReportCount
+	}
+	for _, result := range quantityResults {
+		stage := accountWeightStage{server: result.Server, stageId: result.StageID}
+		if _, ok := stageItemQuantities[stage]; !ok {
+			stageItemQuantities[stage] = make(map[int]int)
+		}
+		stageItemQuantities[stage][result.ItemID] += result.TotalQuantity
+		if _, ok := accountStageItemQuantities[result.AccountID]; !ok {
+			accountStageItemQuantities[result.AccountID] = make(map[accountWeightStage]map[int]int)
+		}
+		if _, ok := accountStageItemQuantities[result.AccountID][stage]; !ok {
+			accountStageItemQuantities[result.AccountID][stage] = make(map[int]int)
+		}
+		accountStageItemQuantities[result.AccountID][stage][result.ItemID] = result.TotalQuantity
+	}
+
+	recalculation := &model.AccountWeightRecalculation{
+		StartTime: startTime,
+		EndTime:   endTime,
+		DryRun:    dryRun,
+	}
+	weights := make([]*model.AccountWeightResult, 0)
+	for accountId, reports := range accountReports {
+		if reports < s.Config.AccountWeightMinReports {
+			recalculation.Skipped++
+			continue
+		}
+
+		var zScoreSquareSum float64
+		var scored int
+		for stage, times := range accountStageTimes[accountId] {
+			othersTimes := stageTimes[stage] - times
+			if othersTimes <= 0 {
+				continue
+			}
+			for itemId, totalQuantity := range stageItemQuantities[stage] {
+				quantity := accountStageItemQuantities[accountId][stage][itemId]
+				expected := float64(totalQuantity-quantity) / float64(othersTimes) * float64(times)
+				if expected < accountWeightMinExpected {
+					continue
+				}
+				zScore := (float64(quantity) - expected) / math.Sqrt(expected)
+				zScoreSquareSum += zScore * zScore
+				scored++
+			}
+		}
+
+		weights = append(weights, s.calcAccountWeight(accountId, reports, zScoreSquareSum, scored))
+	}
+	sort.Slice(weights, func(i, j int) bool {
+		return weights[i].AccountID < weights[j].AccountID
+	})
+
+	recalculation.Evaluated = len(weights)
+	if len(weights) > 0 {
+		recalculation.MeanWeight = lo.SumBy(weights, func(w *model.AccountWeightResult) float64 { return w.Weight }) / float64(len(weights))
+	}
+	if dryRun {
+		recalculation.Weights = weights
+		return recalculation, nil
+	}
+
+	accounts := lo.Map(weights, func(w *model.AccountWeightResult, _ int) *model.Account {
+		return &model.Account{AccountID: w.AccountID, Weight: w.Weight}
+	})
+	err = s.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		for _, chunk := range lo.Chunk(accounts, 1000) {
+			if err := s.AccountRepo.UpdateAccountWeights(ctx, tx, chunk); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// cached accounts carry their weights, and deleting them one by one would take a round trip per account
+	if err := cache.AccountByID.Flush(); err != nil {
+		return nil, err
+	}
+	if err := cache.AccountByPenguinID.Flush(); err != nil {
+		return nil, err
+	}
+	if err := flushWeightedMatrixCaches(); err != nil {
+		return nil, err
+	}
+	return recalculation, nil
+}
+
+// flushWeightedMatrixCaches evicts the cached weighted matrices, which are calculated with the previous weights.
+func flushWeightedMatrixCaches() error {
+	if err := cache.WeightedDropMatrix.Flush(); err != nil {
+		return err
+	}
+	return cache.WeightedPatternMatrix.Flush()
+}
+
+// calcAccountWeight derives the weight of an account from the sum of the squared z-scores of its item quantities
+// against the consensus. A mean squared z-score of 1 is what chance alone produces, so only the excess lowers the
+// agreement. Accounts without any scored quantity are given full agreement, leaving their weight to experience.
+func (s *AccountWeight) calcAccountWeight(accountId int, reports int, zScoreSquareSum float64, scored int) *model.AccountWeightResult {
+	agreement := 1.0
+	if scored > 0 {
+		agreement = 1 / (1 + math.Max(0, zScoreSquareSum/float64(scored)-1))
+	}
+	experience := 1.0
+	if s.Config.AccountWeightFullReports > 0 {
+		experience = math.Min(1, math.Log1p(float64(reports))/math.Log1p(float64(s.Config.AccountWeightFullReports)))
+	}
+	weight := s.Config.AccountWeightMin + (s.Config.AccountWeightMax-s.Config.AccountWeightMin)*agreement*experience
+
+	return &model.AccountWeightResult{
+		AccountID:  accountId,
+		Reports:    reports,
+		Agreement:  agreement,
+		Experience: experience,
+		Weight:     weight,
+	}
+}
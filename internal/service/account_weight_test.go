@@ -0,0 +1,61 @@
+package service
+
+import (
+	"math"
+	"testing"
+
+	"exusiai.dev/backend-next/internal/app/appconfig"
+)
+
+func TestCalcAccountWeight(t *testing.T) {
+	s := &AccountWeight{
+		Config: &appconfig.Config{
+			ConfigSpec: appconfig.ConfigSpec{
+				AccountWeightMin:         0.2,
+				AccountWeightMax:         1,
+				AccountWeightFullReports: 99,
+			},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		reports         int
+		zScoreSquareSum float64
+		scored          int
+		agreement       float64
+		experience      float64
+		weight          float64
+	}{
+		{name: "no scored items", reports: 99, agreement: 1, experience: 1, weight: 1},
+		{name: "unit variance agrees fully", reports: 99, zScoreSquareSum: 10, scored: 10, agreement: 1, experience: 1, weight: 1},
+		{name: "below unit variance is not rewarded", reports: 99, zScoreSquareSum: 2, scored: 10, agreement: 1, experience: 1, weight: 1},
+		{name: "excess variance lowers agreement", reports: 99, zScoreSquareSum: 30, scored: 10, agreement: 1.0 / 3, experience: 1, weight: 0.2 + 0.8/3},
+		{name: "experience is capped", reports: 1000, agreement: 1, experience: 1, weight: 1},
+		{name: "few reports lower experience", reports: 9, agreement: 1, experience: 0.5, weight: 0.6},
+		{name: "new account", reports: 0, zScoreSquareSum: 30, scored: 10, agreement: 1.0 / 3, experience: 0, weight: 0.2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := s.calcAccountWeight(1, tt.reports, tt.zScoreSquareSum, tt.scored)
+			if got.AccountID != 1 || got.Reports != tt.reports {
+				t.Errorf("Expected account 1 with %d reports, got account %d with %d reports", tt.reports, got.AccountID, got.Reports)
+			}
+			if math.Abs(got.Agreement-tt.agreement) > 1e-9 {
+				t.Errorf("Expected agreement %f, got %f", tt.agreement, got.Agreement)
+			}
+			if math.Abs(got.Experience-tt.experience) > 1e-9 {
+				t.Errorf("Expected experience %f, got %f", tt.experience, got.Experience)
+			}
+			if math.Abs(got.Weight-tt.weight) > 1e-9 {
+				t.Errorf("Expected weight %f, got %f", tt.weight, got.Weight)
+			}
+		})
+	}
+
+	s.Config.AccountWeightFullReports = 0
+	if got := s.calcAccountWeight(1, 0, 0, 0); got.Experience != 1 || got.Weight != 1 {
+		t.Errorf("Expected experience to be ignored without a full report count, got experience %f and weight %f", got.Experience, got.Weight)
+	}
+}
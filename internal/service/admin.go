@@ -16,7 +16,6 @@ import (
 	"gopkg.in/guregu/null.v3"
 
 	"exusiai.dev/backend-next/internal/model"
-	"exusiai.dev/backend-next/internal/model/cache"
 	"exusiai.dev/backend-next/internal/model/gamedata"
 	"exusiai.dev/backend-next/internal/model/types"
 	"exusiai.dev/backend-next/internal/repo"
@@ -133,35 +132,21 @@ func (s *Admin) SaveRenderedObjects(ctx context.Context, objects *gamedata.Rende
 
 	// if no error, purge cache
 	if innerErr == nil {
-		// zone
 		if objects.Zone != nil {
-			cache.Zones.Delete()
-			cache.ShimZones.Delete()
+			purgeZoneCaches(objects.Zone.ArkZoneID)
 		}
-
-		// activity
 		if objects.Activity != nil {
-			cache.Activities.Delete()
-			cache.ShimActivities.Delete()
+			purgeActivityCaches()
 		}
-
-		// timerange
 		if objects.TimeRange != nil {
-			cache.TimeRanges.Delete(objects.TimeRange.Server)
-			cache.TimeRangesMap.Delete(objects.TimeRange.Server)
-			cache.MaxAccumulableTimeRanges.Delete(objects.TimeRange.Server)
-			cache.AllMaxAccumulableTimeRanges.Delete(objects.TimeRange.Server)
-			cache.LatestTimeRanges.Delete(objects.TimeRange.Server)
+			purgeTimeRangeCaches(objects.TimeRange.Server)
 		}
-
-		// stage
 		if len(objects.Stages) > 0 {
-			cache.Stages.Delete()
-			cache.StagesMapByID.Delete()
-			cache.StagesMapByArkID.Delete()
-			for _, server := range constant.Servers {
-				cache.ShimStages.Delete(server)
+			arkStageIds := make([]string, 0, len(objects.Stages))
+			for _, stage := range objects.Stages {
+				arkStageIds = append(arkStageIds, stage.ArkStageID)
 			}
+			purgeStageCaches(arkStageIds...)
 		}
 	}
 
@@ -378,7 +363,9 @@ func (s *Admin) CloneFromCN(ctx context.Context, req types.CloneFromCNRequest) e
 	return nil
 }
 
-func (s *Admin) GetRejectRulesReportContext(ctx context.Context, req types.RejectRulesReevaluationPreviewRequest) ([]RejectRulesReevaluationEvaluationContext, error) {
+// GetRejectRulesReportContext builds the evaluation contexts of the reports within the reevaluate range. If limit is
+// positive, only the most recent limit reports are fetched.
+func (s *Admin) GetRejectRulesReportContext(ctx context.Context, req types.RejectRulesReevaluationPreviewRequest, limit int) ([]RejectRulesReevaluationEvaluationContext, error) {
 	log.Info().
 		Str("evt.name", "admin.reject_rules.get_report_context").
 		Interface("req", req).
@@ -394,7 +381,7 @@ func (s *Admin) GetRejectRulesReportContext(ctx context.Context, req types.Rejec
 
 	var dropReports []dropReportJoinedResult
 
-	err := s.DB.NewSelect().
+	query := s.DB.NewSelect().
 		Model(&dropReports).
 		ColumnExpr("dr.*").
 		ColumnExpr("dre.*").
@@ -403,8 +390,11 @@ func (s *Admin) GetRejectRulesReportContext(ctx context.Context, req types.Rejec
 		Where("dr.created_at >= ?", req.ReevaluateRange.From).
 		Where("dr.created_at <= ?", req.ReevaluateRange.To).
 		Join("JOIN drop_report_extras as dre ON dr.report_id = dre.report_id").
-		Join("JOIN stages as st ON dr.stage_id = st.stage_id").
-		Scan(ctx)
+		Join("JOIN stages as st ON dr.stage_id = st.stage_id")
+	if limit > 0 {
+		query = query.Order("dr.created_at DESC").Limit(limit)
+	}
+	err := query.Scan(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -608,6 +598,11 @@ func (s *Admin) EvaluateRejectRules(ctx context.Context, evaluationContexts []Re
 		return nil, errors.New("rule is not active")
 	}
 
+	program, err := reportverifs.CompileRejectRuleExpr(rule.Expr)
+	if err != nil {
+		return nil, errors.New("failed to compile rule: " + err.Error())
+	}
+
 	var evalResult any
 	var evalErr error
 
@@ -619,7 +614,7 @@ func (s *Admin) EvaluateRejectRules(ctx context.Context, evaluationContexts []Re
 
 	evaluationResults := make([]*RejectRulesReevaluationEvaluationResult, len(evaluationContexts))
 	for i, evaluationContext := range evaluationContexts {
-		evalResult, evalErr = expr.Eval(rule.Expr, *evaluationContext.EvaluateContext)
+		evalResult, evalErr = expr.Run(program, *evaluationContext.EvaluateContext)
 		if evalErr != nil {
 			return nil, errors.New("failed to evaluate rule: " + evalErr.Error())
 		}
@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"exusiai.dev/gommon/constant"
+	"github.com/goccy/go-json"
+	"github.com/uptrace/bun"
+
+	"exusiai.dev/backend-next/internal/model"
+	"exusiai.dev/backend-next/internal/model/cache"
+	"exusiai.dev/backend-next/internal/pkg/pgerr"
+)
+
+const (
+	AdminAuditLogsDefaultLimit = 100
+	AdminAuditLogsMaxLimit     = 1000
+)
+
+// gameDataReference is a column of a table referencing game data, which prevents the game data from being
+// deleted while any row references it.
+type gameDataReference struct {
+	table  string
+	column string
+}
+
+var (
+	zoneReferences = []gameDataReference{
+		{"stages", "zone_id"},
+	}
+	stageReferences = []gameDataReference{
+		{"drop_infos", "stage_id"},
+		{"drop_reports", "stage_id"},
+		{"drop_matrix_elements", "stage_id"},
+		{"pattern_matrix_elements", "stage_id"},
+	}
+	timeRangeReferences = []gameDataReference{
+		{"drop_infos", "range_id"},
+		{"drop_matrix_elements", "range_id"},
+		// pattern_matrix_elements are not listed as they are persisted with their start and end times rather
+		// than a range_id (see model.PatternMatrixElement.RangeID)
+	}
+)
+
+// UpdateZone replaces the zone of zoneId. Its ark zone ID cannot be changed.
+func (s *Admin) UpdateZone(ctx context.Context, zoneId int, zone *model.Zone, ip string) error {
+	before := &model.Zone{ZoneID: zoneId}
+	zone.ZoneID = zoneId
+	err := updateGameData(ctx, s, "zones", zoneId, before, zone, ip, func() error {
+		zone.ArkZoneID = before.ArkZoneID
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	purgeZoneCaches(zone.ArkZoneID)
+	return nil
+}
+
+func (s *Admin) DeleteZone(ctx context.Context, zoneId int, ip string) error {
+	zone := &model.Zone{ZoneID: zoneId}
+	if err := deleteGameData(ctx, s, "zones", zoneId, zone, zoneReferences, ip); err != nil {
+		return err
+	}
+	purgeZoneCaches(zone.ArkZoneID)
+	return nil
+}
+
+// UpdateStage replaces the stage of stageId. Its ark stage ID cannot be changed.
+func (s *Admin) UpdateStage(ctx context.Context, stageId int, stage *model.Stage, ip string) error {
+	before := &model.Stage{StageID: stageId}
+	stage.StageID = stageId
+	err := updateGameData(ctx, s, "stages", stageId, before, stage, ip, func() error {
+		stage.ArkStageID = before.ArkStageID
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	purgeStageCaches(stage.ArkStageID)
+	return nil
+}
+
+func (s *Admin) DeleteStage(ctx context.Context, stageId int, ip string) error {
+	stage := &model.Stage{StageID: stageId}
+	if err := deleteGameData(ctx, s, "stages", stageId, stage, stageReferences, ip); err != nil {
+		return err
+	}
+	purgeStageCaches(stage.ArkStageID)
+	return nil
+}
+
+// UpdateTimeRange replaces the time range of rangeId. Its server cannot be changed.
+func (s *Admin) UpdateTimeRange(ctx context.Context, rangeId int, timeRange *model.TimeRange, ip string) error {
+	if timeRange.StartTime == nil || timeRange.EndTime == nil || !timeRange.EndTime.After(*timeRange.StartTime) {
+		return pgerr.ErrInvalidReq.Msg("startTime and endTime are required, and endTime must be after startTime")
+	}
+	before := &model.TimeRange{RangeID: rangeId}
+	timeRange.RangeID = rangeId
+	err := updateGameData(ctx, s, "time_ranges", rangeId, before, timeRange, ip, func() error {
+		timeRange.Server = before.Server
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	purgeTimeRangeCaches(timeRange.Server)
+	return nil
+}
+
+func (s *Admin) DeleteTimeRange(ctx context.Context, rangeId int, ip string) error {
+	timeRange := &model.TimeRange{RangeID: rangeId}
+	if err := deleteGameData(ctx, s, "time_ranges", rangeId, timeRange, timeRangeReferences, ip); err != nil {
+		return err
+	}
+	purgeTimeRangeCaches(timeRange.Server)
+	return nil
+}
+
+// UpdateDropInfo replaces the drop info of dropId. Its server and stage cannot be changed.
+func (s *Admin) UpdateDropInfo(ctx context.Context, dropId int, dropInfo *model.DropInfo, ip string) error {
+	if dropInfo.Bounds != nil && dropInfo.Bounds.Lower > dropInfo.Bounds.Upper {
+		return pgerr.ErrInvalidReq.Msg("bounds.lower must not be greater than bounds.upper")
+	}
+	before := &model.DropInfo{DropID: dropId}
+	dropInfo.DropID = dropId
+	err := updateGameData(ctx, s, "drop_infos", dropId, before, dropInfo, ip, func() error {
+		dropInfo.Server = before.Server
+		dropInfo.StageID = before.StageID
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	purgeDropInfoCaches(dropInfo.Server)
+	return nil
+}
+
+func (s *Admin) DeleteDropInfo(ctx context.Context, dropId int, ip string) error {
+	dropInfo := &model.DropInfo{DropID: dropId}
+	if err := deleteGameData(ctx, s, "drop_infos", dropId, dropInfo, nil, ip); err != nil {
+		return err
+	}
+	purgeDropInfoCaches(dropInfo.Server)
+	return nil
+}
+
+func (s *Admin) GetAdminAuditLogs(ctx context.Context, entity string, entityId int, limit int) ([]*model.AdminAuditLog, error) {
+	if limit <= 0 {
+		limit = AdminAuditLogsDefaultLimit
+	} else if limit > AdminAuditLogsMaxLimit {
+		limit = AdminAuditLogsMaxLimit
+	}
+	return s.AdminRepo.GetAdminAuditLogs(ctx, entity, entityId, limit)
+}
+
+// updateGameData loads before by its primary key, lets prepare carry the immutable fields over from before to
+// after, and then saves after along with an audit log, in a single transaction.
+func updateGameData[T any](ctx context.Context, s *Admin, entity string, id int, before *T, after *T, ip string, prepare func() error) error {
+	return s.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if err := s.AdminRepo.GetGameDataForUpdate(ctx, tx, before); err != nil {
+			return err
+		}
+		if err := prepare(); err != nil {
+			return err
+		}
+		if err := s.AdminRepo.UpdateGameData(ctx, tx, after); err != nil {
+			return err
+		}
+		return s.createAdminAuditLog(ctx, tx, model.AdminAuditActionUpdate, entity, id, before, after, ip)
+	})
+}
+
+// deleteGameData loads m by its primary key and deletes it along with an audit log, in a single transaction,
+// unless it is still referenced.
+func deleteGameData[T any](ctx context.Context, s *Admin, entity string, id int, m *T, references []gameDataReference, ip string) error {
+	return s.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if err := s.AdminRepo.GetGameDataForUpdate(ctx, tx, m); err != nil {
+			return err
+		}
+		for _, reference := range references {
+			referenced, err := s.AdminRepo.IsGameDataReferenced(ctx, tx, reference.table, reference.column, id)
+			if err != nil {
+				return err
+			}
+			if referenced {
+				return pgerr.ErrInvalidReq.Msg("%s %d is still referenced by %s", entity, id, reference.table)
+			}
+		}
+		if err := s.AdminRepo.DeleteGameData(ctx, tx, m); err != nil {
+			return err
+		}
+		return s.createAdminAuditLog(ctx, tx, model.AdminAuditActionDelete, entity, id, m, nil, ip)
+	})
+}
+
+func (s *Admin) createAdminAuditLog(ctx context.Context, tx bun.Tx, action string, entity string, id int, before any, after any, ip string) error {
+	beforeJSON, err := json.Marshal(before)
+	if err != nil {
+		return err
+	}
+	var afterJSON json.RawMessage
+	if after != nil {
+		afterJSON, err = json.Marshal(after)
+		if err != nil {
+			return err
+		}
+	}
+	now := time.Now()
+	return s.AdminRepo.CreateAdminAuditLog(ctx, tx, &model.AdminAuditLog{
+		Action:    action,
+		Entity:    entity,
+		EntityID:  id,
+		Before:    beforeJSON,
+		After:     afterJSON,
+		IP:        ip,
+		CreatedAt: &now,
+	})
+}
+
+func purgeZoneCaches(arkZoneIds ...string) {
+	cache.Zones.Delete()
+	cache.ShimZones.Delete()
+	for _, arkZoneId := range arkZoneIds {
+		cache.ZoneByArkID.Delete(arkZoneId)
+		cache.ShimZoneByArkID.Delete(arkZoneId)
+	}
+}
+
+func purgeActivityCaches() {
+	cache.Activities.Delete()
+	cache.ShimActivities.Delete()
+}
+
+func purgeTimeRangeCaches(server string) {
+	cache.TimeRanges.Delete(server)
+	cache.TimeRangesMap.Delete(server)
+	cache.MaxAccumulableTimeRanges.Delete(server)
+	cache.AllMaxAccumulableTimeRanges.Delete(server)
+	cache.LatestTimeRanges.Delete(server)
+	cache.TimeRangeByID.Flush()
+	cache.ItemDropSetByStageIdAndTimeRange.Flush()
+}
+
+func purgeStageCaches(arkStageIds ...string) {
+	cache.Stages.Delete()
+	cache.StagesMapByID.Delete()
+	cache.StagesMapByArkID.Delete()
+	for _, server := range constant.Servers {
+		cache.ShimStages.Delete(server)
+	}
+	for _, arkStageId := range arkStageIds {
+		cache.StageByArkID.Delete(arkStageId)
+		cache.ShimStageByArkID.Delete(arkStageId)
+	}
+}
+
+// purgeDropInfoCaches purges the caches derived from drop infos, including the accumulable time ranges.
+func purgeDropInfoCaches(server string) {
+	purgeTimeRangeCaches(server)
+	cache.ItemDropSetByStageIDAndRangeID.Flush()
+	// shim stages embed their drop infos
+	cache.ShimStages.Delete(server)
+	cache.ShimStageByArkID.Flush()
+}
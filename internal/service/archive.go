@@ -4,10 +4,13 @@ import (
 	"context"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/go-redsync/redsync/v4"
+	"github.com/parquet-go/parquet-go"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 	"github.com/uptrace/bun"
@@ -16,6 +19,12 @@ import (
 	"exusiai.dev/backend-next/internal/app/appconfig"
 	"exusiai.dev/backend-next/internal/model"
 	"exusiai.dev/backend-next/internal/pkg/archiver"
+	"exusiai.dev/backend-next/internal/util"
+)
+
+var (
+	ErrArchiveRestoreVerification  = errors.New("archive verification failed")
+	ErrArchiveStorageNotConfigured = errors.New("archive storage is not configured")
 )
 
 const (
@@ -25,62 +34,169 @@ const (
 	ArchiveS3Prefix = "v1/"
 )
 
+const (
+	ArchiveStorageS3           = "s3"
+	ArchiveStorageS3Compatible = "s3compatible"
+	ArchiveStorageLocal        = "local"
+)
+
+// ArchiveRestoreRealmResult summarizes the restoration of a single realm. Restored is less than Archived
+// when some of the archived rows still exist in (or have already been restored into) the database.
+type ArchiveRestoreRealmResult struct {
+	Archived int   `json:"archived"`
+	Restored int64 `json:"restored"`
+	FirstID  int   `json:"firstId"`
+	LastID   int   `json:"lastId"`
+}
+
+type ArchiveRestoreResult struct {
+	Date             string                    `json:"date"`
+	DropReports      ArchiveRestoreRealmResult `json:"dropReports"`
+	DropReportExtras ArchiveRestoreRealmResult `json:"dropReportExtras"`
+}
+
 type Archive struct {
 	DropReportService      *DropReport
 	DropReportExtraService *DropReportExtra
 	Config                 *appconfig.Config
 
-	s3Client *s3.Client
-	lock     *redsync.Mutex
-	db       *bun.DB
+	lock *redsync.Mutex
+	db   *bun.DB
+
+	// storageErr is set when the archive storage is not configured properly while archiving is disabled,
+	// in which case archiving and restoring fail with it instead of preventing the app from starting.
+	storageErr error
 
 	dropReportsArchiver      *archiver.Archiver
 	dropReportExtrasArchiver *archiver.Archiver
 }
 
 func NewArchive(dropReportService *DropReport, dropReportExtraService *DropReportExtra, conf *appconfig.Config, lock *redsync.Redsync, db *bun.DB) (*Archive, error) {
-	cfg, err := config.LoadDefaultConfig(context.Background(),
-		config.WithRegion(conf.DropReportArchiveS3Region),
-		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(conf.AWSAccessKey, conf.AWSSecretKey, "")),
-	)
+	storage, err := newArchiveStorage(conf)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to load aws config")
+		if conf.DropReportArchiveEnabled {
+			return nil, errors.Wrap(err, "failed to create archive storage")
+		}
+		log.Debug().
+			Err(err).
+			Str("evt.name", "archive.storage.disabled").
+			Msg("archive storage is not configured; archiving and restoring are unavailable")
+		err = errors.Wrap(ErrArchiveStorageNotConfigured, err.Error())
 	}
-	s3Client := s3.NewFromConfig(cfg)
 
 	return &Archive{
 		DropReportService:      dropReportService,
 		DropReportExtraService: dropReportExtraService,
 		Config:                 conf,
-		s3Client:               s3Client,
 		lock:                   lock.NewMutex("mutex:archiver", redsync.WithExpiry(30*time.Minute), redsync.WithTries(2)),
 		db:                     db,
+		storageErr:             err,
 		dropReportsArchiver: &archiver.Archiver{
-			S3Client:  s3Client,
-			S3Bucket:  conf.DropReportArchiveS3Bucket,
-			S3Prefix:  ArchiveS3Prefix,
-			RealmName: RealmDropReports,
+			Storage:       storage,
+			Prefix:        ArchiveS3Prefix,
+			RealmName:     RealmDropReports,
+			ParquetSchema: parquet.SchemaOf(model.DropReportArchiveRow{}),
+			ParquetRowFunc: func(item any) any {
+				return model.NewDropReportArchiveRow(item.(*model.DropReport))
+			},
 		},
 		dropReportExtrasArchiver: &archiver.Archiver{
-			S3Client:  s3Client,
-			S3Bucket:  conf.DropReportArchiveS3Bucket,
-			S3Prefix:  ArchiveS3Prefix,
-			RealmName: RealmDropReportExtras,
+			Storage:       storage,
+			Prefix:        ArchiveS3Prefix,
+			RealmName:     RealmDropReportExtras,
+			ParquetSchema: parquet.SchemaOf(model.DropReportExtraArchiveRow{}),
+			ParquetRowFunc: func(item any) any {
+				return model.NewDropReportExtraArchiveRow(item.(*model.DropReportExtra))
+			},
 		},
 	}, nil
 }
 
+func newArchiveStorage(conf *appconfig.Config) (archiver.Storage, error) {
+	switch conf.DropReportArchiveStorage {
+	case ArchiveStorageS3, ArchiveStorageS3Compatible:
+		if conf.DropReportArchiveS3Bucket == "" {
+			return nil, errors.New("DropReportArchiveS3Bucket is required")
+		}
+
+		opts := []func(*config.LoadOptions) error{
+			config.WithRegion(conf.DropReportArchiveS3Region),
+		}
+		if conf.AWSAccessKey != "" || conf.AWSSecretKey != "" {
+			opts = append(opts, config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(conf.AWSAccessKey, conf.AWSSecretKey, "")))
+		}
+		cfg, err := config.LoadDefaultConfig(context.Background(), opts...)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load aws config")
+		}
+
+		if conf.DropReportArchiveStorage == ArchiveStorageS3 {
+			if conf.DropReportArchiveS3Region == "" {
+				return nil, errors.New("DropReportArchiveS3Region is required")
+			}
+			return &archiver.S3Storage{
+				Client:       s3.NewFromConfig(cfg),
+				Bucket:       conf.DropReportArchiveS3Bucket,
+				StorageClass: s3types.StorageClassGlacierIr,
+			}, nil
+		}
+
+		if conf.DropReportArchiveS3Endpoint == "" {
+			return nil, errors.New("DropReportArchiveS3Endpoint is required")
+		}
+		if cfg.Region == "" {
+			// S3-compatible services generally ignore the region, but requests could not be signed without one
+			cfg.Region = "us-east-1"
+		}
+		return &archiver.S3Storage{
+			Client: s3.NewFromConfig(cfg, func(o *s3.Options) {
+				o.BaseEndpoint = aws.String(conf.DropReportArchiveS3Endpoint)
+				o.UsePathStyle = true
+			}),
+			Bucket: conf.DropReportArchiveS3Bucket,
+		}, nil
+	case ArchiveStorageLocal:
+		if conf.DropReportArchiveLocalDir == "" {
+			return nil, errors.New("DropReportArchiveLocalDir is required")
+		}
+		return &archiver.LocalStorage{
+			Dir: conf.DropReportArchiveLocalDir,
+		}, nil
+	default:
+		return nil, errors.Errorf("unknown archive storage %q", conf.DropReportArchiveStorage)
+	}
+}
+
 func (s *Archive) ArchiveByGlobalConfig(ctx context.Context) error {
 	targetDay := time.Now().AddDate(0, 0, -1*s.Config.NoArchiveDays)
 	return s.ArchiveByDate(ctx, targetDay, s.Config.DeleteDropReportAfterArchive)
 }
 
 func (s *Archive) ArchiveByDate(ctx context.Context, date time.Time, deleteAfterArchive bool) error {
+	return s.ArchiveByDateWithFormats(ctx, date, deleteAfterArchive, s.Config.DropReportArchiveFormats)
+}
+
+// ArchiveByDateWithFormats is the same as ArchiveByDate, but archives into the given formats
+// (see archiver.FormatJsonlGzip and archiver.FormatParquet) instead of the configured ones.
+func (s *Archive) ArchiveByDateWithFormats(ctx context.Context, date time.Time, deleteAfterArchive bool, formats []string) error {
+	if s.storageErr != nil {
+		return s.storageErr
+	}
+	for _, format := range formats {
+		if _, err := archiver.FileExt(format); err != nil {
+			return err
+		}
+	}
+
 	if err := s.lock.Lock(); err != nil {
 		return errors.Wrap(err, "failed to acquire lock")
 	}
 	defer s.lock.Unlock()
 
+	// archivers are only used with the lock held, therefore it is safe to reconfigure them here
+	s.dropReportsArchiver.Formats = formats
+	s.dropReportExtrasArchiver.Formats = formats
+
 	eg := errgroup.Group{}
 
 	if err := s.dropReportsArchiver.Prepare(ctx, date); err != nil {
@@ -277,3 +393,163 @@ func (s *Archive) DeleteReportsAndExtras(ctx context.Context, date time.Time, id
 
 	return nil
 }
+
+// RestoreByDate reads the jsonl.gz archives of the date back from the storage and reinserts the drop reports and extras
+// with their original IDs. The archives are verified before anything is written, and the restored rows are
+// counted again before committing. Rows which already exist are skipped, so the restore is idempotent.
+func (s *Archive) RestoreByDate(ctx context.Context, date time.Time) (*ArchiveRestoreResult, error) {
+	if s.storageErr != nil {
+		return nil, s.storageErr
+	}
+	if err := s.lock.Lock(); err != nil {
+		return nil, errors.Wrap(err, "failed to acquire lock")
+	}
+	defer s.lock.Unlock()
+
+	defer func() {
+		if err := s.dropReportsArchiver.Cleanup(); err != nil {
+			log.Warn().Err(err).Str("evt.name", "archive.restore.cleanup").Msg("failed to cleanup drop reports archiver")
+		}
+		if err := s.dropReportExtrasArchiver.Cleanup(); err != nil {
+			log.Warn().Err(err).Str("evt.name", "archive.restore.cleanup").Msg("failed to cleanup drop report extras archiver")
+		}
+	}()
+
+	if err := s.dropReportsArchiver.PrepareRestore(ctx, date); err != nil {
+		return nil, errors.Wrap(err, "failed to prepare drop reports archiver")
+	}
+	if err := s.dropReportExtrasArchiver.PrepareRestore(ctx, date); err != nil {
+		return nil, errors.Wrap(err, "failed to prepare drop report extras archiver")
+	}
+
+	result := &ArchiveRestoreResult{
+		Date: date.Format("2006-01-02"),
+	}
+	if err := s.verifyDropReportsArchive(date, &result.DropReports); err != nil {
+		return nil, err
+	}
+	if err := s.verifyDropReportExtrasArchive(result.DropReports, &result.DropReportExtras); err != nil {
+		return nil, err
+	}
+	log.Info().
+		Str("evt.name", "archive.restore.verified").
+		Interface("result", result).
+		Msg("verified archives")
+
+	if result.DropReports.Archived == 0 {
+		return result, nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	result.DropReports.Restored, err = restoreInBatches(s.dropReportsArchiver, s.Config.DropReportArchiveBatchSize, func(reports []*model.DropReport) (int64, error) {
+		return s.DropReportService.RestoreDropReportsFromArchive(ctx, tx, reports)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to restore drop reports")
+	}
+
+	result.DropReportExtras.Restored, err = restoreInBatches(s.dropReportExtrasArchiver, s.Config.DropReportArchiveBatchSize, func(extras []*model.DropReportExtra) (int64, error) {
+		return s.DropReportExtraService.RestoreDropReportExtrasFromArchive(ctx, tx, extras)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to restore drop report extras")
+	}
+
+	reportsCount, err := s.DropReportService.CountDropReportsForArchive(ctx, tx, date, result.DropReports.FirstID, result.DropReports.LastID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to count restored drop reports")
+	}
+	if reportsCount != result.DropReports.Archived {
+		return nil, errors.Wrapf(ErrArchiveRestoreVerification, "expected %d drop reports after restore, got %d", result.DropReports.Archived, reportsCount)
+	}
+	extrasCount, err := s.DropReportExtraService.CountDropReportExtrasForArchive(ctx, tx, result.DropReports.FirstID, result.DropReports.LastID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to count restored drop report extras")
+	}
+	if extrasCount != result.DropReportExtras.Archived {
+		return nil, errors.Wrapf(ErrArchiveRestoreVerification, "expected %d drop report extras after restore, got %d", result.DropReportExtras.Archived, extrasCount)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "failed to commit transaction")
+	}
+
+	log.Info().
+		Str("evt.name", "archive.restore.success").
+		Interface("result", result).
+		Msg("finished restoring drop reports and extras")
+
+	return result, nil
+}
+
+// verifyDropReportsArchive asserts that the archived drop reports are ordered by strictly increasing IDs
+// and are all created within the archive date.
+func (s *Archive) verifyDropReportsArchive(date time.Time, result *ArchiveRestoreRealmResult) error {
+	start := time.UnixMilli(util.GetDayStartTime(&date, "CN")) // we use CN server's day start time across all servers for archive
+	end := start.Add(time.Hour * 24)
+
+	return archiver.ReadJsonlGzip(s.dropReportsArchiver, func(report *model.DropReport) error {
+		if report.ReportID <= result.LastID {
+			return errors.Wrapf(ErrArchiveRestoreVerification, "drop report %d is out of order (after %d)", report.ReportID, result.LastID)
+		}
+		if report.CreatedAt == nil || report.CreatedAt.Before(start) || !report.CreatedAt.Before(end) {
+			return errors.Wrapf(ErrArchiveRestoreVerification, "drop report %d is not created within the archive date", report.ReportID)
+		}
+		if result.FirstID == 0 {
+			result.FirstID = report.ReportID
+		}
+		result.LastID = report.ReportID
+		result.Archived++
+		return nil
+	})
+}
+
+// verifyDropReportExtrasArchive asserts that the archived drop report extras are ordered by strictly increasing IDs
+// and are all within the ID range of the archived drop reports.
+func (s *Archive) verifyDropReportExtrasArchive(reports ArchiveRestoreRealmResult, result *ArchiveRestoreRealmResult) error {
+	return archiver.ReadJsonlGzip(s.dropReportExtrasArchiver, func(extra *model.DropReportExtra) error {
+		if extra.ReportID <= result.LastID {
+			return errors.Wrapf(ErrArchiveRestoreVerification, "drop report extra %d is out of order (after %d)", extra.ReportID, result.LastID)
+		}
+		if extra.ReportID < reports.FirstID || extra.ReportID > reports.LastID {
+			return errors.Wrapf(ErrArchiveRestoreVerification, "drop report extra %d is out of the drop reports id range [%d, %d]", extra.ReportID, reports.FirstID, reports.LastID)
+		}
+		if result.FirstID == 0 {
+			result.FirstID = extra.ReportID
+		}
+		result.LastID = extra.ReportID
+		result.Archived++
+		return nil
+	})
+}
+
+func restoreInBatches[T any](a *archiver.Archiver, batchSize int, restoreFn func(batch []*T) (int64, error)) (int64, error) {
+	var restored int64
+	batch := make([]*T, 0, batchSize)
+	flush := func() error {
+		rowsAffected, err := restoreFn(batch)
+		if err != nil {
+			return err
+		}
+		restored += rowsAffected
+		batch = batch[:0]
+		return nil
+	}
+
+	err := archiver.ReadJsonlGzip(a, func(item *T) error {
+		batch = append(batch, item)
+		if len(batch) >= batchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return restored, err
+	}
+	return restored, flush()
+}
@@ -0,0 +1,279 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"exusiai.dev/gommon/constant"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/samber/lo"
+
+	"exusiai.dev/backend-next/internal/app/appconfig"
+	"exusiai.dev/backend-next/internal/model"
+	"exusiai.dev/backend-next/internal/model/cache"
+	"exusiai.dev/backend-next/internal/pkg/observability"
+	"exusiai.dev/backend-next/internal/util"
+)
+
+// Drift detects statistically significant changes of drop rates between the current time range of a stage and
+// the previous one, which usually mean a silent game-side drop table change or a bad drop info import.
+type Drift struct {
+	Config                   *appconfig.Config
+	DropInfoService          *DropInfo
+	TimeRangeService         *TimeRange
+	DropMatrixElementService *DropMatrixElement
+	StageService             *Stage
+	ItemService              *Item
+}
+
+func NewDrift(
+	config *appconfig.Config,
+	dropInfoService *DropInfo,
+	timeRangeService *TimeRange,
+	dropMatrixElementService *DropMatrixElement,
+	stageService *Stage,
+	itemService *Item,
+) *Drift {
+	return &Drift{
+		Config:                   config,
+		DropInfoService:          dropInfoService,
+		TimeRangeService:         timeRangeService,
+		DropMatrixElementService: dropMatrixElementService,
+		StageService:             stageService,
+		ItemService:              itemService,
+	}
+}
+
+// driftPair is a stage/item pair whose drop info in the current time range is compared with the previous one.
+type driftPair struct {
+	stageId     int
+	itemId      int
+	accumulable bool
+	previous    *model.TimeRange
+	current     *model.TimeRange
+}
+
+// Cache: dropDriftReport#server|sourceCategory:{server}|{sourceCategory}, 24 hrs
+func (s *Drift) GetDropDriftReport(ctx context.Context, server string, sourceCategory string) (*model.DropDriftReport, error) {
+	valueFunc := func() (*model.DropDriftReport, error) {
+		return s.calcDropDriftReport(ctx, server, sourceCategory)
+	}
+
+	var report model.DropDriftReport
+	key := server + constant.CacheSep + sourceCategory
+	if _, err := cache.DropDriftReport.MutexGetSet(key, &report, valueFunc, 24*time.Hour); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// RunDropDriftJob recalculates the drift reports of the server for every source category the matrix worker
+// calculates, and exports them as metrics. It should run after the drop matrix job, since drop rates are
+// aggregated from the drop matrix elements.
+func (s *Drift) RunDropDriftJob(ctx context.Context, server string) error {
+	for _, sourceCategory := range s.Config.MatrixWorkerSourceCategories {
+		// deleting instead of setting the cached report is broadcast, so that other instances do not keep
+		// serving the outdated one
+		if err := cache.DropDriftReport.Delete(server + constant.CacheSep + sourceCategory); err != nil {
+			return err
+		}
+		if _, err := s.GetDropDriftReport(ctx, server, sourceCategory); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Drift) calcDropDriftReport(ctx context.Context, server string, sourceCategory string) (*model.DropDriftReport, error) {
+	pairs, err := s.getDriftPairs(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+	stagesMap, err := s.StageService.GetStagesMapById(ctx)
+	if err != nil {
+		return nil, err
+	}
+	itemsMap, err := s.ItemService.GetItemsMapById(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// query drop matrix elements once per time range, for all stages using it
+	stageIdsByRangeId := make(map[int][]int)
+	timeRangesById := make(map[int]*model.TimeRange)
+	for _, pair := range pairs {
+		for _, timeRange := range []*model.TimeRange{pair.previous, pair.current} {
+			timeRangesById[timeRange.RangeID] = timeRange
+			stageIdsByRangeId[timeRange.RangeID] = append(stageIdsByRangeId[timeRange.RangeID], pair.stageId)
+		}
+	}
+	segmentsByRangeId := make(map[int]map[int]map[int]*model.DropDriftSegment, len(stageIdsByRangeId))
+	for rangeId, stageIds := range stageIdsByRangeId {
+		segments, err := s.getDriftSegments(ctx, server, sourceCategory, timeRangesById[rangeId], lo.Uniq(stageIds))
+		if err != nil {
+			return nil, err
+		}
+		segmentsByRangeId[rangeId] = segments
+	}
+
+	report := &model.DropDriftReport{
+		Server:         server,
+		SourceCategory: sourceCategory,
+		Threshold:      s.Config.DropDriftTScoreThreshold,
+		MinTimes:       s.Config.DropDriftMinTimes,
+		Drifts:         make([]*model.DropDrift, 0),
+	}
+	for _, pair := range pairs {
+		previous := segmentsByRangeId[pair.previous.RangeID][pair.stageId][pair.itemId]
+		current := segmentsByRangeId[pair.current.RangeID][pair.stageId][pair.itemId]
+		if previous == nil || current == nil {
+			continue
+		}
+		if previous.Times < s.Config.DropDriftMinTimes || current.Times < s.Config.DropDriftMinTimes {
+			continue
+		}
+		report.Compared++
+
+		tScore := util.CalcTScore(
+			&util.StatsBundle{N: previous.Times, Avg: previous.Rate, StdDev: previous.StdDev},
+			&util.StatsBundle{N: current.Times, Avg: current.Rate, StdDev: current.StdDev},
+		)
+		if tScore < s.Config.DropDriftTScoreThreshold {
+			continue
+		}
+		drift := &model.DropDrift{
+			StageID:     pair.stageId,
+			ItemID:      pair.itemId,
+			Accumulable: pair.accumulable,
+			Previous:    previous,
+			Current:     current,
+			TScore:      util.RoundFloat64(tScore, 4),
+		}
+		if stage, ok := stagesMap[pair.stageId]; ok {
+			drift.ArkStageID = stage.ArkStageID
+		}
+		if item, ok := itemsMap[pair.itemId]; ok {
+			drift.ArkItemID = item.ArkItemID
+		}
+		report.Drifts = append(report.Drifts, drift)
+	}
+	sort.SliceStable(report.Drifts, func(i, j int) bool {
+		return report.Drifts[i].TScore > report.Drifts[j].TScore
+	})
+	report.CalculatedAt = time.Now().UnixMilli()
+
+	recordDropDriftMetrics(report)
+	return report, nil
+}
+
+// getDriftPairs finds all stage/item pairs whose drop info is in a currently open time range, and which had a
+// drop info in an earlier time range.
+func (s *Drift) getDriftPairs(ctx context.Context, server string) ([]*driftPair, error) {
+	dropInfos, err := s.DropInfoService.GetDropInfosByServer(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+	timeRangesMap, err := s.TimeRangeService.GetTimeRangesMap(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	dropInfosByStageIdAndItemId := make(map[int]map[int][]*model.DropInfo)
+	for _, dropInfo := range dropInfos {
+		timeRange, ok := timeRangesMap[dropInfo.RangeID]
+		if !dropInfo.ItemID.Valid || !ok || timeRange.StartTime.After(now) {
+			continue
+		}
+		itemId := int(dropInfo.ItemID.Int64)
+		if _, ok := dropInfosByStageIdAndItemId[dropInfo.StageID]; !ok {
+			dropInfosByStageIdAndItemId[dropInfo.StageID] = make(map[int][]*model.DropInfo)
+		}
+		dropInfosByStageIdAndItemId[dropInfo.StageID][itemId] = append(dropInfosByStageIdAndItemId[dropInfo.StageID][itemId], dropInfo)
+	}
+
+	pairs := make([]*driftPair, 0)
+	for stageId, dropInfosByItemId := range dropInfosByStageIdAndItemId {
+		for itemId, dropInfos := range dropInfosByItemId {
+			dropInfos = lo.UniqBy(dropInfos, func(dropInfo *model.DropInfo) int { return dropInfo.RangeID })
+			if len(dropInfos) < 2 {
+				continue
+			}
+			sort.Slice(dropInfos, func(i, j int) bool {
+				return timeRangesMap[dropInfos[i].RangeID].StartTime.Before(*timeRangesMap[dropInfos[j].RangeID].StartTime)
+			})
+			current := dropInfos[len(dropInfos)-1]
+			previous := dropInfos[len(dropInfos)-2]
+			if timeRangesMap[current.RangeID].EndTime.Before(now) {
+				continue
+			}
+			pairs = append(pairs, &driftPair{
+				stageId:     stageId,
+				itemId:      itemId,
+				accumulable: current.Accumulable,
+				previous:    timeRangesMap[previous.RangeID],
+				current:     timeRangesMap[current.RangeID],
+			})
+		}
+	}
+	return pairs, nil
+}
+
+// getDriftSegments aggregates the drop matrix elements of the stages within the time range. Since elements are
+// saved per day, days crossing the boundaries of the time range are left out.
+func (s *Drift) getDriftSegments(
+	ctx context.Context, server string, sourceCategory string, timeRange *model.TimeRange, stageIds []int,
+) (map[int]map[int]*model.DropDriftSegment, error) {
+	timesResults, err := s.DropMatrixElementService.GetAllTimesForGlobalDropMatrixMapByStageIdAndItemId(ctx, server, timeRange, stageIds, sourceCategory)
+	if err != nil {
+		return nil, err
+	}
+	quantityResults, err := s.DropMatrixElementService.GetAllQuantitiesForGlobalDropMatrixMapByStageIdAndItemId(ctx, server, timeRange, stageIds, sourceCategory)
+	if err != nil {
+		return nil, err
+	}
+	quantityBucketsResults, err := s.DropMatrixElementService.GetAllQuantityBucketsForGlobalDropMatrixMapByStageIdAndItemId(ctx, server, timeRange, stageIds, sourceCategory)
+	if err != nil {
+		return nil, err
+	}
+
+	segments := make(map[int]map[int]*model.DropDriftSegment)
+	for stageId, timesResultsByItemId := range timesResults {
+		segments[stageId] = make(map[int]*model.DropDriftSegment)
+		for itemId, timesResult := range timesResultsByItemId {
+			if timesResult.Times <= 1 {
+				continue
+			}
+			segment := &model.DropDriftSegment{
+				RangeID:   timeRange.RangeID,
+				StartTime: timeRange.StartTime.UnixMilli(),
+				EndTime:   timeRange.EndTime.UnixMilli(),
+				Times:     timesResult.Times,
+			}
+			// items never dropped within the time range have neither quantity nor quantity buckets
+			if quantityResult, ok := quantityResults[stageId][itemId]; ok {
+				segment.Quantity = quantityResult.Quantity
+			}
+			if quantityBucketsResult, ok := quantityBucketsResults[stageId][itemId]; ok {
+				segment.StdDev = util.CalcStdDevFromQuantityBuckets(quantityBucketsResult.QuantityBuckets, timesResult.Times, true)
+			}
+			segment.Rate = float64(segment.Quantity) / float64(segment.Times)
+			segments[stageId][itemId] = segment
+		}
+	}
+	return segments, nil
+}
+
+func recordDropDriftMetrics(report *model.DropDriftReport) {
+	observability.DropDriftSignificant.WithLabelValues(report.Server, report.SourceCategory).Set(float64(len(report.Drifts)))
+
+	// drifts which are no longer significant must not linger around
+	observability.DropDriftTScore.DeletePartialMatch(prometheus.Labels{
+		"server":          report.Server,
+		"source_category": report.SourceCategory,
+	})
+	for _, drift := range report.Drifts {
+		observability.DropDriftTScore.WithLabelValues(report.Server, report.SourceCategory, drift.ArkStageID, drift.ArkItemID).Set(drift.TScore)
+	}
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -61,7 +62,7 @@ func (s *DropMatrix) GetShimDropMatrix(
 		var dropMatrixQueryResult *model.DropMatrixQueryResult
 		var err error
 		if accountId.Valid {
-			dropMatrixQueryResult, err = s.getMaxAccumulableDropMatrixResults(ctx, server, accountId, sourceCategory)
+			dropMatrixQueryResult, err = s.getMaxAccumulableDropMatrixResults(ctx, server, accountId, sourceCategory, "", false)
 		} else {
 			dropMatrixQueryResult, err = s.calcGlobalDropMatrix(ctx, server, sourceCategory)
 		}
@@ -90,6 +91,35 @@ func (s *DropMatrix) GetShimDropMatrix(
 	return &results, nil
 }
 
+// GetShimLiveDropMatrix is GetShimDropMatrix for reports from the given country only (all countries if empty), with
+// each report weighted by the weight of its account if weighted. Since such matrices are not precomputed, they are
+// calculated from drop reports on request; only the weighted global matrices are cached, for WorkerInterval.
+//
+// Cache: weightedDropMatrix#server|sourceCategory:{server}|{sourceCategory}, WorkerInterval
+func (s *DropMatrix) GetShimLiveDropMatrix(
+	ctx context.Context, server string, showClosedZones bool, stageFilterStr string, itemFilterStr string, accountId null.Int, sourceCategory string, country string, weighted bool,
+) (*modelv2.DropMatrixQueryResult, error) {
+	valueFunc := func() (*model.DropMatrixQueryResult, error) {
+		return s.getMaxAccumulableDropMatrixResults(ctx, server, accountId, sourceCategory, country, weighted)
+	}
+
+	var dropMatrixQueryResult *model.DropMatrixQueryResult
+	if weighted && !accountId.Valid && country == "" {
+		var results model.DropMatrixQueryResult
+		if _, err := cache.WeightedDropMatrix.MutexGetSet(server+constant.CacheSep+sourceCategory, &results, valueFunc, s.Config.WorkerInterval); err != nil {
+			return nil, err
+		}
+		dropMatrixQueryResult = &results
+	} else {
+		var err error
+		dropMatrixQueryResult, err = valueFunc()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s.applyShimForDropMatrixQuery(ctx, server, showClosedZones, stageFilterStr, itemFilterStr, dropMatrixQueryResult)
+}
+
 // =========== Global Max Accumulable ===========
 
 // Calc today's drop matrix elements and save to DB
@@ -97,7 +127,7 @@ func (s *DropMatrix) GetShimDropMatrix(
 func (s *DropMatrix) RunCalcDropMatrixJob(ctx context.Context, server string) error {
 	date := time.Now()
 	endTime := time.UnixMilli(constant.FakeEndTimeMilli)
-	dropMatrixElements, err := s.calcDropMatrixByGivenDate(ctx, server, &date, &endTime, s.Config.MatrixWorkerSourceCategories)
+	dropMatrixElements, err := s.calcDropMatrixByGivenDate(ctx, server, &date, &endTime, s.Config.MatrixWorkerSourceCategories, nil)
 	if err != nil {
 		return err
 	}
@@ -116,7 +146,7 @@ func (s *DropMatrix) RunCalcDropMatrixJob(ctx context.Context, server string) er
 	// TODO: archive all drop reports for the today-60d and upload to s3
 	if !exists {
 		yesterday := date.Add(time.Hour * -24)
-		dropMatrixElementsForYesterday, err := s.calcDropMatrixByGivenDate(ctx, server, &yesterday, nil, s.Config.MatrixWorkerSourceCategories)
+		dropMatrixElementsForYesterday, err := s.calcDropMatrixByGivenDate(ctx, server, &yesterday, nil, s.Config.MatrixWorkerSourceCategories, nil)
 		if err != nil {
 			return err
 		}
@@ -126,7 +156,17 @@ func (s *DropMatrix) RunCalcDropMatrixJob(ctx context.Context, server string) er
 		}
 	}
 
-	for _, sourceCategory := range s.Config.MatrixWorkerSourceCategories {
+	if err := purgeGlobalDropMatrixCaches(server, s.Config.MatrixWorkerSourceCategories); err != nil {
+		return err
+	}
+	if err := cache.ShimTrend.Delete(server); err != nil {
+		return err
+	}
+	return nil
+}
+
+func purgeGlobalDropMatrixCaches(server string, sourceCategories []string) error {
+	for _, sourceCategory := range sourceCategories {
 		if err := cache.GlobalDropMatrix.Delete(server + constant.CacheSep + sourceCategory); err != nil {
 			return err
 		}
@@ -137,16 +177,13 @@ func (s *DropMatrix) RunCalcDropMatrixJob(ctx context.Context, server string) er
 			return err
 		}
 	}
-	if err := cache.ShimTrend.Delete(server); err != nil {
-		return err
-	}
 	return nil
 }
 
 // Update drop matrix elements for a given date (entire day)
 // Called by admin api
 func (s *DropMatrix) UpdateDropMatrixByGivenDate(ctx context.Context, server string, date *time.Time) error {
-	dropMatrixElements, err := s.calcDropMatrixByGivenDate(ctx, server, date, nil, s.Config.MatrixWorkerSourceCategories)
+	dropMatrixElements, err := s.calcDropMatrixByGivenDate(ctx, server, date, nil, s.Config.MatrixWorkerSourceCategories, nil)
 	if err != nil {
 		return err
 	}
@@ -162,9 +199,10 @@ func (s *DropMatrix) UpdateDropMatrixByGivenDate(ctx context.Context, server str
  * Calculate drop matrix for a given date
  * date: indicates the date to calculate drop matrix
  * endTime: if nil, the calculation will be done for the entire day; otherwise, the calculation will be done for the partial day
+ * stageIds: if not empty, the calculation will only be done for those stages
  */
 func (s *DropMatrix) calcDropMatrixByGivenDate(
-	ctx context.Context, server string, date *time.Time, endTime *time.Time, sourceCategories []string,
+	ctx context.Context, server string, date *time.Time, endTime *time.Time, sourceCategories []string, stageIds []int,
 ) ([]*model.DropMatrixElement, error) {
 	dropMatrixElements := make([]*model.DropMatrixElement, 0)
 
@@ -183,6 +221,9 @@ func (s *DropMatrix) calcDropMatrixByGivenDate(
 	}
 	stageIdsItemIdsMapByTimeRangeStr := make(map[string]map[int][]int, 0)
 	for stageId, timeRangesMapByItemId := range timeRangesMap {
+		if len(stageIds) > 0 && !slices.Contains(stageIds, stageId) {
+			continue
+		}
 		for itemId, timeRanges := range timeRangesMapByItemId {
 			for _, timeRange := range timeRanges {
 				intersection := util.GetIntersection(timeRange, timeRangeGiven)
@@ -239,6 +280,9 @@ func (s *DropMatrix) calcDropMatrix(ctx context.Context, queryCtx *model.DropRep
 
 	// save stage times for later use
 	stageTimesMap := map[int]int{}
+	for _, timesResult := range timesResults {
+		stageTimesMap[timesResult.StageID] = timesResult.TotalTimes
+	}
 
 	// grouping results by stage id
 	var groupedResults []linq.Group
@@ -246,6 +290,17 @@ func (s *DropMatrix) calcDropMatrix(ctx context.Context, queryCtx *model.DropRep
 		GroupByT(
 			func(el *model.CombinedResultForDropMatrix) int { return el.StageID },
 			func(el *model.CombinedResultForDropMatrix) *model.CombinedResultForDropMatrix { return el }).ToSlice(&groupedResults)
+	// stages whose reports dropped nothing at all have no quantity results, but still have their items with 0 quantity
+	groupedStageIds := make(map[int]struct{}, len(groupedResults))
+	for _, group := range groupedResults {
+		groupedStageIds[group.Key.(int)] = struct{}{}
+	}
+	for _, timesResult := range timesResults {
+		if _, ok := groupedStageIds[timesResult.StageID]; !ok {
+			groupedResults = append(groupedResults, linq.Group{Key: timesResult.StageID})
+			groupedStageIds[timesResult.StageID] = struct{}{}
+		}
+	}
 
 	dropMatrixElements := make([]*model.DropMatrixElement, 0)
 	for _, el := range groupedResults {
@@ -393,6 +448,8 @@ func (s *DropMatrix) calcGlobalDropMatrix(ctx context.Context, server string, so
 						Quantity:  quantityResult.Quantity,
 						TimeRange: timeRange,
 						StdDev:    util.RoundFloat64(util.CalcStdDevFromQuantityBuckets(quantityUniqCountResult.QuantityBuckets, timesResult.Times, false), constant.StdDevDigits),
+
+						QuantityBuckets: quantityUniqCountResult.QuantityBuckets,
 					}
 					finalResult.Matrix = append(finalResult.Matrix, oneDropMatrixElement)
 				}
@@ -412,15 +469,15 @@ func (s *DropMatrix) calcGlobalDropMatrix(ctx context.Context, server string, so
 
 // =========== Personal Max Accumulable ===========
 
-func (s *DropMatrix) getMaxAccumulableDropMatrixResults(ctx context.Context, server string, accountId null.Int, sourceCategory string) (*model.DropMatrixQueryResult, error) {
-	dropMatrixElements, err := s.getDropMatrixElements(ctx, server, accountId, sourceCategory)
+func (s *DropMatrix) getMaxAccumulableDropMatrixResults(ctx context.Context, server string, accountId null.Int, sourceCategory string, country string, weighted bool) (*model.DropMatrixQueryResult, error) {
+	dropMatrixElements, err := s.getDropMatrixElements(ctx, server, accountId, sourceCategory, country, weighted)
 	if err != nil {
 		return nil, err
 	}
 	return s.convertDropMatrixElementsToMaxAccumulableDropMatrixQueryResult(ctx, server, dropMatrixElements)
 }
 
-func (s *DropMatrix) getDropMatrixElements(ctx context.Context, server string, accountId null.Int, sourceCategory string) ([]*model.DropMatrixElement, error) {
+func (s *DropMatrix) getDropMatrixElements(ctx context.Context, server string, accountId null.Int, sourceCategory string, country string, weighted bool) ([]*model.DropMatrixElement, error) {
 	maxAccumulableTimeRanges, err := s.TimeRangeService.GetMaxAccumulableTimeRangesByServer(ctx, server)
 	if err != nil {
 		return nil, err
@@ -438,7 +495,7 @@ func (s *DropMatrix) getDropMatrixElements(ctx context.Context, server string, a
 	for _, timeRange := range timeRangesMap {
 		timeRanges = append(timeRanges, timeRange)
 	}
-	dropMatrixElements, err := s.calcDropMatrixForTimeRanges(ctx, server, timeRanges, nil, nil, accountId, sourceCategory)
+	dropMatrixElements, err := s.calcDropMatrixForTimeRanges(ctx, server, timeRanges, nil, nil, accountId, sourceCategory, country, weighted)
 	if err != nil {
 		return nil, err
 	}
@@ -475,6 +532,8 @@ func (s *DropMatrix) convertDropMatrixElementsToMaxAccumulableDropMatrixQueryRes
 					Quantity: element.Quantity,
 					Times:    element.Times,
 					StdDev:   util.RoundFloat64(util.CalcStdDevFromQuantityBuckets(element.QuantityBuckets, element.Times, false), constant.StdDevDigits),
+
+					QuantityBuckets: element.QuantityBuckets,
 				}
 				if timeRange.StartTime.Before(*startTime) {
 					startTime = timeRange.StartTime
@@ -506,10 +565,10 @@ func (s *DropMatrix) convertDropMatrixElementsToMaxAccumulableDropMatrixQueryRes
 // =========== Customized ===========
 
 func (s *DropMatrix) GetShimCustomizedDropMatrixResults(
-	ctx context.Context, server string, timeRange *model.TimeRange, stageIds []int, itemIds []int, accountId null.Int, sourceCategory string,
+	ctx context.Context, server string, timeRange *model.TimeRange, stageIds []int, itemIds []int, accountId null.Int, sourceCategory string, country string, weighted bool,
 ) (*modelv2.DropMatrixQueryResult, error) {
 	timeRanges := []*model.TimeRange{timeRange}
-	dropMatrixElements, err := s.calcDropMatrixForTimeRanges(ctx, server, timeRanges, stageIds, itemIds, accountId, sourceCategory)
+	dropMatrixElements, err := s.calcDropMatrixForTimeRanges(ctx, server, timeRanges, stageIds, itemIds, accountId, sourceCategory, country, weighted)
 	if err != nil {
 		return nil, err
 	}
@@ -553,6 +612,8 @@ func (s *DropMatrix) convertDropMatrixElementsToDropMatrixQueryResult(ctx contex
 				Times:     dropMatrixElement.Times,
 				StdDev:    util.RoundFloat64(util.CalcStdDevFromQuantityBuckets(dropMatrixElement.QuantityBuckets, dropMatrixElement.Times, false), constant.StdDevDigits),
 				TimeRange: timeRange,
+
+				QuantityBuckets: dropMatrixElement.QuantityBuckets,
 			})
 		}
 	}
@@ -563,7 +624,7 @@ func (s *DropMatrix) convertDropMatrixElementsToDropMatrixQueryResult(ctx contex
 
 // Called in Personal Max Accumulable and Customized
 func (s *DropMatrix) calcDropMatrixForTimeRanges(
-	ctx context.Context, server string, timeRanges []*model.TimeRange, stageIdFilter []int, itemIdFilter []int, accountId null.Int, sourceCategory string,
+	ctx context.Context, server string, timeRanges []*model.TimeRange, stageIdFilter []int, itemIdFilter []int, accountId null.Int, sourceCategory string, country string, weighted bool,
 ) ([]*model.DropMatrixElement, error) {
 	dropInfos, err := s.DropInfoService.GetDropInfosWithFilters(ctx, server, timeRanges, stageIdFilter, itemIdFilter)
 	if err != nil {
@@ -581,6 +642,8 @@ func (s *DropMatrix) calcDropMatrixForTimeRanges(
 			StageItemFilter:    &stageItemFilter,
 			SourceCategory:     sourceCategory,
 			ExcludeNonOneTimes: false,
+			Country:            country,
+			Weighted:           weighted,
 		}
 		timesResults, err := s.DropReportService.CalcTotalTimesForDropMatrix(ctx, queryCtx)
 		if err != nil {
@@ -843,6 +906,13 @@ func (s *DropMatrix) combineDropMatrixResults(a, b *model.OneDropMatrixElement)
 				bundleA,
 				bundleB,
 			).StdDev, constant.StdDevDigits),
+		QuantityBuckets: make(map[int]int, len(a.QuantityBuckets)),
+	}
+	for quantity, count := range a.QuantityBuckets {
+		result.QuantityBuckets[quantity] += count
+	}
+	for quantity, count := range b.QuantityBuckets {
+		result.QuantityBuckets[quantity] += count
 	}
 	return result, nil
 }
@@ -936,8 +1006,53 @@ func (s *DropMatrix) applyShimForDropMatrixQuery(ctx context.Context, server str
 			StdDev:    el.StdDev,
 			StartTime: el.TimeRange.StartTime.UnixMilli(),
 			EndTime:   endTime,
+
+			QuantityBuckets: el.QuantityBuckets,
 		}
 		results.Matrix = append(results.Matrix, &oneDropMatrixElement)
 	}
 	return results, nil
 }
+
+// ApplyConfidenceIntervals returns a copy of the given drop matrix, with the confidence interval of drop rate
+// (calculated using the given method) and the credible interval of expected quantity attached to each element.
+// The given result is left untouched, as it is usually shared with the cache.
+func (s *DropMatrix) ApplyConfidenceIntervals(result *modelv2.DropMatrixQueryResult, method string, level float64) *modelv2.DropMatrixQueryResult {
+	results := &modelv2.DropMatrixQueryResult{
+		Matrix: make([]*modelv2.OneDropMatrixElement, 0, len(result.Matrix)),
+	}
+	for _, el := range result.Matrix {
+		copied := *el
+
+		// a run is considered as "dropped" as long as the quantity is not 0
+		dropped := 0
+		for quantity, count := range copied.QuantityBuckets {
+			if quantity > 0 {
+				dropped += count
+			}
+		}
+		if dropped > copied.Times {
+			dropped = copied.Times
+		}
+
+		var lower, upper float64
+		if method == util.ConfidenceMethodClopperPearson {
+			lower, upper = util.CalcClopperPearsonInterval(dropped, copied.Times, level)
+		} else {
+			lower, upper = util.CalcWilsonInterval(dropped, copied.Times, level)
+		}
+		copied.DropRateCI = &modelv2.ConfidenceInterval{
+			Lower: util.RoundFloat64(lower, constant.StdDevDigits+2),
+			Upper: util.RoundFloat64(upper, constant.StdDevDigits+2),
+		}
+
+		lower, upper = util.CalcQuantityCredibleInterval(copied.QuantityBuckets, copied.Times, level)
+		copied.QuantityCI = &modelv2.ConfidenceInterval{
+			Lower: util.RoundFloat64(lower, constant.StdDevDigits+2),
+			Upper: util.RoundFloat64(upper, constant.StdDevDigits+2),
+		}
+
+		results.Matrix = append(results.Matrix, &copied)
+	}
+	return results
+}
@@ -32,6 +32,12 @@ func (s *DropMatrixElement) GetElementsByServerAndSourceCategoryAndDayNumRange(
 	return s.DropMatrixElementRepo.GetElementsByServerAndSourceCategoryAndDayNumRange(ctx, server, sourceCategory, startDayNum, endDayNum)
 }
 
+func (s *DropMatrixElement) GetElementsByServerAndStageIdAndTimeRange(
+	ctx context.Context, server string, stageId int, timeRange *model.TimeRange, sourceCategory string,
+) ([]*model.DropMatrixElement, error) {
+	return s.DropMatrixElementRepo.GetElementsByServerAndStageIdAndTimeRange(ctx, server, stageId, timeRange, sourceCategory)
+}
+
 func (s *DropMatrixElement) IsExistByServerAndDayNum(ctx context.Context, server string, dayNum int) (bool, error) {
 	return s.DropMatrixElementRepo.IsExistByServerAndDayNum(ctx, server, dayNum)
 }
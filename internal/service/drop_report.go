@@ -81,15 +81,15 @@ func (s *DropReport) CalcTotalTimesForPatternMatrix(
 // Trend
 
 func (s *DropReport) CalcTotalQuantityForTrend(
-	ctx context.Context, server string, startTime *time.Time, intervalLength time.Duration, intervalNum int, stageIdItemIdMap map[int][]int, accountId null.Int, sourceCategory string,
+	ctx context.Context, server string, startTime *time.Time, intervalLength time.Duration, intervalNum int, stageIdItemIdMap map[int][]int, accountId null.Int, sourceCategory string, country string, weighted bool,
 ) ([]*model.TotalQuantityResultForTrend, error) {
-	return s.DropReportRepo.CalcTotalQuantityForTrend(ctx, server, startTime, intervalLength, intervalNum, stageIdItemIdMap, accountId, sourceCategory)
+	return s.DropReportRepo.CalcTotalQuantityForTrend(ctx, server, startTime, intervalLength, intervalNum, stageIdItemIdMap, accountId, sourceCategory, country, weighted)
 }
 
 func (s *DropReport) CalcTotalTimesForTrend(
-	ctx context.Context, server string, startTime *time.Time, intervalLength time.Duration, intervalNum int, stageIds []int, accountId null.Int, sourceCategory string,
+	ctx context.Context, server string, startTime *time.Time, intervalLength time.Duration, intervalNum int, stageIds []int, accountId null.Int, sourceCategory string, country string, weighted bool,
 ) ([]*model.TotalTimesResultForTrend, error) {
-	return s.DropReportRepo.CalcTotalTimesForTrend(ctx, server, startTime, intervalLength, intervalNum, stageIds, accountId, sourceCategory)
+	return s.DropReportRepo.CalcTotalTimesForTrend(ctx, server, startTime, intervalLength, intervalNum, stageIds, accountId, sourceCategory, country, weighted)
 }
 
 // Sitestats
@@ -119,3 +119,11 @@ func (s *DropReport) GetDropReportsForArchive(ctx context.Context, cursor *model
 func (s *DropReport) DeleteDropReportsForArchive(ctx context.Context, tx bun.Tx, date time.Time) (int64, error) {
 	return s.DropReportRepo.DeleteDropReportsForArchive(ctx, tx, date)
 }
+
+func (s *DropReport) RestoreDropReportsFromArchive(ctx context.Context, tx bun.Tx, reports []*model.DropReport) (int64, error) {
+	return s.DropReportRepo.RestoreDropReportsFromArchive(ctx, tx, reports)
+}
+
+func (s *DropReport) CountDropReportsForArchive(ctx context.Context, tx bun.Tx, date time.Time, idInclusiveStart int, idInclusiveEnd int) (int, error) {
+	return s.DropReportRepo.CountDropReportsForArchive(ctx, tx, date, idInclusiveStart, idInclusiveEnd)
+}
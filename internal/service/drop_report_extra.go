@@ -2,23 +2,59 @@ package service
 
 import (
 	"context"
+	"time"
 
+	"github.com/rs/zerolog/log"
 	"github.com/uptrace/bun"
 
+	"exusiai.dev/backend-next/internal/app/appconfig"
 	"exusiai.dev/backend-next/internal/model"
 	"exusiai.dev/backend-next/internal/repo"
 )
 
+// dropReportExtraIPEraseBatchSize is the amount of IPs erased in one statement, to keep row locks short.
+const dropReportExtraIPEraseBatchSize = 10000
+
 type DropReportExtra struct {
+	Config              *appconfig.Config
 	DropReportExtraRepo *repo.DropReportExtra
 }
 
-func NewDropReportExtra(dropReportExtraRepo *repo.DropReportExtra) *DropReportExtra {
+func NewDropReportExtra(config *appconfig.Config, dropReportExtraRepo *repo.DropReportExtra) *DropReportExtra {
 	return &DropReportExtra{
+		Config:              config,
 		DropReportExtraRepo: dropReportExtraRepo,
 	}
 }
 
+// RunEraseExpiredIPsJob erases the IPs of reports older than DropReportIPRetentionDays, batch by batch until none
+// is left. It does nothing if DropReportIPRetentionDays is 0.
+func (s *DropReportExtra) RunEraseExpiredIPsJob(ctx context.Context) error {
+	if s.Config.DropReportIPRetentionDays <= 0 {
+		return nil
+	}
+	before := time.Now().AddDate(0, 0, -s.Config.DropReportIPRetentionDays)
+
+	var total int64
+	for {
+		affected, err := s.DropReportExtraRepo.EraseDropReportExtraIPs(ctx, before, dropReportExtraIPEraseBatchSize)
+		if err != nil {
+			return err
+		}
+		total += affected
+		if affected < dropReportExtraIPEraseBatchSize {
+			break
+		}
+	}
+
+	log.Info().
+		Str("evt.name", "drop_report_extra.ip.erase").
+		Time("before", before).
+		Int64("erased", total).
+		Msg("erased expired drop report ips")
+	return nil
+}
+
 func (s *DropReportExtra) GetDropReportExtraForArchive(ctx context.Context, cursor *model.Cursor, idInclusiveStart int, idInclusiveEnd int, limit int) ([]*model.DropReportExtra, model.Cursor, error) {
 	return s.DropReportExtraRepo.GetDropReportExtraForArchive(ctx, cursor, idInclusiveStart, idInclusiveEnd, limit)
 }
@@ -26,3 +62,11 @@ func (s *DropReportExtra) GetDropReportExtraForArchive(ctx context.Context, curs
 func (c *DropReportExtra) DeleteDropReportExtrasForArchive(ctx context.Context, tx bun.Tx, idInclusiveStart int, idInclusiveEnd int) (int64, error) {
 	return c.DropReportExtraRepo.DeleteDropReportExtrasForArchive(ctx, tx, idInclusiveStart, idInclusiveEnd)
 }
+
+func (c *DropReportExtra) RestoreDropReportExtrasFromArchive(ctx context.Context, tx bun.Tx, extras []*model.DropReportExtra) (int64, error) {
+	return c.DropReportExtraRepo.RestoreDropReportExtrasFromArchive(ctx, tx, extras)
+}
+
+func (c *DropReportExtra) CountDropReportExtrasForArchive(ctx context.Context, tx bun.Tx, idInclusiveStart int, idInclusiveEnd int) (int, error) {
+	return c.DropReportExtraRepo.CountDropReportExtrasForArchive(ctx, tx, idInclusiveStart, idInclusiveEnd)
+}
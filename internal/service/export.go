@@ -2,26 +2,49 @@ package service
 
 import (
 	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/parquet-go/parquet-go"
+	"github.com/pkg/errors"
 	"gopkg.in/guregu/null.v3"
 
 	"exusiai.dev/backend-next/internal/model"
+	"exusiai.dev/backend-next/internal/repo"
 )
 
+const (
+	ExportFormatNDJSON  = "ndjson"
+	ExportFormatCSV     = "csv"
+	ExportFormatParquet = "parquet"
+)
+
+// ExportParquetRowGroupSize is the amount of rows buffered in memory before they are flushed as a row group of
+// parquet exports, so that exporting a whole server does not hold all of its reports in memory.
+const ExportParquetRowGroupSize = 10000
+
+var ErrExportInvalidFormat = errors.New("invalid export format")
+
 type Export struct {
 	DropReportService         *DropReport
+	DropReportRepo            *repo.DropReport
 	DropPatternElementService *DropPatternElement
 	ItemService               *Item
 }
 
 func NewExport(
 	dropReportService *DropReport,
+	dropReportRepo *repo.DropReport,
 	dropPatternElementService *DropPatternElement,
 	itemService *Item,
 ) *Export {
 	return &Export{
 		DropReportService:         dropReportService,
+		DropReportRepo:            dropReportRepo,
 		DropPatternElementService: dropPatternElementService,
 		ItemService:               itemService,
 	}
@@ -95,3 +118,149 @@ func (s *Export) ExportDropReportsAndPatterns(
 		DropPatterns: dropPatternsForExportList,
 	}, nil
 }
+
+// ExportContentType returns the MIME type of the export format.
+func ExportContentType(format string) string {
+	switch format {
+	case ExportFormatCSV:
+		return "text/csv; charset=utf-8"
+	case ExportFormatParquet:
+		return "application/vnd.apache.parquet"
+	default:
+		return "application/x-ndjson"
+	}
+}
+
+// StreamDropReports writes drop reports matching the filter to w in the given format, row by row
+// as they are read from the database.
+func (s *Export) StreamDropReports(ctx context.Context, filter *model.DropReportExportFilter, format string, w io.Writer) error {
+	encoder, err := newDropReportExportEncoder(format, w)
+	if err != nil {
+		return err
+	}
+
+	itemsMap, err := s.ItemService.GetItemsMapById(ctx)
+	if err != nil {
+		return err
+	}
+
+	// patterns are highly repetitive across reports, therefore they are memoized for the whole export
+	patterns := make(map[int][]model.DropPatternElementForExport)
+
+	err = s.DropReportRepo.StreamDropReportsForExport(ctx, filter, func(row *model.DropReportExportRow) error {
+		drops, ok := patterns[row.PatternID]
+		if !ok {
+			elements, err := s.DropPatternElementService.GetDropPatternElementsByPatternId(ctx, row.PatternID)
+			if err != nil {
+				return err
+			}
+			drops = make([]model.DropPatternElementForExport, 0, len(elements))
+			for _, element := range elements {
+				item, ok := itemsMap[element.ItemID]
+				if !ok {
+					continue
+				}
+				drops = append(drops, model.DropPatternElementForExport{
+					ArkItemID: item.ArkItemID,
+					Quantity:  element.Quantity,
+				})
+			}
+			patterns[row.PatternID] = drops
+		}
+
+		return encoder.Encode(&model.DropReportForStreamExport{
+			ReportID:   int64(row.ReportID),
+			CreatedAt:  row.CreatedAt.UnixMilli(),
+			Server:     row.Server,
+			StageID:    row.ArkStageID,
+			Times:      int32(row.Times),
+			SourceName: row.SourceName,
+			Version:    row.Version,
+			Drops:      drops,
+		})
+	})
+	if err != nil {
+		encoder.Close()
+		return err
+	}
+
+	return encoder.Close()
+}
+
+type dropReportExportEncoder interface {
+	Encode(report *model.DropReportForStreamExport) error
+	// Close flushes all pending data. It does not close the underlying writer.
+	Close() error
+}
+
+func newDropReportExportEncoder(format string, w io.Writer) (dropReportExportEncoder, error) {
+	switch format {
+	case ExportFormatNDJSON:
+		return &ndjsonDropReportExportEncoder{enc: json.NewEncoder(w)}, nil
+	case ExportFormatCSV:
+		enc := &csvDropReportExportEncoder{w: csv.NewWriter(w)}
+		if err := enc.w.Write([]string{"report_id", "created_at", "server", "stage_id", "times", "source_name", "version", "drops"}); err != nil {
+			return nil, err
+		}
+		return enc, nil
+	case ExportFormatParquet:
+		return &parquetDropReportExportEncoder{
+			w: parquet.NewGenericWriter[model.DropReportForStreamExport](w, parquet.MaxRowsPerRowGroup(ExportParquetRowGroupSize)),
+		}, nil
+	default:
+		return nil, ErrExportInvalidFormat
+	}
+}
+
+type ndjsonDropReportExportEncoder struct {
+	enc *json.Encoder
+}
+
+func (e *ndjsonDropReportExportEncoder) Encode(report *model.DropReportForStreamExport) error {
+	return e.enc.Encode(report)
+}
+
+func (e *ndjsonDropReportExportEncoder) Close() error {
+	return nil
+}
+
+type csvDropReportExportEncoder struct {
+	w *csv.Writer
+}
+
+// Encode writes drops in a single column, formatted as `itemId:quantity` pairs separated by `|`.
+func (e *csvDropReportExportEncoder) Encode(report *model.DropReportForStreamExport) error {
+	drops := make([]string, len(report.Drops))
+	for i, drop := range report.Drops {
+		drops[i] = drop.ArkItemID + ":" + strconv.Itoa(drop.Quantity)
+	}
+
+	return e.w.Write([]string{
+		strconv.FormatInt(report.ReportID, 10),
+		strconv.FormatInt(report.CreatedAt, 10),
+		report.Server,
+		report.StageID,
+		strconv.Itoa(int(report.Times)),
+		report.SourceName,
+		report.Version,
+		strings.Join(drops, "|"),
+	})
+}
+
+func (e *csvDropReportExportEncoder) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type parquetDropReportExportEncoder struct {
+	w *parquet.GenericWriter[model.DropReportForStreamExport]
+}
+
+func (e *parquetDropReportExportEncoder) Encode(report *model.DropReportForStreamExport) error {
+	_, err := e.w.Write([]model.DropReportForStreamExport{*report})
+	return err
+}
+
+func (e *parquetDropReportExportEncoder) Close() error {
+	return e.w.Close()
+}
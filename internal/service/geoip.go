@@ -25,6 +25,15 @@ func (s *GeoIP) Country(ip string) (*geoip2.Country, error) {
 	return s.db.Country(netIP)
 }
 
+// CountryCode returns the ISO code of the country of the ip, or an empty string if it cannot be resolved.
+func (s *GeoIP) CountryCode(ip string) string {
+	country, err := s.Country(ip)
+	if err != nil || country == nil {
+		return ""
+	}
+	return country.Country.IsoCode
+}
+
 func (s *GeoIP) InChinaMainland(ip string) bool {
 	country, err := s.Country(ip)
 	if err != nil || country == nil {
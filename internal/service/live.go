@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"go.uber.org/fx"
+	"google.golang.org/protobuf/proto"
+
+	"exusiai.dev/backend-next/internal/model/pb"
+	"exusiai.dev/backend-next/internal/model/types"
+)
+
+// LiveMatrixSubjectPrefix is the NATS subject prefix matrix updates are fanned out with.
+// The full subject is suffixed by the server, e.g. LIVE.MATRIX.CN
+const LiveMatrixSubjectPrefix = "LIVE.MATRIX."
+
+// LiveSubscriberBufferSize is the amount of pending messages a subscriber could hold before
+// new messages are dropped for that subscriber.
+const LiveSubscriberBufferSize = 64
+
+var (
+	ErrLiveInvalidServer         = errors.New("invalid server")
+	ErrLiveInvalidSubscribeTopic = errors.New("either stage_id or item_id must be specified")
+)
+
+type Live struct {
+	NatsConn         *nats.Conn
+	TimeRangeService *TimeRange
+	DropInfoService  *DropInfo
+
+	// mu guards subscribers
+	mu          sync.RWMutex
+	subscribers map[*LiveSubscriber]struct{}
+}
+
+func NewLive(lc fx.Lifecycle, natsConn *nats.Conn, timeRangeService *TimeRange, dropInfoService *DropInfo) (*Live, error) {
+	s := &Live{
+		NatsConn:         natsConn,
+		TimeRangeService: timeRangeService,
+		DropInfoService:  dropInfoService,
+		subscribers:      make(map[*LiveSubscriber]struct{}),
+	}
+
+	// core NATS subscription (not a queue group) so that every instance receives every update
+	// and could dispatch it to the websocket clients connected to itself
+	sub, err := natsConn.Subscribe(LiveMatrixSubjectPrefix+"*", s.dispatch)
+	if err != nil {
+		log.Error().Err(err).Msg("service: live: failed to subscribe to live matrix updates")
+		return nil, err
+	}
+
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			return sub.Unsubscribe()
+		},
+	})
+
+	return s, nil
+}
+
+// NewSubscriber registers a new subscriber with no topics subscribed.
+// Callers must call RemoveSubscriber when the subscriber is no longer used.
+func (s *Live) NewSubscriber() *LiveSubscriber {
+	sub := &LiveSubscriber{
+		C:      make(chan *pb.MatrixUpdateMessage, LiveSubscriberBufferSize),
+		topics: make(map[liveTopic]struct{}),
+	}
+
+	s.mu.Lock()
+	s.subscribers[sub] = struct{}{}
+	s.mu.Unlock()
+
+	return sub
+}
+
+func (s *Live) RemoveSubscriber(sub *LiveSubscriber) {
+	s.mu.Lock()
+	delete(s.subscribers, sub)
+	s.mu.Unlock()
+}
+
+// BuildMatrixUpdateElements converts an accepted report into matrix deltas, one for each item
+// that could drop from the stage in its latest time range. Items which are not dropped in the
+// report still receive a delta with zero quantity, since their times have increased.
+func (s *Live) BuildMatrixUpdateElements(ctx context.Context, server string, stageId int, times int, drops []*types.Drop) ([]*pb.MatrixUpdateMessage_Element, error) {
+	serverEnum, ok := pb.Server_value[server]
+	if !ok {
+		return nil, ErrLiveInvalidServer
+	}
+
+	quantities := make(map[int]int)
+	for _, drop := range drops {
+		quantities[drop.ItemID] += drop.Quantity
+	}
+
+	latestTimeRanges, err := s.TimeRangeService.GetLatestTimeRangesByServer(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+	if timeRange, ok := latestTimeRanges[stageId]; ok {
+		itemDropSet, err := s.DropInfoService.GetItemDropSetByStageIdAndRangeId(ctx, server, stageId, timeRange.RangeID)
+		if err != nil {
+			return nil, err
+		}
+		for _, itemId := range itemDropSet {
+			if _, ok := quantities[itemId]; !ok {
+				quantities[itemId] = 0
+			}
+		}
+	}
+
+	elements := make([]*pb.MatrixUpdateMessage_Element, 0, len(quantities))
+	for itemId, quantity := range quantities {
+		elements = append(elements, &pb.MatrixUpdateMessage_Element{
+			Server:   pb.Server(serverEnum),
+			StageId:  uint32(stageId),
+			ItemId:   uint32(itemId),
+			Quantity: uint64(quantity),
+			Times:    uint64(times),
+		})
+	}
+
+	return elements, nil
+}
+
+// PublishMatrixUpdate fans out matrix deltas to all instances via NATS.
+func (s *Live) PublishMatrixUpdate(server string, elements []*pb.MatrixUpdateMessage_Element) error {
+	if len(elements) == 0 {
+		return nil
+	}
+
+	msg := &pb.MatrixUpdateMessage{
+		Header: &pb.Header{
+			Type: pb.MessageType_MATRIX_UPDATE_MESSAGE,
+		},
+		Segments: elements,
+	}
+
+	b, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	return s.NatsConn.Publish(LiveMatrixSubjectPrefix+server, b)
+}
+
+func (s *Live) dispatch(msg *nats.Msg) {
+	var update pb.MatrixUpdateMessage
+	if err := proto.Unmarshal(msg.Data, &update); err != nil {
+		log.Error().
+			Err(err).
+			Str("evt.name", "live.dispatch.unmarshal").
+			Str("subject", msg.Subject).
+			Msg("failed to unmarshal live matrix update")
+		return
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for sub := range s.subscribers {
+		filtered := sub.filter(update.Segments)
+		if len(filtered) == 0 {
+			continue
+		}
+
+		select {
+		case sub.C <- &pb.MatrixUpdateMessage{Header: update.Header, Segments: filtered}:
+		default:
+			log.Warn().
+				Str("evt.name", "live.dispatch.dropped").
+				Msg("live subscriber is too slow to consume matrix updates: dropping message")
+		}
+	}
+}
+
+type liveTopic struct {
+	server  pb.Server
+	stageId uint32
+	itemId  uint32
+}
+
+// LiveSubscriber is a single consumer (typically a websocket connection) of matrix updates.
+type LiveSubscriber struct {
+	// C receives matrix updates which matched any of the subscribed topics
+	C chan *pb.MatrixUpdateMessage
+
+	// mu guards topics
+	mu     sync.RWMutex
+	topics map[liveTopic]struct{}
+}
+
+func (sub *LiveSubscriber) Subscribe(req *pb.MatrixUpdateSubscribeReq) error {
+	topic := liveTopic{server: req.GetServer()}
+	switch id := req.GetId().(type) {
+	case *pb.MatrixUpdateSubscribeReq_StageId:
+		topic.stageId = id.StageId
+	case *pb.MatrixUpdateSubscribeReq_ItemId:
+		topic.itemId = id.ItemId
+	default:
+		return ErrLiveInvalidSubscribeTopic
+	}
+
+	sub.mu.Lock()
+	sub.topics[topic] = struct{}{}
+	sub.mu.Unlock()
+
+	return nil
+}
+
+func (sub *LiveSubscriber) filter(elements []*pb.MatrixUpdateMessage_Element) []*pb.MatrixUpdateMessage_Element {
+	sub.mu.RLock()
+	defer sub.mu.RUnlock()
+
+	if len(sub.topics) == 0 {
+		return nil
+	}
+
+	var filtered []*pb.MatrixUpdateMessage_Element
+	for _, el := range elements {
+		_, stageMatched := sub.topics[liveTopic{server: el.Server, stageId: el.StageId}]
+		_, itemMatched := sub.topics[liveTopic{server: el.Server, itemId: el.ItemId}]
+		if stageMatched || itemMatched {
+			filtered = append(filtered, el)
+		}
+	}
+
+	return filtered
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"google.golang.org/protobuf/proto"
+
+	"exusiai.dev/backend-next/internal/model/pb"
+)
+
+func TestLiveSubscriberSubscribe(t *testing.T) {
+	s := &Live{subscribers: make(map[*LiveSubscriber]struct{})}
+	sub := s.NewSubscriber()
+
+	if err := sub.Subscribe(&pb.MatrixUpdateSubscribeReq{Server: pb.Server_CN}); err != ErrLiveInvalidSubscribeTopic {
+		t.Errorf("Expected ErrLiveInvalidSubscribeTopic for a request without stage_id or item_id, got %v", err)
+	}
+	if err := sub.Subscribe(&pb.MatrixUpdateSubscribeReq{Server: pb.Server_CN, Id: &pb.MatrixUpdateSubscribeReq_StageId{StageId: 1}}); err != nil {
+		t.Fatalf("Expected no error subscribing to a stage, got %v", err)
+	}
+	if err := sub.Subscribe(&pb.MatrixUpdateSubscribeReq{Server: pb.Server_US, Id: &pb.MatrixUpdateSubscribeReq_ItemId{ItemId: 7}}); err != nil {
+		t.Fatalf("Expected no error subscribing to an item, got %v", err)
+	}
+
+	elements := []*pb.MatrixUpdateMessage_Element{
+		{Server: pb.Server_CN, StageId: 1, ItemId: 2},
+		{Server: pb.Server_CN, StageId: 3, ItemId: 7},
+		{Server: pb.Server_US, StageId: 1, ItemId: 2},
+		{Server: pb.Server_US, StageId: 3, ItemId: 7},
+	}
+	filtered := sub.filter(elements)
+	if len(filtered) != 2 || filtered[0] != elements[0] || filtered[1] != elements[3] {
+		t.Errorf("Expected elements of CN stage 1 and US item 7 only, got %v", filtered)
+	}
+}
+
+func TestLiveDispatch(t *testing.T) {
+	s := &Live{subscribers: make(map[*LiveSubscriber]struct{})}
+	subscribed := s.NewSubscriber()
+	if err := subscribed.Subscribe(&pb.MatrixUpdateSubscribeReq{Server: pb.Server_CN, Id: &pb.MatrixUpdateSubscribeReq_StageId{StageId: 1}}); err != nil {
+		t.Fatalf("Expected no error subscribing to a stage, got %v", err)
+	}
+	idle := s.NewSubscriber()
+	removed := s.NewSubscriber()
+	if err := removed.Subscribe(&pb.MatrixUpdateSubscribeReq{Server: pb.Server_CN, Id: &pb.MatrixUpdateSubscribeReq_StageId{StageId: 1}}); err != nil {
+		t.Fatalf("Expected no error subscribing to a stage, got %v", err)
+	}
+	s.RemoveSubscriber(removed)
+
+	data, err := proto.Marshal(&pb.MatrixUpdateMessage{
+		Header: &pb.Header{Type: pb.MessageType_MATRIX_UPDATE_MESSAGE},
+		Segments: []*pb.MatrixUpdateMessage_Element{
+			{Server: pb.Server_CN, StageId: 1, ItemId: 2, Quantity: 3, Times: 1},
+			{Server: pb.Server_CN, StageId: 4, ItemId: 2, Quantity: 1, Times: 1},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error marshalling update, got %v", err)
+	}
+	s.dispatch(&nats.Msg{Subject: LiveMatrixSubjectPrefix + "CN", Data: data})
+
+	select {
+	case msg := <-subscribed.C:
+		if len(msg.Segments) != 1 || msg.Segments[0].StageId != 1 || msg.Segments[0].Quantity != 3 {
+			t.Errorf("Expected only the element of stage 1, got %v", msg.Segments)
+		}
+	default:
+		t.Errorf("Expected subscribed subscriber to receive the update")
+	}
+	if len(idle.C) != 0 {
+		t.Errorf("Expected subscriber without topics not to receive the update")
+	}
+	if len(removed.C) != 0 {
+		t.Errorf("Expected removed subscriber not to receive the update")
+	}
+
+	// a full subscriber has the message dropped instead of blocking the dispatch
+	for i := 0; i < LiveSubscriberBufferSize; i++ {
+		s.dispatch(&nats.Msg{Subject: LiveMatrixSubjectPrefix + "CN", Data: data})
+	}
+	if len(subscribed.C) != LiveSubscriberBufferSize {
+		t.Errorf("Expected subscriber buffer to be full, got %d messages", len(subscribed.C))
+	}
+}
@@ -0,0 +1,800 @@
+package service
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"exusiai.dev/gommon/constant"
+	"github.com/rs/zerolog/log"
+	"github.com/uptrace/bun"
+
+	"exusiai.dev/backend-next/internal/app/appconfig"
+	"exusiai.dev/backend-next/internal/model"
+	"exusiai.dev/backend-next/internal/model/cache"
+	"exusiai.dev/backend-next/internal/model/types"
+	"exusiai.dev/backend-next/internal/pkg/observability"
+	"exusiai.dev/backend-next/internal/repo"
+	"exusiai.dev/backend-next/internal/util"
+)
+
+// MatrixDelta maintains the daily drop and pattern matrix elements incrementally, by applying the drop reports
+// which become reliable or stop being reliable as they are committed, recalled or reevaluated. Since the elements
+// are no longer recalculated as a whole, the matrix jobs reconcile them against the drop reports instead.
+//
+// Unlike the elements calculated by RunCalcDropMatrixJob and RunCalcPatternMatrixJob, the windows of today's
+// elements end at the end of the day as well, so that they never need to be rewritten once the day is over.
+type MatrixDelta struct {
+	Config                   *appconfig.Config
+	DB                       *bun.DB
+	TimeRangeService         *TimeRange
+	DropMatrixService        *DropMatrix
+	PatternMatrixService     *PatternMatrix
+	DropMatrixElementRepo    *repo.DropMatrixElement
+	PatternMatrixElementRepo *repo.PatternMatrixElement
+	DropPatternElementRepo   *repo.DropPatternElement
+
+	purgeMu     sync.Mutex
+	purgeTimers map[string]*time.Timer
+
+	reconciledMu      sync.Mutex
+	reconciledDayNums map[string]int
+}
+
+func NewMatrixDelta(
+	config *appconfig.Config,
+	db *bun.DB,
+	timeRangeService *TimeRange,
+	dropMatrixService *DropMatrix,
+	patternMatrixService *PatternMatrix,
+	dropMatrixElementRepo *repo.DropMatrixElement,
+	patternMatrixElementRepo *repo.PatternMatrixElement,
+	dropPatternElementRepo *repo.DropPatternElement,
+) *MatrixDelta {
+	return &MatrixDelta{
+		Config:                   config,
+		DB:                       db,
+		TimeRangeService:         timeRangeService,
+		DropMatrixService:        dropMatrixService,
+		PatternMatrixService:     patternMatrixService,
+		DropMatrixElementRepo:    dropMatrixElementRepo,
+		PatternMatrixElementRepo: patternMatrixElementRepo,
+		DropPatternElementRepo:   dropPatternElementRepo,
+		purgeTimers:              make(map[string]*time.Timer),
+		reconciledDayNums:        make(map[string]int),
+	}
+}
+
+// MatrixDeltaReport is a drop report to be added to (Sign 1) or removed from (Sign -1) the matrix elements.
+type MatrixDeltaReport struct {
+	Report *model.DropReport
+	Drops  []*types.Drop
+	Sign   int
+}
+
+// matrixWindow is the time range of the matrix elements of a stage on a day. For drop matrix elements, ItemIDs are
+// the items which have an element in the window even if none of them was dropped.
+type matrixWindow struct {
+	TimeRange *model.TimeRange
+	ItemIDs   []int
+}
+
+// includes tells whether a report created at t is counted in the window. The bounds are truncated to seconds, in
+// the same way as the drop report queries do.
+func (w *matrixWindow) includes(t time.Time) bool {
+	return !t.Before(time.Unix(w.TimeRange.StartTime.Unix(), 0)) && t.Before(time.Unix(w.TimeRange.EndTime.Unix(), 0))
+}
+
+// ApplyReports applies the deltas to the matrix elements within tx, and returns the servers whose elements have
+// been updated, whose caches shall be purged with SchedulePurge after tx is committed.
+func (s *MatrixDelta) ApplyReports(ctx context.Context, tx bun.Tx, deltas []*MatrixDeltaReport) ([]string, error) {
+	if !s.Config.MatrixIncrementalEnabled || len(deltas) == 0 {
+		return nil, nil
+	}
+
+	type group struct {
+		server  string
+		dayNum  int
+		stageId int
+		deltas  []*MatrixDeltaReport
+	}
+	groups := make([]*group, 0)
+	groupsMap := make(map[string]*group)
+	for _, delta := range deltas {
+		dayNum := util.GetDayNum(delta.Report.CreatedAt, delta.Report.Server)
+		key := matrixGroupKey(delta.Report.Server, dayNum, delta.Report.StageID)
+		if _, ok := groupsMap[key]; !ok {
+			groupsMap[key] = &group{server: delta.Report.Server, dayNum: dayNum, stageId: delta.Report.StageID}
+			groups = append(groups, groupsMap[key])
+		}
+		groupsMap[key].deltas = append(groupsMap[key].deltas, delta)
+	}
+	// lock in the same order in all transactions to avoid deadlocks
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].server != groups[j].server {
+			return groups[i].server < groups[j].server
+		}
+		if groups[i].dayNum != groups[j].dayNum {
+			return groups[i].dayNum < groups[j].dayNum
+		}
+		return groups[i].stageId < groups[j].stageId
+	})
+
+	servers := make([]string, 0)
+	lockedDays := make(map[string]struct{})
+	for _, g := range groups {
+		dayKey := g.server + constant.CacheSep + strconv.Itoa(g.dayNum)
+		if _, ok := lockedDays[dayKey]; !ok {
+			if err := repo.LockMatrixDay(ctx, tx, g.server, g.dayNum, true); err != nil {
+				return nil, err
+			}
+			lockedDays[dayKey] = struct{}{}
+		}
+		if err := repo.LockMatrixStage(ctx, tx, g.server, g.dayNum, g.stageId); err != nil {
+			return nil, err
+		}
+		if err := s.applyDropMatrixDeltas(ctx, tx, g.server, g.dayNum, g.stageId, g.deltas); err != nil {
+			return nil, err
+		}
+		if err := s.applyPatternMatrixDeltas(ctx, tx, g.server, g.dayNum, g.stageId, g.deltas); err != nil {
+			return nil, err
+		}
+		if !slices.Contains(servers, g.server) {
+			servers = append(servers, g.server)
+		}
+	}
+	return servers, nil
+}
+
+// ApplyReliabilityChanges applies the reports whose reliability is changed to reliabilities (by report id) to the
+// matrix elements within tx. reports are the rows before the change; those not in reliabilities, or still either
+// reliable or not, are left out. Removed reports shall be given a negative reliability.
+func (s *MatrixDelta) ApplyReliabilityChanges(ctx context.Context, tx bun.Tx, reports []*model.DropReport, reliabilities map[int]int) ([]string, error) {
+	if !s.Config.MatrixIncrementalEnabled {
+		return nil, nil
+	}
+
+	changed := make([]*model.DropReport, 0)
+	signs := make(map[int]int)
+	patternIds := make([]int, 0)
+	for _, report := range reports {
+		reliability, ok := reliabilities[report.ReportID]
+		if !ok || (report.Reliability == 0) == (reliability == 0) {
+			continue
+		}
+		changed = append(changed, report)
+		signs[report.ReportID] = 1
+		if reliability != 0 {
+			signs[report.ReportID] = -1
+		}
+		if !slices.Contains(patternIds, report.PatternID) {
+			patternIds = append(patternIds, report.PatternID)
+		}
+	}
+	if len(changed) == 0 {
+		return nil, nil
+	}
+
+	elements, err := s.DropPatternElementRepo.GetDropPatternElementsByPatternIds(ctx, patternIds)
+	if err != nil {
+		return nil, err
+	}
+	dropsMap := make(map[int][]*types.Drop)
+	for _, element := range elements {
+		dropsMap[element.DropPatternID] = append(dropsMap[element.DropPatternID], &types.Drop{
+			ItemID:   element.ItemID,
+			Quantity: element.Quantity,
+		})
+	}
+
+	deltas := make([]*MatrixDeltaReport, 0, len(changed))
+	for _, report := range changed {
+		deltas = append(deltas, &MatrixDeltaReport{
+			Report: report,
+			Drops:  dropsMap[report.PatternID],
+			Sign:   signs[report.ReportID],
+		})
+	}
+	return s.ApplyReports(ctx, tx, deltas)
+}
+
+// SchedulePurge purges the global matrix caches of the servers after MatrixIncrementalPurgeDelay, unless a purge
+// is already pending for a server, which then covers the elements updated so far as well.
+func (s *MatrixDelta) SchedulePurge(servers ...string) {
+	s.purgeMu.Lock()
+	defer s.purgeMu.Unlock()
+	for _, server := range servers {
+		if _, ok := s.purgeTimers[server]; ok {
+			continue
+		}
+		server := server
+		s.purgeTimers[server] = time.AfterFunc(s.Config.MatrixIncrementalPurgeDelay, func() {
+			s.purgeMu.Lock()
+			delete(s.purgeTimers, server)
+			s.purgeMu.Unlock()
+
+			if err := s.purgeCaches(server); err != nil {
+				log.Error().
+					Err(err).
+					Str("evt.name", "matrix.incremental.purge").
+					Str("server", server).
+					Msg("failed to purge global matrix caches")
+			}
+		})
+	}
+}
+
+func (s *MatrixDelta) purgeCaches(server string) error {
+	if err := purgeGlobalDropMatrixCaches(server, s.Config.MatrixWorkerSourceCategories); err != nil {
+		return err
+	}
+	if err := cache.ShimTrend.Delete(server); err != nil {
+		return err
+	}
+	return purgeGlobalPatternMatrixCaches(server, s.Config.MatrixWorkerSourceCategories)
+}
+
+func (s *MatrixDelta) applyDropMatrixDeltas(ctx context.Context, tx bun.Tx, server string, dayNum int, stageId int, deltas []*MatrixDeltaReport) error {
+	windows, err := s.getDropMatrixWindows(ctx, server, dayNum, stageId)
+	if err != nil {
+		return err
+	}
+	if len(windows) == 0 {
+		return nil
+	}
+	stored, err := s.DropMatrixElementRepo.GetElementsByServerAndDayNum(ctx, tx, server, dayNum, []int{stageId})
+	if err != nil {
+		return err
+	}
+	storedMap := make(map[string]map[int]*model.DropMatrixElement)
+	for _, element := range stored {
+		key := matrixElementWindowKey(element.SourceCategory, element.StartTime, element.EndTime)
+		if _, ok := storedMap[key]; !ok {
+			storedMap[key] = make(map[int]*model.DropMatrixElement)
+		}
+		storedMap[key][element.ItemID] = element
+	}
+
+	toCreate := make([]*model.DropMatrixElement, 0)
+	toUpdate := make([]*model.DropMatrixElement, 0)
+	toDelete := make([]int, 0)
+	for _, window := range windows {
+		for _, sourceCategory := range s.Config.MatrixWorkerSourceCategories {
+			windowDeltas := filterMatrixDeltas(deltas, window, sourceCategory)
+			if len(windowDeltas) == 0 {
+				continue
+			}
+			elements := storedMap[matrixElementWindowKey(sourceCategory, window.TimeRange.StartTime, window.TimeRange.EndTime)]
+			created, updated, deleted := accumulateDropMatrixDeltas(elements, window, windowDeltas, func(itemId int) *model.DropMatrixElement {
+				return &model.DropMatrixElement{
+					StageID:         stageId,
+					ItemID:          itemId,
+					StartTime:       window.TimeRange.StartTime,
+					EndTime:         window.TimeRange.EndTime,
+					DayNum:          dayNum,
+					QuantityBuckets: make(map[int]int),
+					Server:          server,
+					SourceCategory:  sourceCategory,
+				}
+			})
+			toCreate = append(toCreate, created...)
+			toUpdate = append(toUpdate, updated...)
+			toDelete = append(toDelete, deleted...)
+		}
+	}
+
+	if len(toCreate) > 0 {
+		if err := s.DropMatrixElementRepo.CreateElements(ctx, tx, toCreate); err != nil {
+			return err
+		}
+	}
+	if len(toUpdate) > 0 {
+		if err := s.DropMatrixElementRepo.UpdateElementCounters(ctx, tx, toUpdate); err != nil {
+			return err
+		}
+	}
+	if len(toDelete) > 0 {
+		if err := s.DropMatrixElementRepo.DeleteElementsByIds(ctx, tx, toDelete); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *MatrixDelta) applyPatternMatrixDeltas(ctx context.Context, tx bun.Tx, server string, dayNum int, stageId int, deltas []*MatrixDeltaReport) error {
+	// only reports of a single time are counted in the pattern matrix
+	deltas = slices.DeleteFunc(slices.Clone(deltas), func(delta *MatrixDeltaReport) bool {
+		return delta.Report.Times != 1
+	})
+	if len(deltas) == 0 {
+		return nil
+	}
+	window, err := s.getPatternMatrixWindow(ctx, server, dayNum, stageId)
+	if err != nil {
+		return err
+	}
+	if window == nil {
+		return nil
+	}
+	stored, err := s.PatternMatrixElementRepo.GetElementsByServerAndDayNum(ctx, tx, server, dayNum, []int{stageId})
+	if err != nil {
+		return err
+	}
+	storedMap := make(map[string]map[int]*model.PatternMatrixElement)
+	for _, element := range stored {
+		key := matrixElementWindowKey(element.SourceCategory, element.StartTime, element.EndTime)
+		if _, ok := storedMap[key]; !ok {
+			storedMap[key] = make(map[int]*model.PatternMatrixElement)
+		}
+		storedMap[key][element.PatternID] = element
+	}
+
+	toCreate := make([]*model.PatternMatrixElement, 0)
+	toUpdate := make([]*model.PatternMatrixElement, 0)
+	toDelete := make([]int, 0)
+	for _, sourceCategory := range s.Config.MatrixWorkerSourceCategories {
+		windowDeltas := filterMatrixDeltas(deltas, window, sourceCategory)
+		if len(windowDeltas) == 0 {
+			continue
+		}
+		elements := storedMap[matrixElementWindowKey(sourceCategory, window.TimeRange.StartTime, window.TimeRange.EndTime)]
+		created, updated, deleted := accumulatePatternMatrixDeltas(elements, windowDeltas, func(patternId int) *model.PatternMatrixElement {
+			return &model.PatternMatrixElement{
+				StageID:        stageId,
+				PatternID:      patternId,
+				StartTime:      window.TimeRange.StartTime,
+				EndTime:        window.TimeRange.EndTime,
+				DayNum:         dayNum,
+				Server:         server,
+				SourceCategory: sourceCategory,
+			}
+		})
+		toCreate = append(toCreate, created...)
+		toUpdate = append(toUpdate, updated...)
+		toDelete = append(toDelete, deleted...)
+	}
+
+	if len(toCreate) > 0 {
+		if err := s.PatternMatrixElementRepo.CreateElements(ctx, tx, toCreate); err != nil {
+			return err
+		}
+	}
+	if len(toUpdate) > 0 {
+		if err := s.PatternMatrixElementRepo.UpdateElementCounters(ctx, tx, toUpdate); err != nil {
+			return err
+		}
+	}
+	if len(toDelete) > 0 {
+		if err := s.PatternMatrixElementRepo.DeleteElementsByIds(ctx, tx, toDelete); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// accumulateDropMatrixDeltas applies the deltas of a window to the stored drop matrix elements of the window (by
+// item id, or nil if there is none), creating elements with newElement for the items dropped or of the window which
+// have none yet. It returns the elements to be created and updated, and the ids of the elements to be deleted.
+func accumulateDropMatrixDeltas(
+	elements map[int]*model.DropMatrixElement, window *matrixWindow, deltas []*MatrixDeltaReport, newElement func(itemId int) *model.DropMatrixElement,
+) (toCreate []*model.DropMatrixElement, toUpdate []*model.DropMatrixElement, toDelete []int) {
+	if elements == nil {
+		elements = make(map[int]*model.DropMatrixElement)
+	}
+	// all elements of a stage in a window share the same times
+	times := 0
+	for _, element := range elements {
+		times = element.Times
+		break
+	}
+	created := make(map[int]struct{})
+	create := func(itemId int) *model.DropMatrixElement {
+		element := newElement(itemId)
+		elements[itemId] = element
+		created[itemId] = struct{}{}
+		return element
+	}
+
+	for _, delta := range deltas {
+		times += delta.Sign * delta.Report.Times
+		for _, drop := range delta.Drops {
+			element, ok := elements[drop.ItemID]
+			if !ok {
+				// the reconciliation will take care of an element missing for a removed report
+				if delta.Sign < 0 {
+					continue
+				}
+				element = create(drop.ItemID)
+			}
+			if element.QuantityBuckets == nil {
+				element.QuantityBuckets = make(map[int]int)
+			}
+			element.Quantity += delta.Sign * drop.Quantity
+			element.QuantityBuckets[drop.Quantity] += delta.Sign
+		}
+	}
+	for _, itemId := range window.ItemIDs {
+		if _, ok := elements[itemId]; !ok {
+			create(itemId)
+		}
+	}
+
+	for itemId, element := range elements {
+		element.Times = times
+		element.QuantityBuckets = normalizeQuantityBuckets(element.QuantityBuckets, times)
+		_, isNew := created[itemId]
+		// elements are only kept for the items of the window unless they have been dropped
+		if times <= 0 || (element.Quantity <= 0 && !slices.Contains(window.ItemIDs, itemId)) {
+			if !isNew {
+				toDelete = append(toDelete, element.ElementID)
+			}
+		} else if isNew {
+			toCreate = append(toCreate, element)
+		} else {
+			toUpdate = append(toUpdate, element)
+		}
+	}
+	return toCreate, toUpdate, toDelete
+}
+
+// accumulatePatternMatrixDeltas is the same as accumulateDropMatrixDeltas, but for the pattern matrix elements of a
+// window by pattern id. Elements are only created for the patterns of the deltas.
+func accumulatePatternMatrixDeltas(
+	elements map[int]*model.PatternMatrixElement, deltas []*MatrixDeltaReport, newElement func(patternId int) *model.PatternMatrixElement,
+) (toCreate []*model.PatternMatrixElement, toUpdate []*model.PatternMatrixElement, toDelete []int) {
+	if elements == nil {
+		elements = make(map[int]*model.PatternMatrixElement)
+	}
+	created := make(map[int]struct{})
+	for _, delta := range deltas {
+		element, ok := elements[delta.Report.PatternID]
+		if !ok {
+			if delta.Sign < 0 {
+				continue
+			}
+			element = newElement(delta.Report.PatternID)
+			elements[delta.Report.PatternID] = element
+			created[delta.Report.PatternID] = struct{}{}
+		}
+		element.Quantity += delta.Sign
+	}
+
+	// every counted report has exactly one pattern, so the times are the sum of the quantities
+	times := 0
+	for _, element := range elements {
+		times += max(element.Quantity, 0)
+	}
+	for patternId, element := range elements {
+		element.Times = times
+		_, isNew := created[patternId]
+		if element.Quantity <= 0 {
+			if !isNew {
+				toDelete = append(toDelete, element.ElementID)
+			}
+		} else if isNew {
+			toCreate = append(toCreate, element)
+		} else {
+			toUpdate = append(toUpdate, element)
+		}
+	}
+	return toCreate, toUpdate, toDelete
+}
+
+// getDropMatrixWindows returns the windows of the drop matrix elements of the stage on the day, in the same way as
+// calcDropMatrixByGivenDate derives them from the max accumulable time ranges.
+func (s *MatrixDelta) getDropMatrixWindows(ctx context.Context, server string, dayNum int, stageId int) ([]*matrixWindow, error) {
+	timeRangesMap, err := s.TimeRangeService.GetAllMaxAccumulableTimeRangesByServer(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+	day := matrixDayTimeRange(server, dayNum)
+	windows := make([]*matrixWindow, 0)
+	windowsMap := make(map[string]*matrixWindow)
+	for itemId, timeRanges := range timeRangesMap[stageId] {
+		for _, timeRange := range timeRanges {
+			intersection := util.GetIntersection(timeRange, day)
+			if intersection == nil {
+				continue
+			}
+			key := intersection.String()
+			if _, ok := windowsMap[key]; !ok {
+				// calcDropMatrixByGivenDate truncates the windows to seconds by converting them from their strings
+				windowsMap[key] = &matrixWindow{TimeRange: model.TimeRangeFromString(key)}
+				windows = append(windows, windowsMap[key])
+			}
+			windowsMap[key].ItemIDs = append(windowsMap[key].ItemIDs, itemId)
+		}
+	}
+	return windows, nil
+}
+
+// getPatternMatrixWindow returns the window of the pattern matrix elements of the stage on the day, in the same way
+// as calcPatternMatrixByGivenDate derives it from the latest time range of the stage, or nil if there is none.
+func (s *MatrixDelta) getPatternMatrixWindow(ctx context.Context, server string, dayNum int, stageId int) (*matrixWindow, error) {
+	excludeStageIdsSet, err := s.PatternMatrixService.getExcludeStageIdsSet(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := excludeStageIdsSet[stageId]; ok {
+		return nil, nil
+	}
+	latestTimeRanges, err := s.TimeRangeService.GetLatestTimeRangesByServer(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+	intersection := util.GetIntersection(latestTimeRanges[stageId], matrixDayTimeRange(server, dayNum))
+	if intersection == nil {
+		return nil, nil
+	}
+	return &matrixWindow{TimeRange: intersection}, nil
+}
+
+// RunReconcileDropMatrixJob reconciles today's drop matrix elements, and yesterday's as well on the first run of
+// the day, against the drop reports. Stages whose elements have drifted are recalculated.
+// Called by worker
+func (s *MatrixDelta) RunReconcileDropMatrixJob(ctx context.Context, server string) error {
+	return s.runReconcileJob(ctx, server, "drop", s.reconcileDropMatrix, func() error {
+		if err := purgeGlobalDropMatrixCaches(server, s.Config.MatrixWorkerSourceCategories); err != nil {
+			return err
+		}
+		return cache.ShimTrend.Delete(server)
+	})
+}
+
+// RunReconcilePatternMatrixJob is the same as RunReconcileDropMatrixJob, but for the pattern matrix elements.
+// Called by worker
+func (s *MatrixDelta) RunReconcilePatternMatrixJob(ctx context.Context, server string) error {
+	return s.runReconcileJob(ctx, server, "pattern", s.reconcilePatternMatrix, func() error {
+		return purgeGlobalPatternMatrixCaches(server, s.Config.MatrixWorkerSourceCategories)
+	})
+}
+
+func (s *MatrixDelta) runReconcileJob(
+	ctx context.Context, server string, matrix string,
+	reconcile func(ctx context.Context, server string, dayNum int) ([]int, error), purge func() error,
+) error {
+	now := time.Now()
+	dayNum := util.GetDayNum(&now, server)
+	dayNums := []int{dayNum}
+	key := matrix + constant.CacheSep + server
+	s.reconciledMu.Lock()
+	reconciledDayNum, ok := s.reconciledDayNums[key]
+	s.reconciledMu.Unlock()
+	// reports of yesterday might still have been committed after yesterday's last reconciliation
+	if !ok || reconciledDayNum != dayNum {
+		dayNums = []int{dayNum - 1, dayNum}
+	}
+
+	drifted := 0
+	for _, d := range dayNums {
+		stageIds, err := reconcile(ctx, server, d)
+		if err != nil {
+			return err
+		}
+		if len(stageIds) > 0 {
+			log.Warn().
+				Str("evt.name", "matrix.reconcile.drifted").
+				Str("server", server).
+				Str("matrix", matrix).
+				Int("dayNum", d).
+				Ints("stageIds", stageIds).
+				Msg("matrix elements drifted from drop reports: recalculated")
+		}
+		drifted += len(stageIds)
+	}
+	observability.MatrixReconcileDriftedStages.WithLabelValues(server, matrix).Set(float64(drifted))
+
+	s.reconciledMu.Lock()
+	s.reconciledDayNums[key] = dayNum
+	s.reconciledMu.Unlock()
+
+	if drifted == 0 {
+		return nil
+	}
+	return purge()
+}
+
+// reconcileDropMatrix recalculates the drop matrix elements of the stages which drifted on the day, and returns
+// those stages.
+func (s *MatrixDelta) reconcileDropMatrix(ctx context.Context, server string, dayNum int) ([]int, error) {
+	date := time.UnixMilli(util.GetDayStartTimestampFromDayNum(dayNum, server))
+	sourceCategories := s.Config.MatrixWorkerSourceCategories
+
+	// compare without locking first, as most of the time nothing has drifted
+	expected, err := s.DropMatrixService.calcDropMatrixByGivenDate(ctx, server, &date, nil, sourceCategories, nil)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := s.DropMatrixElementRepo.GetElementsByServerAndDayNum(ctx, s.DB, server, dayNum, nil)
+	if err != nil {
+		return nil, err
+	}
+	stageIds := driftedMatrixStages(expected, stored, dropMatrixElementKey, dropMatrixElementsEqual)
+	if len(stageIds) == 0 {
+		return nil, nil
+	}
+
+	err = s.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if err := repo.LockMatrixDay(ctx, tx, server, dayNum, false); err != nil {
+			return err
+		}
+		// reports committed in the meantime make stages look drifted as well, so compare them again while no
+		// report can be applied
+		expected, err := s.DropMatrixService.calcDropMatrixByGivenDate(ctx, server, &date, nil, sourceCategories, stageIds)
+		if err != nil {
+			return err
+		}
+		stored, err := s.DropMatrixElementRepo.GetElementsByServerAndDayNum(ctx, tx, server, dayNum, stageIds)
+		if err != nil {
+			return err
+		}
+		stageIds = driftedMatrixStages(expected, stored, dropMatrixElementKey, dropMatrixElementsEqual)
+		if len(stageIds) == 0 {
+			return nil
+		}
+		if err := s.DropMatrixElementRepo.DeleteByServerAndDayNumAndStageIds(ctx, tx, server, dayNum, stageIds); err != nil {
+			return err
+		}
+		expected = slices.DeleteFunc(expected, func(element *model.DropMatrixElement) bool {
+			return !slices.Contains(stageIds, element.StageID)
+		})
+		if len(expected) == 0 {
+			return nil
+		}
+		return s.DropMatrixElementRepo.CreateElements(ctx, tx, expected)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stageIds, nil
+}
+
+// reconcilePatternMatrix is the same as reconcileDropMatrix, but for the pattern matrix elements.
+func (s *MatrixDelta) reconcilePatternMatrix(ctx context.Context, server string, dayNum int) ([]int, error) {
+	date := time.UnixMilli(util.GetDayStartTimestampFromDayNum(dayNum, server))
+	sourceCategories := s.Config.MatrixWorkerSourceCategories
+
+	expected, err := s.PatternMatrixService.calcPatternMatrixByGivenDate(ctx, server, &date, nil, sourceCategories, nil)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := s.PatternMatrixElementRepo.GetElementsByServerAndDayNum(ctx, s.DB, server, dayNum, nil)
+	if err != nil {
+		return nil, err
+	}
+	stageIds := driftedMatrixStages(expected, stored, patternMatrixElementKey, patternMatrixElementsEqual)
+	if len(stageIds) == 0 {
+		return nil, nil
+	}
+
+	err = s.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if err := repo.LockMatrixDay(ctx, tx, server, dayNum, false); err != nil {
+			return err
+		}
+		expected, err := s.PatternMatrixService.calcPatternMatrixByGivenDate(ctx, server, &date, nil, sourceCategories, stageIds)
+		if err != nil {
+			return err
+		}
+		stored, err := s.PatternMatrixElementRepo.GetElementsByServerAndDayNum(ctx, tx, server, dayNum, stageIds)
+		if err != nil {
+			return err
+		}
+		stageIds = driftedMatrixStages(expected, stored, patternMatrixElementKey, patternMatrixElementsEqual)
+		if len(stageIds) == 0 {
+			return nil
+		}
+		if err := s.PatternMatrixElementRepo.DeleteByServerAndDayNumAndStageIds(ctx, tx, server, dayNum, stageIds); err != nil {
+			return err
+		}
+		expected = slices.DeleteFunc(expected, func(element *model.PatternMatrixElement) bool {
+			return !slices.Contains(stageIds, element.StageID)
+		})
+		if len(expected) == 0 {
+			return nil
+		}
+		return s.PatternMatrixElementRepo.CreateElements(ctx, tx, expected)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stageIds, nil
+}
+
+// driftedMatrixStages returns the stages, in ascending order, of which any element is missing, superfluous or
+// differs between expected and stored.
+func driftedMatrixStages[T any](expected []T, stored []T, keyFunc func(T) (string, int), equalFunc func(a, b T) bool) []int {
+	drifted := make(map[int]struct{})
+	expectedMap := make(map[string]T, len(expected))
+	for _, element := range expected {
+		key, _ := keyFunc(element)
+		expectedMap[key] = element
+	}
+	for _, element := range stored {
+		key, stageId := keyFunc(element)
+		if e, ok := expectedMap[key]; !ok || !equalFunc(e, element) {
+			drifted[stageId] = struct{}{}
+		}
+		delete(expectedMap, key)
+	}
+	for _, element := range expectedMap {
+		_, stageId := keyFunc(element)
+		drifted[stageId] = struct{}{}
+	}
+
+	stageIds := make([]int, 0, len(drifted))
+	for stageId := range drifted {
+		stageIds = append(stageIds, stageId)
+	}
+	sort.Ints(stageIds)
+	return stageIds
+}
+
+func dropMatrixElementKey(element *model.DropMatrixElement) (string, int) {
+	return matrixElementWindowKey(element.SourceCategory, element.StartTime, element.EndTime) +
+		constant.CacheSep + strconv.Itoa(element.StageID) + constant.CacheSep + strconv.Itoa(element.ItemID), element.StageID
+}
+
+func dropMatrixElementsEqual(a, b *model.DropMatrixElement) bool {
+	return a.Quantity == b.Quantity && a.Times == b.Times && maps.Equal(a.QuantityBuckets, b.QuantityBuckets)
+}
+
+func patternMatrixElementKey(element *model.PatternMatrixElement) (string, int) {
+	return matrixElementWindowKey(element.SourceCategory, element.StartTime, element.EndTime) +
+		constant.CacheSep + strconv.Itoa(element.StageID) + constant.CacheSep + strconv.Itoa(element.PatternID), element.StageID
+}
+
+func patternMatrixElementsEqual(a, b *model.PatternMatrixElement) bool {
+	return a.Quantity == b.Quantity && a.Times == b.Times
+}
+
+func matrixElementWindowKey(sourceCategory string, start *time.Time, end *time.Time) string {
+	return sourceCategory + constant.CacheSep + strconv.FormatInt(start.UnixMilli(), 10) + constant.CacheSep + strconv.FormatInt(end.UnixMilli(), 10)
+}
+
+func matrixGroupKey(server string, dayNum int, stageId int) string {
+	return server + constant.CacheSep + strconv.Itoa(dayNum) + constant.CacheSep + strconv.Itoa(stageId)
+}
+
+func matrixDayTimeRange(server string, dayNum int) *model.TimeRange {
+	start := time.UnixMilli(util.GetDayStartTimestampFromDayNum(dayNum, server))
+	end := start.Add(time.Hour * 24)
+	return &model.TimeRange{
+		StartTime: &start,
+		EndTime:   &end,
+	}
+}
+
+// filterMatrixDeltas returns the deltas of reports counted in the window for the source category.
+func filterMatrixDeltas(deltas []*MatrixDeltaReport, window *matrixWindow, sourceCategory string) []*MatrixDeltaReport {
+	results := make([]*MatrixDeltaReport, 0)
+	for _, delta := range deltas {
+		if !window.includes(*delta.Report.CreatedAt) {
+			continue
+		}
+		manual := slices.Contains(constant.ManualSources, delta.Report.SourceName)
+		if (sourceCategory == constant.SourceCategoryManual && !manual) || (sourceCategory == constant.SourceCategoryAutomated && manual) {
+			continue
+		}
+		results = append(results, delta)
+	}
+	return results
+}
+
+// normalizeQuantityBuckets removes empty buckets, and counts all times in the bucket of 0 if nothing was dropped,
+// in the same way as calcDropMatrix does.
+func normalizeQuantityBuckets(quantityBuckets map[int]int, times int) map[int]int {
+	results := make(map[int]int)
+	for quantity, count := range quantityBuckets {
+		if quantity != 0 && count > 0 {
+			results[quantity] = count
+		}
+	}
+	if len(results) == 0 {
+		results[0] = times
+	}
+	return results
+}
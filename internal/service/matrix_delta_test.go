@@ -0,0 +1,420 @@
+package service
+
+import (
+	"maps"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"exusiai.dev/gommon/constant"
+
+	"exusiai.dev/backend-next/internal/model"
+	"exusiai.dev/backend-next/internal/model/types"
+)
+
+func newTestMatrixWindow(start time.Time, end time.Time, itemIds ...int) *matrixWindow {
+	return &matrixWindow{
+		TimeRange: &model.TimeRange{StartTime: &start, EndTime: &end},
+		ItemIDs:   itemIds,
+	}
+}
+
+func newTestMatrixDelta(createdAt time.Time, sourceName string, times int, patternId int, sign int, drops ...*types.Drop) *MatrixDeltaReport {
+	return &MatrixDeltaReport{
+		Report: &model.DropReport{
+			StageID:    1,
+			PatternID:  patternId,
+			Times:      times,
+			CreatedAt:  &createdAt,
+			Server:     "CN",
+			SourceName: sourceName,
+		},
+		Drops: drops,
+		Sign:  sign,
+	}
+}
+
+func TestNormalizeQuantityBuckets(t *testing.T) {
+	tests := []struct {
+		name            string
+		quantityBuckets map[int]int
+		times           int
+		want            map[int]int
+	}{
+		{"dropped buckets are kept", map[int]int{1: 2, 2: 1}, 5, map[int]int{1: 2, 2: 1}},
+		{"bucket of 0 is removed if anything was dropped", map[int]int{0: 3, 1: 2}, 5, map[int]int{1: 2}},
+		{"emptied buckets are removed", map[int]int{1: 0, 2: 1}, 3, map[int]int{2: 1}},
+		{"all times are counted in the bucket of 0 if nothing was dropped", map[int]int{1: 0}, 4, map[int]int{0: 4}},
+		{"nil buckets", nil, 2, map[int]int{0: 2}},
+		{"no times", map[int]int{}, 0, map[int]int{0: 0}},
+	}
+	for _, tt := range tests {
+		if got := normalizeQuantityBuckets(tt.quantityBuckets, tt.times); !maps.Equal(got, tt.want) {
+			t.Errorf("%s: normalizeQuantityBuckets(%v, %d) = %v, want %v", tt.name, tt.quantityBuckets, tt.times, got, tt.want)
+		}
+	}
+}
+
+func TestFilterMatrixDeltas(t *testing.T) {
+	defer func(manualSources []string) { constant.ManualSources = manualSources }(constant.ManualSources)
+	constant.ManualSources = []string{"penguin-stats.io"}
+
+	now := time.Now()
+	start := time.Date(2022, 5, 1, 4, 0, 0, 500_000_000, time.UTC)
+	end := start.Add(24 * time.Hour)
+	window := newTestMatrixWindow(start, end)
+	// the window of yesterday, which has already been closed
+	closedWindow := newTestMatrixWindow(now.Add(-48*time.Hour), now.Add(-24*time.Hour))
+
+	tests := []struct {
+		name           string
+		window         *matrixWindow
+		delta          *MatrixDeltaReport
+		sourceCategory string
+		want           bool
+	}{
+		{"within the window", window, newTestMatrixDelta(start.Add(time.Hour), "MeoAssistant", 1, 1, 1), constant.SourceCategoryAll, true},
+		{"at the start truncated to seconds", window, newTestMatrixDelta(start.Add(-400*time.Millisecond), "MeoAssistant", 1, 1, 1), constant.SourceCategoryAll, true},
+		{"before the window", window, newTestMatrixDelta(start.Add(-time.Second), "MeoAssistant", 1, 1, 1), constant.SourceCategoryAll, false},
+		{"at the end", window, newTestMatrixDelta(time.Unix(end.Unix(), 0), "MeoAssistant", 1, 1, 1), constant.SourceCategoryAll, false},
+		{"recalled from a closed window", closedWindow, newTestMatrixDelta(now.Add(-36*time.Hour), "MeoAssistant", 1, 1, -1), constant.SourceCategoryAll, true},
+		{"recalled after a closed window", closedWindow, newTestMatrixDelta(now.Add(-time.Hour), "MeoAssistant", 1, 1, -1), constant.SourceCategoryAll, false},
+		{"automated source in automated", window, newTestMatrixDelta(start.Add(time.Hour), "MeoAssistant", 1, 1, 1), constant.SourceCategoryAutomated, true},
+		{"automated source in manual", window, newTestMatrixDelta(start.Add(time.Hour), "MeoAssistant", 1, 1, 1), constant.SourceCategoryManual, false},
+		{"manual source in manual", window, newTestMatrixDelta(start.Add(time.Hour), "penguin-stats.io", 1, 1, 1), constant.SourceCategoryManual, true},
+		{"manual source in automated", window, newTestMatrixDelta(start.Add(time.Hour), "penguin-stats.io", 1, 1, 1), constant.SourceCategoryAutomated, false},
+		{"manual source in all", window, newTestMatrixDelta(start.Add(time.Hour), "penguin-stats.io", 1, 1, 1), constant.SourceCategoryAll, true},
+	}
+	for _, tt := range tests {
+		got := filterMatrixDeltas([]*MatrixDeltaReport{tt.delta}, tt.window, tt.sourceCategory)
+		if (len(got) == 1) != tt.want {
+			t.Errorf("%s: expected the delta to be included: %v, got %d deltas", tt.name, tt.want, len(got))
+		}
+	}
+}
+
+func TestDriftedMatrixStages(t *testing.T) {
+	start := time.Date(2022, 5, 1, 4, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+	element := func(elementId int, stageId int, itemId int, quantity int, times int) *model.DropMatrixElement {
+		return &model.DropMatrixElement{
+			ElementID:       elementId,
+			StageID:         stageId,
+			ItemID:          itemId,
+			StartTime:       &start,
+			EndTime:         &end,
+			Quantity:        quantity,
+			Times:           times,
+			QuantityBuckets: normalizeQuantityBuckets(map[int]int{1: quantity}, times),
+			SourceCategory:  constant.SourceCategoryAll,
+		}
+	}
+
+	tests := []struct {
+		name     string
+		expected []*model.DropMatrixElement
+		stored   []*model.DropMatrixElement
+		want     []int
+	}{
+		{
+			name:     "nothing drifted, regardless of element ids",
+			expected: []*model.DropMatrixElement{element(0, 1, 1, 2, 3), element(0, 2, 1, 0, 3)},
+			stored:   []*model.DropMatrixElement{element(10, 1, 1, 2, 3), element(11, 2, 1, 0, 3)},
+			want:     []int{},
+		},
+		{
+			name:     "differing quantity",
+			expected: []*model.DropMatrixElement{element(0, 1, 1, 2, 3), element(0, 2, 1, 1, 3)},
+			stored:   []*model.DropMatrixElement{element(10, 1, 1, 2, 3), element(11, 2, 1, 2, 3)},
+			want:     []int{2},
+		},
+		{
+			name:     "differing times",
+			expected: []*model.DropMatrixElement{element(0, 1, 1, 2, 3)},
+			stored:   []*model.DropMatrixElement{element(10, 1, 1, 2, 4)},
+			want:     []int{1},
+		},
+		{
+			name:     "missing element of a stage with zero drops",
+			expected: []*model.DropMatrixElement{element(0, 3, 1, 0, 2), element(0, 1, 1, 2, 3)},
+			stored:   []*model.DropMatrixElement{element(10, 1, 1, 2, 3)},
+			want:     []int{3},
+		},
+		{
+			name:     "superfluous elements, sorted by stage",
+			expected: []*model.DropMatrixElement{},
+			stored:   []*model.DropMatrixElement{element(10, 5, 1, 2, 3), element(11, 4, 1, 0, 3), element(12, 5, 2, 0, 3)},
+			want:     []int{4, 5},
+		},
+	}
+	for _, tt := range tests {
+		if got := driftedMatrixStages(tt.expected, tt.stored, dropMatrixElementKey, dropMatrixElementsEqual); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: driftedMatrixStages() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// testDropMatrixElement is the counters of a drop matrix element, which accumulateDropMatrixDeltas updates.
+type testDropMatrixElement struct {
+	quantity        int
+	times           int
+	quantityBuckets map[int]int
+}
+
+func TestAccumulateDropMatrixDeltas(t *testing.T) {
+	start := time.Date(2022, 5, 1, 4, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+	createdAt := start.Add(time.Hour)
+	// items 1 and 2 are dropped in the stage within the window
+	window := newTestMatrixWindow(start, end, 1, 2)
+
+	stored := func(elementId int, itemId int, quantity int, times int, quantityBuckets map[int]int) *model.DropMatrixElement {
+		return &model.DropMatrixElement{
+			ElementID:       elementId,
+			StageID:         1,
+			ItemID:          itemId,
+			Quantity:        quantity,
+			Times:           times,
+			QuantityBuckets: quantityBuckets,
+		}
+	}
+	drop := func(itemId int, quantity int) *types.Drop {
+		return &types.Drop{ItemID: itemId, Quantity: quantity}
+	}
+
+	tests := []struct {
+		name       string
+		elements   []*model.DropMatrixElement
+		deltas     []*MatrixDeltaReport
+		wantCreate map[int]testDropMatrixElement
+		wantUpdate map[int]testDropMatrixElement
+		wantDelete []int
+	}{
+		{
+			name:   "first report of a stage with zero drops",
+			deltas: []*MatrixDeltaReport{newTestMatrixDelta(createdAt, "MeoAssistant", 1, 1, 1)},
+			wantCreate: map[int]testDropMatrixElement{
+				1: {0, 1, map[int]int{0: 1}},
+				2: {0, 1, map[int]int{0: 1}},
+			},
+		},
+		{
+			name: "added report",
+			elements: []*model.DropMatrixElement{
+				stored(10, 1, 2, 2, map[int]int{1: 2}),
+				stored(11, 2, 0, 2, map[int]int{0: 2}),
+			},
+			// item 3 is not dropped in the window, but has been dropped anyway
+			deltas: []*MatrixDeltaReport{newTestMatrixDelta(createdAt, "MeoAssistant", 1, 1, 1, drop(1, 2), drop(3, 1))},
+			wantCreate: map[int]testDropMatrixElement{
+				3: {1, 3, map[int]int{1: 1}},
+			},
+			wantUpdate: map[int]testDropMatrixElement{
+				1: {4, 3, map[int]int{1: 2, 2: 1}},
+				2: {0, 3, map[int]int{0: 3}},
+			},
+		},
+		{
+			name: "added report of multiple times",
+			elements: []*model.DropMatrixElement{
+				stored(10, 1, 2, 2, map[int]int{1: 2}),
+				stored(11, 2, 0, 2, map[int]int{0: 2}),
+			},
+			deltas: []*MatrixDeltaReport{newTestMatrixDelta(createdAt, "MeoAssistant", 3, 1, 1, drop(2, 3))},
+			wantUpdate: map[int]testDropMatrixElement{
+				1: {2, 5, map[int]int{1: 2}},
+				2: {3, 5, map[int]int{3: 1}},
+			},
+		},
+		{
+			name: "removed report",
+			elements: []*model.DropMatrixElement{
+				stored(10, 1, 3, 3, map[int]int{1: 3}),
+				stored(11, 2, 0, 3, map[int]int{0: 3}),
+			},
+			deltas: []*MatrixDeltaReport{newTestMatrixDelta(createdAt, "MeoAssistant", 1, 1, -1, drop(1, 1))},
+			wantUpdate: map[int]testDropMatrixElement{
+				1: {2, 2, map[int]int{1: 2}},
+				2: {0, 2, map[int]int{0: 2}},
+			},
+		},
+		{
+			name: "removed report with zero drops",
+			elements: []*model.DropMatrixElement{
+				stored(10, 1, 1, 3, map[int]int{1: 1}),
+				stored(11, 2, 0, 3, map[int]int{0: 3}),
+			},
+			deltas: []*MatrixDeltaReport{newTestMatrixDelta(createdAt, "MeoAssistant", 1, 1, -1)},
+			wantUpdate: map[int]testDropMatrixElement{
+				1: {1, 2, map[int]int{1: 1}},
+				2: {0, 2, map[int]int{0: 2}},
+			},
+		},
+		{
+			name: "removed the only drop of an item not dropped in the window",
+			elements: []*model.DropMatrixElement{
+				stored(10, 1, 0, 2, map[int]int{0: 2}),
+				stored(11, 2, 0, 2, map[int]int{0: 2}),
+				stored(12, 3, 1, 2, map[int]int{1: 1}),
+			},
+			deltas: []*MatrixDeltaReport{newTestMatrixDelta(createdAt, "MeoAssistant", 1, 1, -1, drop(3, 1))},
+			wantUpdate: map[int]testDropMatrixElement{
+				1: {0, 1, map[int]int{0: 1}},
+				2: {0, 1, map[int]int{0: 1}},
+			},
+			wantDelete: []int{12},
+		},
+		{
+			name: "removed the last report",
+			elements: []*model.DropMatrixElement{
+				stored(10, 1, 1, 1, map[int]int{1: 1}),
+				stored(11, 2, 0, 1, map[int]int{0: 1}),
+			},
+			deltas:     []*MatrixDeltaReport{newTestMatrixDelta(createdAt, "MeoAssistant", 1, 1, -1, drop(1, 1))},
+			wantDelete: []int{10, 11},
+		},
+		{
+			name: "removed report of an item without element",
+			elements: []*model.DropMatrixElement{
+				stored(10, 1, 1, 2, map[int]int{1: 1}),
+				stored(11, 2, 0, 2, map[int]int{0: 2}),
+			},
+			deltas: []*MatrixDeltaReport{newTestMatrixDelta(createdAt, "MeoAssistant", 1, 1, -1, drop(5, 1))},
+			wantUpdate: map[int]testDropMatrixElement{
+				1: {1, 1, map[int]int{1: 1}},
+				2: {0, 1, map[int]int{0: 1}},
+			},
+		},
+		{
+			name:   "added and removed report cancel out",
+			deltas: []*MatrixDeltaReport{newTestMatrixDelta(createdAt, "MeoAssistant", 1, 1, 1, drop(1, 1)), newTestMatrixDelta(createdAt, "MeoAssistant", 1, 1, -1, drop(1, 1))},
+		},
+	}
+	for _, tt := range tests {
+		var elements map[int]*model.DropMatrixElement
+		if tt.elements != nil {
+			elements = make(map[int]*model.DropMatrixElement)
+			for _, element := range tt.elements {
+				elements[element.ItemID] = element
+			}
+		}
+		toCreate, toUpdate, toDelete := accumulateDropMatrixDeltas(elements, window, tt.deltas, func(itemId int) *model.DropMatrixElement {
+			return &model.DropMatrixElement{StageID: 1, ItemID: itemId, QuantityBuckets: make(map[int]int)}
+		})
+
+		assertDropMatrixElements(t, tt.name+": created", toCreate, tt.wantCreate)
+		assertDropMatrixElements(t, tt.name+": updated", toUpdate, tt.wantUpdate)
+		sort.Ints(toDelete)
+		if !slices.Equal(toDelete, tt.wantDelete) {
+			t.Errorf("%s: deleted %v, want %v", tt.name, toDelete, tt.wantDelete)
+		}
+	}
+}
+
+func assertDropMatrixElements(t *testing.T, name string, got []*model.DropMatrixElement, want map[int]testDropMatrixElement) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("%s: got %d elements, want %d", name, len(got), len(want))
+	}
+	for _, element := range got {
+		w, ok := want[element.ItemID]
+		if !ok {
+			t.Errorf("%s: unexpected element of item %d", name, element.ItemID)
+			continue
+		}
+		if element.Quantity != w.quantity || element.Times != w.times || !maps.Equal(element.QuantityBuckets, w.quantityBuckets) {
+			t.Errorf("%s: element of item %d is %d/%d %v, want %d/%d %v", name, element.ItemID,
+				element.Quantity, element.Times, element.QuantityBuckets, w.quantity, w.times, w.quantityBuckets)
+		}
+	}
+}
+
+func TestAccumulatePatternMatrixDeltas(t *testing.T) {
+	createdAt := time.Date(2022, 5, 1, 5, 0, 0, 0, time.UTC)
+	stored := func(elementId int, patternId int, quantity int, times int) *model.PatternMatrixElement {
+		return &model.PatternMatrixElement{ElementID: elementId, StageID: 1, PatternID: patternId, Quantity: quantity, Times: times}
+	}
+	// quantity and times of the elements by pattern id
+	type counters [2]int
+
+	tests := []struct {
+		name       string
+		elements   []*model.PatternMatrixElement
+		deltas     []*MatrixDeltaReport
+		wantCreate map[int]counters
+		wantUpdate map[int]counters
+		wantDelete []int
+	}{
+		{
+			name:       "first report",
+			deltas:     []*MatrixDeltaReport{newTestMatrixDelta(createdAt, "MeoAssistant", 1, 1, 1)},
+			wantCreate: map[int]counters{1: {1, 1}},
+		},
+		{
+			name:       "added report of a new pattern",
+			elements:   []*model.PatternMatrixElement{stored(10, 1, 2, 2)},
+			deltas:     []*MatrixDeltaReport{newTestMatrixDelta(createdAt, "MeoAssistant", 1, 2, 1)},
+			wantCreate: map[int]counters{2: {1, 3}},
+			wantUpdate: map[int]counters{1: {2, 3}},
+		},
+		{
+			name:       "added report of a stored pattern",
+			elements:   []*model.PatternMatrixElement{stored(10, 1, 2, 3), stored(11, 2, 1, 3)},
+			deltas:     []*MatrixDeltaReport{newTestMatrixDelta(createdAt, "MeoAssistant", 1, 2, 1)},
+			wantUpdate: map[int]counters{1: {2, 4}, 2: {2, 4}},
+		},
+		{
+			name:       "removed the only report of a pattern",
+			elements:   []*model.PatternMatrixElement{stored(10, 1, 2, 3), stored(11, 2, 1, 3)},
+			deltas:     []*MatrixDeltaReport{newTestMatrixDelta(createdAt, "MeoAssistant", 1, 2, -1)},
+			wantUpdate: map[int]counters{1: {2, 2}},
+			wantDelete: []int{11},
+		},
+		{
+			name:       "removed report of a pattern without element",
+			elements:   []*model.PatternMatrixElement{stored(10, 1, 2, 2)},
+			deltas:     []*MatrixDeltaReport{newTestMatrixDelta(createdAt, "MeoAssistant", 1, 3, -1)},
+			wantUpdate: map[int]counters{1: {2, 2}},
+		},
+		{
+			name:       "removed the last report",
+			elements:   []*model.PatternMatrixElement{stored(10, 1, 1, 1)},
+			deltas:     []*MatrixDeltaReport{newTestMatrixDelta(createdAt, "MeoAssistant", 1, 1, -1)},
+			wantDelete: []int{10},
+		},
+	}
+	for _, tt := range tests {
+		var elements map[int]*model.PatternMatrixElement
+		if tt.elements != nil {
+			elements = make(map[int]*model.PatternMatrixElement)
+			for _, element := range tt.elements {
+				elements[element.PatternID] = element
+			}
+		}
+		toCreate, toUpdate, toDelete := accumulatePatternMatrixDeltas(elements, tt.deltas, func(patternId int) *model.PatternMatrixElement {
+			return &model.PatternMatrixElement{StageID: 1, PatternID: patternId}
+		})
+
+		for _, c := range []struct {
+			name string
+			got  []*model.PatternMatrixElement
+			want map[int]counters
+		}{
+			{"created", toCreate, tt.wantCreate},
+			{"updated", toUpdate, tt.wantUpdate},
+		} {
+			got := make(map[int]counters, len(c.got))
+			for _, element := range c.got {
+				got[element.PatternID] = counters{element.Quantity, element.Times}
+			}
+			if len(got) != len(c.want) || !maps.Equal(got, c.want) {
+				t.Errorf("%s: %s %v, want %v", tt.name, c.name, got, c.want)
+			}
+		}
+		sort.Ints(toDelete)
+		if !slices.Equal(toDelete, tt.wantDelete) {
+			t.Errorf("%s: deleted %v, want %v", tt.name, toDelete, tt.wantDelete)
+		}
+	}
+}
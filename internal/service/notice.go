@@ -2,13 +2,20 @@ package service
 
 import (
 	"context"
+	"sort"
 	"time"
 
 	"exusiai.dev/backend-next/internal/model"
 	"exusiai.dev/backend-next/internal/model/cache"
+	modelv3 "exusiai.dev/backend-next/internal/model/v3"
+	"exusiai.dev/backend-next/internal/pkg/pgerr"
 	"exusiai.dev/backend-next/internal/repo"
 )
 
+// NoticeLanguages are the languages notices can be written in, in the order they are fallen back to when a
+// notice is not available in the requested language.
+var NoticeLanguages = []string{"en", "zh", "ja", "ko"}
+
 type Notice struct {
 	NoticeRepo *repo.Notice
 }
@@ -35,3 +42,104 @@ func (s *Notice) GetNotices(ctx context.Context) ([]*model.Notice, error) {
 	cache.LastModifiedTime.Set("[notices]", time.Now(), 0)
 	return notices, err
 }
+
+// Cache: (singular) scheduledNotices, 1 min
+func (s *Notice) GetScheduledNotices(ctx context.Context) ([]*model.ScheduledNotice, error) {
+	var notices []*model.ScheduledNotice
+	err := cache.ScheduledNotices.MutexGetSet(&notices, func() ([]*model.ScheduledNotice, error) {
+		return s.NoticeRepo.GetScheduledNotices(ctx)
+	}, time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	return notices, nil
+}
+
+// GetActiveNotices returns the notices shown now to the client, in the language if available, most severe first.
+func (s *Notice) GetActiveNotices(ctx context.Context, target *model.NoticeTarget, language string) ([]*modelv3.Notice, error) {
+	notices, err := s.GetScheduledNotices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	results := make([]*modelv3.Notice, 0)
+	for _, notice := range notices {
+		if !notice.ShownAt(now) || !notice.Targets(target) {
+			continue
+		}
+		results = append(results, renderNotice(notice, language))
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Severity > results[j].Severity
+	})
+	return results, nil
+}
+
+func (s *Notice) GetScheduledNotice(ctx context.Context, noticeId int) (*model.ScheduledNotice, error) {
+	return s.NoticeRepo.GetScheduledNotice(ctx, noticeId)
+}
+
+// SaveScheduledNotice creates the notice if it has no ID yet, or updates it otherwise.
+func (s *Notice) SaveScheduledNotice(ctx context.Context, notice *model.ScheduledNotice) error {
+	if notice.StartTime != nil && notice.EndTime != nil && !notice.EndTime.After(*notice.StartTime) {
+		return pgerr.ErrInvalidReq.Msg("endTime must be after startTime")
+	}
+	for language := range notice.Title {
+		if _, ok := notice.Content[language]; !ok {
+			return pgerr.ErrInvalidReq.Msg("title of language %q has no content", language)
+		}
+	}
+
+	// empty targets are stored as empty lists rather than nulls
+	for _, targets := range []*[]string{&notice.Servers, &notice.Variants, &notice.Sources} {
+		if *targets == nil {
+			*targets = make([]string, 0)
+		}
+	}
+
+	now := time.Now()
+	if notice.CreatedAt == nil {
+		notice.CreatedAt = &now
+	}
+	notice.UpdatedAt = &now
+
+	if err := s.NoticeRepo.SaveScheduledNotice(ctx, notice); err != nil {
+		return err
+	}
+	return cache.ScheduledNotices.Delete()
+}
+
+func (s *Notice) DeleteScheduledNotice(ctx context.Context, noticeId int) error {
+	if err := s.NoticeRepo.DeleteScheduledNotice(ctx, noticeId); err != nil {
+		return err
+	}
+	return cache.ScheduledNotices.Delete()
+}
+
+// renderNotice renders the notice in the language, or in the first of NoticeLanguages it is available in.
+func renderNotice(notice *model.ScheduledNotice, language string) *modelv3.Notice {
+	if _, ok := notice.Content[language]; !ok {
+		for _, fallback := range NoticeLanguages {
+			if _, ok := notice.Content[fallback]; ok {
+				language = fallback
+				break
+			}
+		}
+	}
+
+	rendered := &modelv3.Notice{
+		ID:       notice.NoticeID,
+		Severity: notice.Severity,
+		Language: language,
+		Title:    notice.Title[language],
+		Content:  notice.Content[language],
+	}
+	if notice.StartTime != nil {
+		rendered.StartTime = notice.StartTime.UnixMilli()
+	}
+	if notice.EndTime != nil {
+		rendered.EndTime = notice.EndTime.UnixMilli()
+	}
+	return rendered
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"slices"
 	"strconv"
 	"time"
 
@@ -60,7 +61,7 @@ func (s *PatternMatrix) GetShimPatternMatrix(ctx context.Context, server string,
 		var patternMatrixQueryResult *model.PatternMatrixQueryResult
 		var err error
 		if accountId.Valid {
-			patternMatrixQueryResult, err = s.getLatestPatternMatrixResults(ctx, server, accountId, sourceCategory)
+			patternMatrixQueryResult, err = s.getLatestPatternMatrixResults(ctx, server, accountId, sourceCategory, false)
 		} else {
 			patternMatrixQueryResult, err = s.calcGlobalPatternMatrix(ctx, server, sourceCategory)
 		}
@@ -96,6 +97,31 @@ func (s *PatternMatrix) GetShimPatternMatrix(ctx context.Context, server string,
 	}
 }
 
+// GetShimWeightedPatternMatrix is the global GetShimPatternMatrix with each report weighted by the weight of its
+// account. Since weighted matrices are not precomputed, they are calculated from drop reports on request and
+// cached for WorkerInterval.
+//
+// Cache: weightedPatternMatrix#server|sourceCategory:{server}|{sourceCategory}, WorkerInterval
+func (s *PatternMatrix) GetShimWeightedPatternMatrix(ctx context.Context, server string, sourceCategory string, showAllPatterns bool,
+) (*modelv2.PatternMatrixQueryResult, error) {
+	valueFunc := func() (*model.PatternMatrixQueryResult, error) {
+		return s.getLatestPatternMatrixResults(ctx, server, null.NewInt(0, false), sourceCategory, true)
+	}
+
+	var patternMatrixQueryResult model.PatternMatrixQueryResult
+	if _, err := cache.WeightedPatternMatrix.MutexGetSet(server+constant.CacheSep+sourceCategory, &patternMatrixQueryResult, valueFunc, s.Config.WorkerInterval); err != nil {
+		return nil, err
+	}
+	if !showAllPatterns {
+		newPatternMatrix, err := s.interceptPatternMatrixResults(patternMatrixQueryResult.PatternMatrix, s.Config.PatternMatrixLimit)
+		if err != nil {
+			return nil, err
+		}
+		patternMatrixQueryResult.PatternMatrix = newPatternMatrix
+	}
+	return s.applyShimForPatternMatrixQuery(ctx, &patternMatrixQueryResult)
+}
+
 // =========== Global ===========
 
 // Calc today's pattern matrix elements and save to DB
@@ -103,7 +129,7 @@ func (s *PatternMatrix) GetShimPatternMatrix(ctx context.Context, server string,
 func (s *PatternMatrix) RunCalcPatternMatrixJob(ctx context.Context, server string) error {
 	date := time.Now()
 	endTime := time.Now()
-	patternMatrixElements, err := s.calcPatternMatrixByGivenDate(ctx, server, &date, &endTime, s.Config.MatrixWorkerSourceCategories)
+	patternMatrixElements, err := s.calcPatternMatrixByGivenDate(ctx, server, &date, &endTime, s.Config.MatrixWorkerSourceCategories, nil)
 	if err != nil {
 		return err
 	}
@@ -121,7 +147,7 @@ func (s *PatternMatrix) RunCalcPatternMatrixJob(ctx context.Context, server stri
 	// If this is the first time we run the job for this server at this day, we need to update the pattern matrix for the previous day.
 	if !exists {
 		yesterday := date.Add(time.Hour * -24)
-		patternMatrixElementsForYesterday, err := s.calcPatternMatrixByGivenDate(ctx, server, &yesterday, nil, s.Config.MatrixWorkerSourceCategories)
+		patternMatrixElementsForYesterday, err := s.calcPatternMatrixByGivenDate(ctx, server, &yesterday, nil, s.Config.MatrixWorkerSourceCategories, nil)
 		if err != nil {
 			return err
 		}
@@ -131,15 +157,16 @@ func (s *PatternMatrix) RunCalcPatternMatrixJob(ctx context.Context, server stri
 		}
 	}
 
-	for _, sourceCategory := range s.Config.MatrixWorkerSourceCategories {
+	return purgeGlobalPatternMatrixCaches(server, s.Config.MatrixWorkerSourceCategories)
+}
+
+func purgeGlobalPatternMatrixCaches(server string, sourceCategories []string) error {
+	for _, sourceCategory := range sourceCategories {
 		for _, showAllPatterns := range []bool{true, false} {
 			key := server + constant.CacheSep + sourceCategory + constant.CacheSep + strconv.FormatBool(showAllPatterns)
 			if err := cache.ShimGlobalPatternMatrix.Delete(key); err != nil {
 				return err
 			}
-			if err := cache.ShimGlobalPatternMatrix.Delete(key); err != nil {
-				return err
-			}
 		}
 	}
 	return nil
@@ -148,7 +175,7 @@ func (s *PatternMatrix) RunCalcPatternMatrixJob(ctx context.Context, server stri
 // Update pattern matrix elements for a given date (entire day)
 // Called by admin api
 func (s *PatternMatrix) UpdatePatternMatrixByGivenDate(ctx context.Context, server string, date *time.Time) error {
-	patternMatrixElements, err := s.calcPatternMatrixByGivenDate(ctx, server, date, nil, s.Config.MatrixWorkerSourceCategories)
+	patternMatrixElements, err := s.calcPatternMatrixByGivenDate(ctx, server, date, nil, s.Config.MatrixWorkerSourceCategories, nil)
 	if err != nil {
 		return err
 	}
@@ -160,8 +187,10 @@ func (s *PatternMatrix) UpdatePatternMatrixByGivenDate(ctx context.Context, serv
 	return nil
 }
 
+// calcPatternMatrixByGivenDate calculates the pattern matrix elements of the day of date, until endTime if it is not
+// nil, and only of stageIds if it is not empty.
 func (s *PatternMatrix) calcPatternMatrixByGivenDate(
-	ctx context.Context, server string, date *time.Time, endTime *time.Time, sourceCategories []string,
+	ctx context.Context, server string, date *time.Time, endTime *time.Time, sourceCategories []string, stageIds []int,
 ) ([]*model.PatternMatrixElement, error) {
 	start := time.UnixMilli(util.GetDayStartTime(date, server))
 	startNextDay := start.Add(time.Hour * 24)
@@ -185,7 +214,7 @@ func (s *PatternMatrix) calcPatternMatrixByGivenDate(
 	}
 	stageIdsMap := s.getStageIdsMapByTimeRange(allTimeRanges)
 	elements := make([]*model.PatternMatrixElement, 0)
-	for rangeId, stageIds := range stageIdsMap {
+	for rangeId, rangeStageIds := range stageIdsMap {
 		timeRange := timeRangesMap[rangeId]
 		intersection := util.GetIntersection(timeRange, timeRangeGiven)
 		if intersection == nil {
@@ -193,15 +222,15 @@ func (s *PatternMatrix) calcPatternMatrixByGivenDate(
 		}
 
 		// exclude some stages (gachabox, recruit) before calc
-		linq.From(stageIds).WhereT(func(stageId int) bool {
+		linq.From(rangeStageIds).WhereT(func(stageId int) bool {
 			_, ok := excludeStageIdsSet[stageId]
-			return !ok
-		}).ToSlice(&stageIds)
-		if len(stageIds) == 0 {
+			return !ok && (len(stageIds) == 0 || slices.Contains(stageIds, stageId))
+		}).ToSlice(&rangeStageIds)
+		if len(rangeStageIds) == 0 {
 			continue
 		}
 		for _, sourceCategory := range sourceCategories {
-			currentBatch, err := s.calcPatternMatrixForTimeRanges(ctx, server, []*model.TimeRange{intersection}, stageIds, null.NewInt(0, false), sourceCategory)
+			currentBatch, err := s.calcPatternMatrixForTimeRanges(ctx, server, []*model.TimeRange{intersection}, rangeStageIds, null.NewInt(0, false), sourceCategory, false)
 			if err != nil {
 				return nil, err
 			}
@@ -258,15 +287,15 @@ func (s *PatternMatrix) calcGlobalPatternMatrix(ctx context.Context, server stri
 
 // =========== Personal ===========
 
-func (s *PatternMatrix) getLatestPatternMatrixResults(ctx context.Context, server string, accountId null.Int, sourceCategory string) (*model.PatternMatrixQueryResult, error) {
-	patternMatrixElements, err := s.getLatestPatternMatrixElements(ctx, server, accountId, sourceCategory)
+func (s *PatternMatrix) getLatestPatternMatrixResults(ctx context.Context, server string, accountId null.Int, sourceCategory string, weighted bool) (*model.PatternMatrixQueryResult, error) {
+	patternMatrixElements, err := s.getLatestPatternMatrixElements(ctx, server, accountId, sourceCategory, weighted)
 	if err != nil {
 		return nil, err
 	}
 	return s.convertPatternMatrixElementsToDropPatternQueryResult(ctx, server, patternMatrixElements)
 }
 
-func (s *PatternMatrix) getLatestPatternMatrixElements(ctx context.Context, server string, accountId null.Int, sourceCategory string) ([]*model.PatternMatrixElement, error) {
+func (s *PatternMatrix) getLatestPatternMatrixElements(ctx context.Context, server string, accountId null.Int, sourceCategory string, weighted bool) ([]*model.PatternMatrixElement, error) {
 	timeRangesMap, err := s.TimeRangeService.GetTimeRangesMap(ctx, server)
 	if err != nil {
 		return nil, err
@@ -293,7 +322,7 @@ func (s *PatternMatrix) getLatestPatternMatrixElements(ctx context.Context, serv
 		}
 
 		timeRanges := []*model.TimeRange{timeRangesMap[rangeId]}
-		currentBatch, err := s.calcPatternMatrixForTimeRanges(ctx, server, timeRanges, stageIds, accountId, sourceCategory)
+		currentBatch, err := s.calcPatternMatrixForTimeRanges(ctx, server, timeRanges, stageIds, accountId, sourceCategory, weighted)
 		if err != nil {
 			return nil, err
 		}
@@ -328,7 +357,7 @@ func (s *PatternMatrix) convertPatternMatrixElementsToDropPatternQueryResult(ctx
 
 // Called by both global and personal
 func (s *PatternMatrix) calcPatternMatrixForTimeRanges(
-	ctx context.Context, server string, timeRanges []*model.TimeRange, stageIdFilter []int, accountId null.Int, sourceCategory string,
+	ctx context.Context, server string, timeRanges []*model.TimeRange, stageIdFilter []int, accountId null.Int, sourceCategory string, weighted bool,
 ) ([]*model.PatternMatrixElement, error) {
 	results := make([]*model.PatternMatrixElement, 0)
 
@@ -351,6 +380,7 @@ func (s *PatternMatrix) calcPatternMatrixForTimeRanges(
 			StageItemFilter:    &stageItemFilter,
 			SourceCategory:     sourceCategory,
 			ExcludeNonOneTimes: true,
+			Weighted:           weighted,
 		}
 		quantityResults, err := s.DropReportService.CalcTotalQuantityForPatternMatrix(ctx, queryCtx)
 		if err != nil {
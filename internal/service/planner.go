@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"exusiai.dev/gommon/constant"
+	"github.com/goccy/go-json"
+	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v3"
+
+	"exusiai.dev/backend-next/internal/model"
+	"exusiai.dev/backend-next/internal/model/types"
+	modelv3 "exusiai.dev/backend-next/internal/model/v3"
+	"exusiai.dev/backend-next/internal/pkg/pgerr"
+	"exusiai.dev/backend-next/internal/util"
+)
+
+const (
+	PlannerDefaultMinTimes = 100
+
+	// PlannerByproductRate is the probability of producing a byproduct when crafting in the workshop,
+	// without any operator skill bonus.
+	PlannerByproductRate = 0.18
+
+	// PlannerGoldSanityValue is the sanity worth of one gold (LMD), as farmed from CE-6 which yields 10000 gold
+	// for 36 sanity. It prices the gold cost of crafting in sanity.
+	PlannerGoldSanityValue = 36.0 / 10000
+)
+
+// Planner plans the sanity-minimizing way of farming the needed items, based on the global drop matrix
+// and the crafting formula.
+type Planner struct {
+	DropMatrixService *DropMatrix
+	FormulaService    *Formula
+	StageService      *Stage
+	ItemService       *Item
+}
+
+func NewPlanner(dropMatrixService *DropMatrix, formulaService *Formula, stageService *Stage, itemService *Item) *Planner {
+	return &Planner{
+		DropMatrixService: dropMatrixService,
+		FormulaService:    formulaService,
+		StageService:      stageService,
+		ItemService:       itemService,
+	}
+}
+
+type plannerStage struct {
+	arkStageId string
+	sanity     float64
+}
+
+func (s *Planner) Plan(ctx context.Context, req *types.PlannerRequest) (*modelv3.PlannerResult, error) {
+	itemsMap, err := s.ItemService.GetItemsMapByArkId(ctx)
+	if err != nil {
+		return nil, err
+	}
+	demands := make(map[string]float64)
+	for arkItemId, needed := range req.Needed {
+		if _, ok := itemsMap[arkItemId]; !ok {
+			return nil, pgerr.ErrInvalidReq.Msg("unknown item in needed: %s", arkItemId)
+		}
+		demands[arkItemId] += float64(needed)
+	}
+	for arkItemId, owned := range req.Owned {
+		if _, ok := itemsMap[arkItemId]; !ok {
+			return nil, pgerr.ErrInvalidReq.Msg("unknown item in owned: %s", arkItemId)
+		}
+		demands[arkItemId] -= float64(owned)
+	}
+
+	stageActivities, stages, err := s.stageActivities(ctx, req.Server, constant.SourceCategoryAll, req.MinTimes, req.ExcludeStages)
+	if err != nil {
+		return nil, err
+	}
+	activities := stageActivities
+
+	var formula []*model.FormulaEntry
+	if !req.ExcludeCrafting {
+		formula, err = s.getFormulaEntries(ctx)
+		if err != nil {
+			return nil, err
+		}
+		activities = append(activities, craftActivities(formula, req.Byproduct)...)
+	}
+
+	amounts, totalSanity, err := util.SolvePlanner(activities, demands)
+	if err != nil {
+		if errors.Is(err, util.ErrPlannerInfeasible) {
+			return nil, pgerr.ErrInvalidReq.Msg("needed items could not be obtained from the currently open stages of server %s", req.Server)
+		}
+		return nil, errors.Wrap(err, "failed to solve planner")
+	}
+
+	result := &modelv3.PlannerResult{
+		Server:      req.Server,
+		TotalSanity: totalSanity,
+		Stages:      make([]*modelv3.PlannerStage, 0),
+		Syntheses:   make([]*modelv3.PlannerSynthesis, 0),
+	}
+	for i, amount := range amounts {
+		if amount <= 0 {
+			continue
+		}
+		if i < len(stages) {
+			result.Stages = append(result.Stages, &modelv3.PlannerStage{
+				StageID: stages[i].arkStageId,
+				Runs:    amount,
+				Sanity:  amount * stages[i].sanity,
+			})
+		} else {
+			entry := formula[i-len(stages)]
+			result.Syntheses = append(result.Syntheses, &modelv3.PlannerSynthesis{
+				ItemID: entry.ID,
+				Count:  amount,
+				Gold:   amount * float64(entry.GoldCost),
+			})
+			result.TotalGold += amount * float64(entry.GoldCost)
+		}
+	}
+	sort.SliceStable(result.Stages, func(i, j int) bool {
+		return result.Stages[i].Sanity > result.Stages[j].Sanity
+	})
+
+	return result, nil
+}
+
+// stageActivities converts the global drop matrix of the server into one activity per currently open stage.
+// The returned stages are in the same order as the activities.
+func (s *Planner) stageActivities(ctx context.Context, server string, sourceCategory string, minTimes int, excludeStages []string) ([]*util.PlannerActivity, []*plannerStage, error) {
+	matrix, err := s.DropMatrixService.GetShimDropMatrix(ctx, server, false, "", "", null.NewInt(0, false), sourceCategory)
+	if err != nil {
+		return nil, nil, err
+	}
+	stagesMap, err := s.StageService.GetStagesMapByArkId(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if minTimes <= 0 {
+		minTimes = PlannerDefaultMinTimes
+	}
+	excluded := make(map[string]struct{}, len(excludeStages))
+	for _, arkStageId := range excludeStages {
+		excluded[arkStageId] = struct{}{}
+	}
+
+	now := time.Now().UnixMilli()
+	yieldsByStage := make(map[string]map[string]float64)
+	for _, el := range matrix.Matrix {
+		if el.Times < minTimes || el.Times == 0 {
+			continue
+		}
+		if el.EndTime.Valid && el.EndTime.Int64 <= now {
+			continue
+		}
+		if _, ok := excluded[el.StageID]; ok {
+			continue
+		}
+		yields, ok := yieldsByStage[el.StageID]
+		if !ok {
+			yields = make(map[string]float64)
+			yieldsByStage[el.StageID] = yields
+		}
+		yields[el.ItemID] = float64(el.Quantity) / float64(el.Times)
+	}
+
+	arkStageIds := make([]string, 0, len(yieldsByStage))
+	for arkStageId := range yieldsByStage {
+		arkStageIds = append(arkStageIds, arkStageId)
+	}
+	sort.Strings(arkStageIds)
+
+	activities := make([]*util.PlannerActivity, 0, len(arkStageIds))
+	stages := make([]*plannerStage, 0, len(arkStageIds))
+	for _, arkStageId := range arkStageIds {
+		stage, ok := stagesMap[arkStageId]
+		// stages with special drop mechanisms (e.g. gacha boxes) could not be farmed by their expectation
+		if !ok || !stage.Sanity.Valid || stage.Sanity.Int64 <= 0 || stage.ExtraProcessType.Valid {
+			continue
+		}
+		activities = append(activities, &util.PlannerActivity{
+			Cost:   float64(stage.Sanity.Int64),
+			Yields: yieldsByStage[arkStageId],
+		})
+		stages = append(stages, &plannerStage{
+			arkStageId: arkStageId,
+			sanity:     float64(stage.Sanity.Int64),
+		})
+	}
+	return activities, stages, nil
+}
+
+func (s *Planner) getFormulaEntries(ctx context.Context) ([]*model.FormulaEntry, error) {
+	raw, err := s.FormulaService.GetFormula(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var formula []*model.FormulaEntry
+	if err := json.Unmarshal(raw, &formula); err != nil {
+		return nil, errors.Wrap(err, "failed to parse formula")
+	}
+	return formula, nil
+}
+
+// craftActivities converts every formula entry into an activity, which costs the sanity worth of the gold cost
+// of the formula.
+func craftActivities(formula []*model.FormulaEntry, byproduct bool) []*util.PlannerActivity {
+	activities := make([]*util.PlannerActivity, 0, len(formula))
+	for _, entry := range formula {
+		yields := map[string]float64{
+			entry.ID: 1,
+		}
+		for _, cost := range entry.Costs {
+			yields[cost.ID] -= float64(cost.Count)
+		}
+		if byproduct && entry.TotalWeight > 0 {
+			for _, outcome := range entry.ExtraOutcome {
+				yields[outcome.ID] += PlannerByproductRate * outcome.Weight / entry.TotalWeight * float64(outcome.Count)
+			}
+		}
+		activities = append(activities, &util.PlannerActivity{
+			Cost:   float64(entry.GoldCost) * PlannerGoldSanityValue,
+			Yields: yields,
+		})
+	}
+	return activities
+}
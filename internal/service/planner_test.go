@@ -0,0 +1,41 @@
+package service
+
+import (
+	"math"
+	"testing"
+
+	"exusiai.dev/backend-next/internal/model"
+)
+
+func TestCraftActivities(t *testing.T) {
+	formula := []*model.FormulaEntry{
+		{
+			ID:       "30012",
+			GoldCost: 100,
+			Costs:    []*model.FormulaCost{{ID: "30011", Count: 3}},
+			ExtraOutcome: []*model.FormulaExtraOutcome{
+				{ID: "30022", Count: 1, Weight: 1},
+			},
+			TotalWeight: 2,
+		},
+	}
+
+	activities := craftActivities(formula, false)
+	if len(activities) != 1 {
+		t.Fatalf("Expected one activity per formula entry, got %d", len(activities))
+	}
+	if want := 100 * PlannerGoldSanityValue; math.Abs(activities[0].Cost-want) > 1e-9 {
+		t.Errorf("Expected the gold cost to be priced at %f sanity, got %f", want, activities[0].Cost)
+	}
+	if activities[0].Yields["30012"] != 1 || activities[0].Yields["30011"] != -3 {
+		t.Errorf("Expected to yield the crafted item and consume its costs, got %v", activities[0].Yields)
+	}
+	if _, ok := activities[0].Yields["30022"]; ok {
+		t.Errorf("Expected no byproduct yields without byproduct, got %v", activities[0].Yields)
+	}
+
+	activities = craftActivities(formula, true)
+	if want := PlannerByproductRate / 2; math.Abs(activities[0].Yields["30022"]-want) > 1e-9 {
+		t.Errorf("Expected byproduct yield %f, got %f", want, activities[0].Yields["30022"])
+	}
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"exusiai.dev/gommon/constant"
+	"github.com/antonmedv/expr"
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog/log"
+
+	"exusiai.dev/backend-next/internal/model"
+	"exusiai.dev/backend-next/internal/model/types"
+	"exusiai.dev/backend-next/internal/pkg/pgerr"
+	"exusiai.dev/backend-next/internal/repo"
+	"exusiai.dev/backend-next/internal/util/reportverifs"
+)
+
+// DefaultRejectRuleDryRunHours is the amount of hours of most recent reports a reject rule is
+// evaluated against before it is saved, when not specified otherwise.
+const DefaultRejectRuleDryRunHours = 24
+
+// RejectRuleDryRunMaxReports is the maximum amount of reports a reject rule is evaluated against in a dry run.
+// When more reports have been created within the dry run hours, only the most recent ones are evaluated.
+const RejectRuleDryRunMaxReports = 20000
+
+type RejectRule struct {
+	NatsConn       *nats.Conn
+	RejectRuleRepo *repo.RejectRule
+	AdminService   *Admin
+}
+
+func NewRejectRule(natsConn *nats.Conn, rejectRuleRepo *repo.RejectRule, adminService *Admin) *RejectRule {
+	return &RejectRule{
+		NatsConn:       natsConn,
+		RejectRuleRepo: rejectRuleRepo,
+		AdminService:   adminService,
+	}
+}
+
+type RejectRuleDryRunResult struct {
+	From           time.Time `json:"from"`
+	To             time.Time `json:"to"`
+	EvaluatedCount int       `json:"evaluatedCount"`
+	// Sampled indicates that more reports were created within the range than RejectRuleDryRunMaxReports, and that
+	// only the most recent ones were evaluated.
+	Sampled         bool  `json:"sampled"`
+	RejectedCount   int   `json:"rejectedCount"`
+	SampledRejected []int `json:"sampledRejected"`
+}
+
+func (s *RejectRule) GetAllRejectRules(ctx context.Context) ([]*model.RejectRule, error) {
+	return s.RejectRuleRepo.GetAllRejectRules(ctx)
+}
+
+func (s *RejectRule) GetRejectRule(ctx context.Context, ruleId int) (*model.RejectRule, error) {
+	return s.RejectRuleRepo.GetRejectRule(ctx, ruleId)
+}
+
+func (s *RejectRule) GetRejectRuleRevisions(ctx context.Context, ruleId int) ([]*model.RejectRuleRevision, error) {
+	if _, err := s.RejectRuleRepo.GetRejectRule(ctx, ruleId); err != nil {
+		return nil, err
+	}
+	return s.RejectRuleRepo.GetRejectRuleRevisions(ctx, ruleId)
+}
+
+// DryRunRejectRuleExpr evaluates the expression against reports created within the last `hours` hours, up to
+// RejectRuleDryRunMaxReports of the most recent ones, and counts the reports which would have been rejected by it.
+func (s *RejectRule) DryRunRejectRuleExpr(ctx context.Context, exprStr string, hours int) (*RejectRuleDryRunResult, error) {
+	program, err := reportverifs.CompileRejectRuleExpr(exprStr)
+	if err != nil {
+		return nil, pgerr.ErrInvalidReq.Msg("invalid reject rule expression: %s", err)
+	}
+
+	if hours <= 0 {
+		hours = DefaultRejectRuleDryRunHours
+	}
+
+	var req types.RejectRulesReevaluationPreviewRequest
+	req.ReevaluateRange.To = time.Now()
+	req.ReevaluateRange.From = req.ReevaluateRange.To.Add(-time.Duration(hours) * time.Hour)
+
+	// fetch one more report than evaluated to tell whether the range has been sampled
+	evalContexts, err := s.AdminService.GetRejectRulesReportContext(ctx, req, RejectRuleDryRunMaxReports+1)
+	if err != nil {
+		return nil, err
+	}
+	sampled := len(evalContexts) > RejectRuleDryRunMaxReports
+	if sampled {
+		evalContexts = evalContexts[:RejectRuleDryRunMaxReports]
+	}
+
+	result := &RejectRuleDryRunResult{
+		From:            req.ReevaluateRange.From,
+		To:              req.ReevaluateRange.To,
+		EvaluatedCount:  len(evalContexts),
+		Sampled:         sampled,
+		SampledRejected: make([]int, 0, 10),
+	}
+	for _, evalContext := range evalContexts {
+		out, err := expr.Run(program, *evalContext.EvaluateContext)
+		if err != nil {
+			return nil, pgerr.ErrInvalidReq.Msg("failed to evaluate reject rule expression against report %d: %s", evalContext.OriginalReport.ReportID, err)
+		}
+		if shouldReject, _ := out.(bool); shouldReject {
+			result.RejectedCount++
+			if len(result.SampledRejected) < cap(result.SampledRejected) {
+				result.SampledRejected = append(result.SampledRejected, evalContext.OriginalReport.ReportID)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// ValidateRejectRuleExpr ensures the expression compiles against the ReportContext environment
+// and evaluates to a boolean, so that broken rules are never persisted.
+func (s *RejectRule) ValidateRejectRuleExpr(exprStr string) error {
+	if _, err := reportverifs.CompileRejectRuleExpr(exprStr); err != nil {
+		return pgerr.ErrInvalidReq.Msg("invalid reject rule expression: %s", err)
+	}
+	return nil
+}
+
+func (s *RejectRule) validateRejectRule(rejectRule *model.RejectRule) error {
+	if err := s.ValidateRejectRuleExpr(rejectRule.Expr); err != nil {
+		return err
+	}
+	if rejectRule.WithReliability < constant.ViolationReliabilityRejectRuleRangeLeast ||
+		rejectRule.WithReliability >= constant.ViolationReliabilityRejectRuleRangeMost {
+		return pgerr.ErrInvalidReq.Msg("withReliability must be within [%d, %d)", constant.ViolationReliabilityRejectRuleRangeLeast, constant.ViolationReliabilityRejectRuleRangeMost)
+	}
+	return nil
+}
+
+// SaveRejectRule validates and saves the reject rule along with the result of its dry run, then broadcasts the
+// change to every instance so that compiled programs of the rule are dropped. New rules, and rules whose expression
+// or reliability has been changed, are always saved as inactive: they only go live through ActivateRejectRule,
+// once the dry run result has been confirmed.
+func (s *RejectRule) SaveRejectRule(ctx context.Context, rejectRule *model.RejectRule, dryRun *RejectRuleDryRunResult) (*model.RejectRuleRevision, error) {
+	if err := s.validateRejectRule(rejectRule); err != nil {
+		return nil, err
+	}
+
+	rejectRule.Status = repo.RejectRuleInactiveStatus
+	if rejectRule.RuleID != 0 {
+		existing, err := s.RejectRuleRepo.GetRejectRule(ctx, rejectRule.RuleID)
+		if err != nil {
+			return nil, err
+		}
+		if existing.Expr == rejectRule.Expr && existing.WithReliability == rejectRule.WithReliability {
+			rejectRule.Status = existing.Status
+		}
+	}
+
+	now := time.Now()
+	if rejectRule.CreatedAt == nil {
+		rejectRule.CreatedAt = &now
+	}
+	rejectRule.UpdatedAt = &now
+
+	revision, err := s.RejectRuleRepo.SaveRejectRule(ctx, rejectRule, dryRun.EvaluatedCount, dryRun.RejectedCount)
+	if err != nil {
+		return nil, err
+	}
+
+	s.InvalidateRejectRulePrograms()
+
+	return revision, nil
+}
+
+// ActivateRejectRule activates the reject rule, provided that the revision and the dry run rejected count confirmed
+// by the caller match the latest revision of the rule, so that a rule never goes live with a dry run result the
+// caller has not seen.
+func (s *RejectRule) ActivateRejectRule(ctx context.Context, ruleId int, revision int, dryRunRejectedCount int) (*model.RejectRule, error) {
+	rejectRule, err := s.RejectRuleRepo.GetRejectRule(ctx, ruleId)
+	if err != nil {
+		return nil, err
+	}
+
+	latest, err := s.RejectRuleRepo.GetLatestRejectRuleRevision(ctx, ruleId)
+	if err != nil {
+		return nil, err
+	}
+	if latest.Revision != revision {
+		return nil, pgerr.ErrInvalidReq.Msg("rule has been changed since revision %d, the latest revision is %d", revision, latest.Revision)
+	}
+	if latest.DryRunRejectedCount == nil {
+		return nil, pgerr.ErrInvalidReq.Msg("revision %d has not been dry run, save the rule again to dry run it", latest.Revision)
+	}
+	if *latest.DryRunRejectedCount != dryRunRejectedCount {
+		return nil, pgerr.ErrInvalidReq.Msg("dry run of revision %d rejected %d reports, not %d", latest.Revision, *latest.DryRunRejectedCount, dryRunRejectedCount)
+	}
+
+	if rejectRule.Status == repo.RejectRuleActiveStatus {
+		return rejectRule, nil
+	}
+	if err := s.validateRejectRule(rejectRule); err != nil {
+		return nil, err
+	}
+
+	return s.updateRejectRuleStatus(ctx, ruleId, repo.RejectRuleActiveStatus)
+}
+
+// DisableRejectRule deactivates the reject rule. Disabled rules are kept, along with their revisions. The rule is
+// not validated, so that rules which no longer compile can still be disabled.
+func (s *RejectRule) DisableRejectRule(ctx context.Context, ruleId int) (*model.RejectRule, error) {
+	rejectRule, err := s.RejectRuleRepo.GetRejectRule(ctx, ruleId)
+	if err != nil {
+		return nil, err
+	}
+
+	if rejectRule.Status == repo.RejectRuleInactiveStatus {
+		return rejectRule, nil
+	}
+
+	return s.updateRejectRuleStatus(ctx, ruleId, repo.RejectRuleInactiveStatus)
+}
+
+func (s *RejectRule) updateRejectRuleStatus(ctx context.Context, ruleId int, status int) (*model.RejectRule, error) {
+	rejectRule, err := s.RejectRuleRepo.UpdateRejectRuleStatus(ctx, ruleId, status, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	s.InvalidateRejectRulePrograms()
+
+	return rejectRule, nil
+}
+
+// InvalidateRejectRulePrograms is best-effort: programs are keyed by rule revision, therefore
+// instances which missed the broadcast would still pick up the new revision of a saved rule.
+func (s *RejectRule) InvalidateRejectRulePrograms() {
+	if err := reportverifs.BroadcastRejectRuleProgramsInvalidation(s.NatsConn); err != nil {
+		log.Warn().
+			Err(err).
+			Str("evt.name", "reject_rule.invalidate").
+			Msg("failed to broadcast reject rule programs invalidation")
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/uptrace/bun"
 
 	"exusiai.dev/backend-next/internal/model/types"
+	"exusiai.dev/backend-next/internal/pkg/flog"
 	"exusiai.dev/backend-next/internal/pkg/pgerr"
 	"exusiai.dev/backend-next/internal/pkg/pgid"
 	"exusiai.dev/backend-next/internal/repo"
@@ -44,9 +45,11 @@ type Report struct {
 	DropReportExtraRepo    *repo.DropReportExtra
 	DropPatternElementRepo *repo.DropPatternElement
 	ReportVerifier         *reportverifs.ReportVerifiers
+	ReportTaskStatus       *ReportTaskStatus
+	MatrixDelta            *MatrixDelta
 }
 
-func NewReport(db *bun.DB, redisClient *redis.Client, natsJs nats.JetStreamContext, itemService *Item, stageService *Stage, stageRepo *repo.Stage, dropInfoRepo *repo.DropInfo, dropReportRepo *repo.DropReport, dropReportExtraRepo *repo.DropReportExtra, dropPatternRepo *repo.DropPattern, dropPatternElementRepo *repo.DropPatternElement, accountService *Account, timeRangeService *TimeRange, reportVerifier *reportverifs.ReportVerifiers) *Report {
+func NewReport(db *bun.DB, redisClient *redis.Client, natsJs nats.JetStreamContext, itemService *Item, stageService *Stage, stageRepo *repo.Stage, dropInfoRepo *repo.DropInfo, dropReportRepo *repo.DropReport, dropReportExtraRepo *repo.DropReportExtra, dropPatternRepo *repo.DropPattern, dropPatternElementRepo *repo.DropPatternElement, accountService *Account, timeRangeService *TimeRange, reportVerifier *reportverifs.ReportVerifiers, reportTaskStatus *ReportTaskStatus, matrixDelta *MatrixDelta) *Report {
 	service := &Report{
 		DB:                     db,
 		Redis:                  redisClient,
@@ -62,6 +65,8 @@ func NewReport(db *bun.DB, redisClient *redis.Client, natsJs nats.JetStreamConte
 		DropReportExtraRepo:    dropReportExtraRepo,
 		DropPatternElementRepo: dropPatternElementRepo,
 		ReportVerifier:         reportVerifier,
+		ReportTaskStatus:       reportTaskStatus,
+		MatrixDelta:            matrixDelta,
 	}
 	return service
 }
@@ -202,6 +207,15 @@ func (s *Report) commitReportTask(ctx *fiber.Ctx, subject string, task *types.Re
 		return "", err
 	}
 
+	// marked as queued before publishing, so that it never overrides the status set by the report worker. Status
+	// tracking is best-effort: failing to mark the task shall not reject the submission.
+	if err := s.ReportTaskStatus.SetReportTaskQueued(ctx.UserContext(), taskId); err != nil {
+		flog.WarnFrom(ctx, "report.task_status.queued").
+			Err(err).
+			Str("taskId", taskId).
+			Msg("failed to set report task status as queued")
+	}
+
 	pub, err := s.NatsJS.PublishAsync(subject, reportTaskJsonBytes)
 	if err != nil {
 		return "", err
@@ -339,10 +353,22 @@ func (s *Report) RecallSingularReport(ctx context.Context, req *types.SingularRe
 		return err
 	}
 
-	err = s.DropReportRepo.DeleteDropReport(ctx, reportId)
+	var servers []string
+	err = s.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		reports, err := s.DropReportRepo.GetDropReportsForUpdate(ctx, tx, []int{reportId})
+		if err != nil {
+			return err
+		}
+		if err := s.DropReportRepo.DeleteDropReport(ctx, tx, reportId); err != nil {
+			return err
+		}
+		servers, err = s.MatrixDelta.ApplyReliabilityChanges(ctx, tx, reports, map[int]int{reportId: -1})
+		return err
+	})
 	if err != nil {
 		return err
 	}
+	s.MatrixDelta.SchedulePurge(servers...)
 
 	s.Redis.Del(ctx, req.ReportHash)
 
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-json"
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+
+	"exusiai.dev/backend-next/internal/model"
+	"exusiai.dev/backend-next/internal/model/types"
+	"exusiai.dev/backend-next/internal/pkg/jetstream"
+	"exusiai.dev/backend-next/internal/pkg/pgerr"
+)
+
+const (
+	ReportDeadLettersDefaultLimit = 50
+	ReportDeadLettersMaxLimit     = 500
+)
+
+// ReportDeadLetter manages the report tasks the report worker failed to process after all of their deliveries.
+// They are kept in a JetStream stream of their own, where admins could inspect them, and either replay them to
+// their original subject once the cause of the failure is resolved, or discard them.
+type ReportDeadLetter struct {
+	NatsJS           nats.JetStreamContext
+	ReportTaskStatus *ReportTaskStatus
+}
+
+func NewReportDeadLetter(natsJs nats.JetStreamContext, reportTaskStatus *ReportTaskStatus) *ReportDeadLetter {
+	return &ReportDeadLetter{
+		NatsJS:           natsJs,
+		ReportTaskStatus: reportTaskStatus,
+	}
+}
+
+// DeadLetterReportTask publishes the report task message to the dead letter stream, along with the amount of
+// times it has been delivered and the error of its last delivery, and marks the task as failed. taskId is empty if
+// the message could not be decoded into a report task.
+func (s *ReportDeadLetter) DeadLetterReportTask(ctx context.Context, msg *nats.Msg, taskId string, deliveries uint64, cause error) error {
+	deadLetter := nats.NewMsg(jetstream.ReportDeadLetterSubjectPrefix + msg.Subject)
+	deadLetter.Data = msg.Data
+	deadLetter.Header.Set(jetstream.HeaderOriginalSubject, msg.Subject)
+	deadLetter.Header.Set(jetstream.HeaderDeliveries, strconv.FormatUint(deliveries, 10))
+	if cause != nil {
+		deadLetter.Header.Set(jetstream.HeaderError, cause.Error())
+	}
+
+	if _, err := s.NatsJS.PublishMsg(deadLetter, nats.Context(ctx)); err != nil {
+		return err
+	}
+
+	if taskId == "" {
+		return nil
+	}
+	return s.ReportTaskStatus.SetReportTaskFailed(ctx, taskId)
+}
+
+// GetDeadLetters lists the dead letters after the stream sequence after, earliest first.
+func (s *ReportDeadLetter) GetDeadLetters(ctx context.Context, after uint64, limit int) ([]*model.ReportDeadLetter, error) {
+	if limit <= 0 {
+		limit = ReportDeadLettersDefaultLimit
+	} else if limit > ReportDeadLettersMaxLimit {
+		limit = ReportDeadLettersMaxLimit
+	}
+
+	info, err := s.NatsJS.StreamInfo(jetstream.ReportDeadLetterStream, nats.Context(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	deadLetters := make([]*model.ReportDeadLetter, 0, limit)
+	// messages which have been replayed or discarded leave gaps in the sequence
+	for seq := max(info.State.FirstSeq, after+1); seq <= info.State.LastSeq && len(deadLetters) < limit; seq++ {
+		deadLetter, err := s.GetDeadLetter(ctx, seq)
+		if errors.Is(err, pgerr.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		deadLetters = append(deadLetters, deadLetter)
+	}
+	return deadLetters, nil
+}
+
+func (s *ReportDeadLetter) GetDeadLetter(ctx context.Context, seq uint64) (*model.ReportDeadLetter, error) {
+	msg, err := s.getDeadLetterMsg(ctx, seq)
+	if err != nil {
+		return nil, err
+	}
+	return parseDeadLetter(msg), nil
+}
+
+func (s *ReportDeadLetter) getDeadLetterMsg(ctx context.Context, seq uint64) (*nats.RawStreamMsg, error) {
+	msg, err := s.NatsJS.GetMsg(jetstream.ReportDeadLetterStream, seq, nats.Context(ctx))
+	if errors.Is(err, nats.ErrMsgNotFound) {
+		return nil, pgerr.ErrNotFound
+	}
+	return msg, err
+}
+
+func parseDeadLetter(msg *nats.RawStreamMsg) *model.ReportDeadLetter {
+	deadLetter := &model.ReportDeadLetter{
+		Sequence:       msg.Sequence,
+		Subject:        msg.Header.Get(jetstream.HeaderOriginalSubject),
+		Error:          msg.Header.Get(jetstream.HeaderError),
+		DeadLetteredAt: msg.Time,
+	}
+	if deadLetter.Subject == "" {
+		deadLetter.Subject = strings.TrimPrefix(msg.Subject, jetstream.ReportDeadLetterSubjectPrefix)
+	}
+	deadLetter.Deliveries, _ = strconv.Atoi(msg.Header.Get(jetstream.HeaderDeliveries))
+
+	task := &types.ReportTask{}
+	if err := json.Unmarshal(msg.Data, task); err != nil {
+		deadLetter.Raw = string(msg.Data)
+	} else {
+		deadLetter.Task = task
+	}
+	return deadLetter
+}
+
+// ReplayDeadLetter republishes the dead letter to its original subject, where it is delivered to the report
+// worker again with a fresh delivery count, and then removes it from the dead letter stream.
+func (s *ReportDeadLetter) ReplayDeadLetter(ctx context.Context, seq uint64) (*model.ReportDeadLetter, error) {
+	msg, err := s.getDeadLetterMsg(ctx, seq)
+	if err != nil {
+		return nil, err
+	}
+	deadLetter := parseDeadLetter(msg)
+	if deadLetter.Task == nil {
+		return nil, pgerr.ErrInvalidReq.Msg("dead letter %d is not a valid report task and could only be discarded", seq)
+	}
+
+	if err := s.ReportTaskStatus.SetReportTaskQueued(ctx, deadLetter.Task.TaskID); err != nil {
+		return nil, err
+	}
+	if _, err := s.NatsJS.Publish(deadLetter.Subject, msg.Data, nats.Context(ctx)); err != nil {
+		return nil, err
+	}
+
+	if err := s.NatsJS.DeleteMsg(jetstream.ReportDeadLetterStream, seq, nats.Context(ctx)); err != nil {
+		return nil, err
+	}
+	return deadLetter, nil
+}
+
+func (s *ReportDeadLetter) DiscardDeadLetter(ctx context.Context, seq uint64) error {
+	err := s.NatsJS.DeleteMsg(jetstream.ReportDeadLetterStream, seq, nats.Context(ctx))
+	if errors.Is(err, nats.ErrMsgNotFound) {
+		return pgerr.ErrNotFound
+	}
+	return err
+}
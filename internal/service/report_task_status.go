@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+
+	modelv3 "exusiai.dev/backend-next/internal/model/v3"
+	"exusiai.dev/backend-next/internal/pkg/pgerr"
+)
+
+const (
+	reportTaskStatusRedisPrefix = "report:task_status:"
+	// reportTaskStatusTTL is the same as the TTL of the report IDs used by report recalls
+	reportTaskStatusTTL = time.Hour * 24
+)
+
+// ReportTaskStatus tracks the processing status of report tasks, so that clients could tell whether the reports
+// they submitted have been accepted, had their reliability lowered by a verifier, or failed to be processed.
+type ReportTaskStatus struct {
+	Redis *redis.Client
+}
+
+func NewReportTaskStatus(redisClient *redis.Client) *ReportTaskStatus {
+	return &ReportTaskStatus{
+		Redis: redisClient,
+	}
+}
+
+func (s *ReportTaskStatus) GetReportTaskStatus(ctx context.Context, taskId string) (*modelv3.ReportTaskStatus, error) {
+	b, err := s.Redis.Get(ctx, reportTaskStatusRedisPrefix+taskId).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, pgerr.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	var status modelv3.ReportTaskStatus
+	if err := json.Unmarshal(b, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func (s *ReportTaskStatus) SetReportTaskQueued(ctx context.Context, taskId string) error {
+	return s.setReportTaskStatus(ctx, &modelv3.ReportTaskStatus{
+		TaskID: taskId,
+		Status: modelv3.ReportTaskStatusQueued,
+	})
+}
+
+func (s *ReportTaskStatus) SetReportTaskProcessed(ctx context.Context, taskId string, results []*modelv3.ReportTaskResult) error {
+	return s.setReportTaskStatus(ctx, &modelv3.ReportTaskStatus{
+		TaskID:  taskId,
+		Status:  modelv3.ReportTaskStatusProcessed,
+		Reports: results,
+	})
+}
+
+func (s *ReportTaskStatus) SetReportTaskFailed(ctx context.Context, taskId string) error {
+	return s.setReportTaskStatus(ctx, &modelv3.ReportTaskStatus{
+		TaskID: taskId,
+		Status: modelv3.ReportTaskStatusFailed,
+	})
+}
+
+func (s *ReportTaskStatus) setReportTaskStatus(ctx context.Context, status *modelv3.ReportTaskStatus) error {
+	status.UpdatedAt = time.Now().UnixMilli()
+	b, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	return s.Redis.Set(ctx, reportTaskStatusRedisPrefix+status.TaskID, b, reportTaskStatusTTL).Err()
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"exusiai.dev/gommon/constant"
+	"github.com/pkg/errors"
+
+	"exusiai.dev/backend-next/internal/app/appconfig"
+	"exusiai.dev/backend-next/internal/model/cache"
+	modelv3 "exusiai.dev/backend-next/internal/model/v3"
+	"exusiai.dev/backend-next/internal/util"
+)
+
+// SanityValue computes the "equivalent sanity" value of items, i.e. how much sanity an item is worth when
+// farmed in the most efficient way, and the resulting efficiency of every currently open stage.
+type SanityValue struct {
+	Config         *appconfig.Config
+	PlannerService *Planner
+}
+
+func NewSanityValue(config *appconfig.Config, plannerService *Planner) *SanityValue {
+	return &SanityValue{
+		Config:         config,
+		PlannerService: plannerService,
+	}
+}
+
+// Cache: sanityValues#server|sourceCategory:{server}|{sourceCategory}, 24 hrs, records last modified time
+func (s *SanityValue) GetSanityValues(ctx context.Context, server string, sourceCategory string) (*modelv3.SanityValues, error) {
+	valueFunc := func() (*modelv3.SanityValues, error) {
+		return s.calcSanityValues(ctx, server, sourceCategory)
+	}
+
+	var result modelv3.SanityValues
+	key := server + constant.CacheSep + sourceCategory
+	calculated, err := cache.SanityValues.MutexGetSet(key, &result, valueFunc, 24*time.Hour)
+	if err != nil {
+		return nil, err
+	} else if calculated {
+		cache.LastModifiedTime.Set("[sanityValues#server|sourceCategory:"+key+"]", time.Now(), 0)
+	}
+	return &result, nil
+}
+
+// RunCalcSanityValueJob recalculates the sanity values of the server for every source category the matrix worker
+// calculates. It should run after the drop matrix job so that the latest drop matrix is used.
+func (s *SanityValue) RunCalcSanityValueJob(ctx context.Context, server string) error {
+	for _, sourceCategory := range s.Config.MatrixWorkerSourceCategories {
+		// deleting instead of setting the cached values is broadcast, so that other instances do not keep
+		// serving the outdated ones
+		if err := cache.SanityValues.Delete(server + constant.CacheSep + sourceCategory); err != nil {
+			return err
+		}
+		if _, err := s.GetSanityValues(ctx, server, sourceCategory); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SanityValue) calcSanityValues(ctx context.Context, server string, sourceCategory string) (*modelv3.SanityValues, error) {
+	activities, stages, err := s.PlannerService.stageActivities(ctx, server, sourceCategory, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	formula, err := s.PlannerService.getFormulaEntries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := util.SolveItemValues(append(activities, craftActivities(formula, true)...))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to solve item values")
+	}
+
+	result := &modelv3.SanityValues{
+		Server:         server,
+		SourceCategory: sourceCategory,
+		Items:          make([]*modelv3.ItemSanityValue, 0, len(values)),
+		Stages:         make([]*modelv3.StageSanityEfficiency, 0, len(stages)),
+		UpdatedAt:      time.Now().UnixMilli(),
+	}
+	for arkItemId, value := range values {
+		result.Items = append(result.Items, &modelv3.ItemSanityValue{
+			ItemID: arkItemId,
+			Value:  value,
+		})
+	}
+	sort.Slice(result.Items, func(i, j int) bool {
+		return result.Items[i].ItemID < result.Items[j].ItemID
+	})
+
+	for i, stage := range stages {
+		value := 0.0
+		for arkItemId, yield := range activities[i].Yields {
+			value += yield * values[arkItemId]
+		}
+		result.Stages = append(result.Stages, &modelv3.StageSanityEfficiency{
+			StageID:    stage.arkStageId,
+			Sanity:     int(stage.sanity),
+			Value:      value,
+			Efficiency: value / stage.sanity,
+		})
+	}
+	sort.SliceStable(result.Stages, func(i, j int) bool {
+		return result.Stages[i].Efficiency > result.Stages[j].Efficiency
+	})
+
+	return result, nil
+}
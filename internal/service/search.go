@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"sort"
+
+	"github.com/goccy/go-json"
+
+	"exusiai.dev/backend-next/internal/model"
+	modelv3 "exusiai.dev/backend-next/internal/model/v3"
+	"exusiai.dev/backend-next/internal/util"
+)
+
+const (
+	SearchTypeItem  = "item"
+	SearchTypeStage = "stage"
+	SearchTypeZone  = "zone"
+
+	SearchDefaultLimit = 10
+	SearchMaxLimit     = 50
+)
+
+// weights of the fields candidates are matched by, so that e.g. an exact match on an item's group ranks lower
+// than an exact match on another item's name
+const (
+	searchWeightName    = 1.0
+	searchWeightID      = 0.95
+	searchWeightKeyword = 0.9
+	searchWeightGroup   = 0.7
+)
+
+// Search ranks items, stages and zones by how well they match a query. Matching is done on the names of all
+// languages, ark IDs, item keywords (which include the pinyin and romaji pronunciations of item names), item groups
+// and stage codes, tolerating typos.
+type Search struct {
+	ItemService  *Item
+	StageService *Stage
+	ZoneService  *Zone
+}
+
+func NewSearch(itemService *Item, stageService *Stage, zoneService *Zone) *Search {
+	return &Search{
+		ItemService:  itemService,
+		StageService: stageService,
+		ZoneService:  zoneService,
+	}
+}
+
+type searchTerm struct {
+	text   string
+	weight float64
+}
+
+type searchCandidate struct {
+	hit   *modelv3.SearchHit
+	terms []*searchTerm
+}
+
+// Search returns at most limit hits of the given types (all types if empty), best match first.
+func (s *Search) Search(ctx context.Context, query string, types []string, limit int) (*modelv3.SearchResult, error) {
+	if limit <= 0 {
+		limit = SearchDefaultLimit
+	} else if limit > SearchMaxLimit {
+		limit = SearchMaxLimit
+	}
+	result := &modelv3.SearchResult{
+		Query: query,
+		Hits:  make([]*modelv3.SearchHit, 0),
+	}
+	normalizedQuery := util.NormalizeSearchText(query)
+	if normalizedQuery == "" {
+		return result, nil
+	}
+
+	candidates, err := s.getCandidates(ctx, types)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, candidate := range candidates {
+		best, matched := 0.0, ""
+		for _, term := range candidate.terms {
+			score := util.CalcSearchScore(normalizedQuery, term.text) * term.weight
+			if score > best {
+				best, matched = score, term.text
+			}
+		}
+		if best == 0 {
+			continue
+		}
+		hit := *candidate.hit
+		hit.Score = util.RoundFloat64(best, 4)
+		hit.Matched = matched
+		result.Hits = append(result.Hits, &hit)
+	}
+	sort.SliceStable(result.Hits, func(i, j int) bool {
+		return result.Hits[i].Score > result.Hits[j].Score
+	})
+	if len(result.Hits) > limit {
+		result.Hits = result.Hits[:limit]
+	}
+	return result, nil
+}
+
+func (s *Search) getCandidates(ctx context.Context, types []string) ([]*searchCandidate, error) {
+	enabled := make(map[string]bool, 3)
+	for _, t := range types {
+		enabled[t] = true
+	}
+	all := len(enabled) == 0
+
+	candidates := make([]*searchCandidate, 0)
+	var zones []*model.Zone
+	if all || enabled[SearchTypeStage] || enabled[SearchTypeZone] {
+		var err error
+		zones, err = s.ZoneService.GetZones(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if all || enabled[SearchTypeItem] {
+		items, err := s.ItemService.GetItems(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			candidates = append(candidates, newItemSearchCandidate(item))
+		}
+	}
+	if all || enabled[SearchTypeStage] {
+		stages, err := s.StageService.GetStages(ctx)
+		if err != nil {
+			return nil, err
+		}
+		arkZoneIds := make(map[int]string, len(zones))
+		for _, zone := range zones {
+			arkZoneIds[zone.ZoneID] = zone.ArkZoneID
+		}
+		for _, stage := range stages {
+			candidates = append(candidates, newStageSearchCandidate(stage, arkZoneIds[stage.ZoneID]))
+		}
+	}
+	if all || enabled[SearchTypeZone] {
+		for _, zone := range zones {
+			candidates = append(candidates, newZoneSearchCandidate(zone))
+		}
+	}
+	return candidates, nil
+}
+
+func newItemSearchCandidate(item *model.Item) *searchCandidate {
+	candidate := &searchCandidate{
+		hit: &modelv3.SearchHit{
+			Type: SearchTypeItem,
+			ID:   item.ArkItemID,
+			Name: item.Name,
+		},
+	}
+	candidate.addTerms(searchWeightID, item.ArkItemID)
+	candidate.addTerms(searchWeightName, collectJSONStrings(item.Name)...)
+	candidate.addTerms(searchWeightKeyword, collectJSONStrings(item.Keywords)...)
+	if item.Group.Valid {
+		candidate.addTerms(searchWeightGroup, item.Group.String)
+	}
+	return candidate
+}
+
+func newStageSearchCandidate(stage *model.Stage, arkZoneId string) *searchCandidate {
+	candidate := &searchCandidate{
+		hit: &modelv3.SearchHit{
+			Type:   SearchTypeStage,
+			ID:     stage.ArkStageID,
+			Name:   stage.Code,
+			ZoneID: arkZoneId,
+		},
+	}
+	candidate.addTerms(searchWeightID, stage.ArkStageID)
+	candidate.addTerms(searchWeightName, collectJSONStrings(stage.Code)...)
+	return candidate
+}
+
+func newZoneSearchCandidate(zone *model.Zone) *searchCandidate {
+	candidate := &searchCandidate{
+		hit: &modelv3.SearchHit{
+			Type:   SearchTypeZone,
+			ID:     zone.ArkZoneID,
+			Name:   zone.Name,
+			ZoneID: zone.ArkZoneID,
+		},
+	}
+	candidate.addTerms(searchWeightID, zone.ArkZoneID)
+	candidate.addTerms(searchWeightName, collectJSONStrings(zone.Name)...)
+	return candidate
+}
+
+func (c *searchCandidate) addTerms(weight float64, texts ...string) {
+	for _, text := range texts {
+		if normalized := util.NormalizeSearchText(text); normalized != "" {
+			c.terms = append(c.terms, &searchTerm{text: normalized, weight: weight})
+		}
+	}
+}
+
+// collectJSONStrings returns all strings in raw, no matter how deep they are nested in objects and arrays. This
+// copes with the different shapes of names (language to name) and keywords (arbitrary objects).
+func collectJSONStrings(raw json.RawMessage) []string {
+	if len(raw) == 0 {
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil
+	}
+	strs := make([]string, 0)
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case string:
+			strs = append(strs, v)
+		case []any:
+			for _, el := range v {
+				walk(el)
+			}
+		case map[string]any:
+			for _, el := range v {
+				walk(el)
+			}
+		}
+	}
+	walk(v)
+	return strs
+}
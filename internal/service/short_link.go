@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.uber.org/fx"
+
+	"exusiai.dev/backend-next/internal/model"
+	"exusiai.dev/backend-next/internal/model/cache"
+	"exusiai.dev/backend-next/internal/pkg/pgerr"
+	"exusiai.dev/backend-next/internal/repo"
+)
+
+const (
+	ShortLinkDefaultLocale = "default"
+
+	ShortLinkClicksDefaultDays = 30
+
+	// ShortLinkClicksFlushInterval is the interval clicks counted in memory are flushed to the database in.
+	ShortLinkClicksFlushInterval = 10 * time.Second
+)
+
+// ShortLink manages the custom short links created by admins, and aggregates their clicks by day and country.
+type ShortLink struct {
+	ShortLinkRepo *repo.ShortLink
+
+	// clicks maps shortLinkClickKey to the *atomic.Int64 amount of clicks not yet flushed to the database
+	clicks sync.Map
+}
+
+type shortLinkClickKey struct {
+	linkId  int
+	date    time.Time
+	country string
+}
+
+func NewShortLink(lc fx.Lifecycle, shortLinkRepo *repo.ShortLink) *ShortLink {
+	s := &ShortLink{
+		ShortLinkRepo: shortLinkRepo,
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			go func() {
+				defer close(stopped)
+				ticker := time.NewTicker(ShortLinkClicksFlushInterval)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-ticker.C:
+						ctx, cancel := context.WithTimeout(context.Background(), ShortLinkClicksFlushInterval)
+						s.flushShortLinkClicks(ctx)
+						cancel()
+					case <-done:
+						return
+					}
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			close(done)
+			<-stopped
+			// clicks counted since the last tick must not be lost on shutdown
+			s.flushShortLinkClicks(ctx)
+			return nil
+		},
+	})
+
+	return s
+}
+
+func (s *ShortLink) GetShortLinks(ctx context.Context) ([]*model.ShortLink, error) {
+	return s.ShortLinkRepo.GetShortLinks(ctx)
+}
+
+func (s *ShortLink) GetShortLink(ctx context.Context, linkId int) (*model.ShortLink, error) {
+	return s.ShortLinkRepo.GetShortLink(ctx, linkId)
+}
+
+// Cache: (singular) shortLinksMapByWord, 5 min
+func (s *ShortLink) GetShortLinksMapByWord(ctx context.Context) (map[string]*model.ShortLink, error) {
+	var shortLinksMap map[string]*model.ShortLink
+	err := cache.ShortLinksMapByWord.MutexGetSet(&shortLinksMap, func() (map[string]*model.ShortLink, error) {
+		shortLinks, err := s.ShortLinkRepo.GetShortLinks(ctx)
+		if err != nil {
+			return nil, err
+		}
+		m := make(map[string]*model.ShortLink, len(shortLinks))
+		for _, shortLink := range shortLinks {
+			m[shortLink.Word] = shortLink
+		}
+		return m, nil
+	}, 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	return shortLinksMap, nil
+}
+
+// GetActiveShortLinkByWord returns the short link of the word (case-insensitive), or pgerr.ErrNotFound if there is
+// none or it has expired.
+func (s *ShortLink) GetActiveShortLinkByWord(ctx context.Context, word string) (*model.ShortLink, error) {
+	shortLinksMap, err := s.GetShortLinksMapByWord(ctx)
+	if err != nil {
+		return nil, err
+	}
+	shortLink, ok := shortLinksMap[strings.ToLower(word)]
+	if !ok || shortLink.Expired(time.Now()) {
+		return nil, pgerr.ErrNotFound
+	}
+	return shortLink, nil
+}
+
+// SaveShortLink creates the short link if it has no ID yet, or updates it otherwise.
+func (s *ShortLink) SaveShortLink(ctx context.Context, shortLink *model.ShortLink) error {
+	shortLink.Word = strings.ToLower(shortLink.Word)
+	if strings.ContainsAny(shortLink.Word, "/?# ") {
+		return pgerr.ErrInvalidReq.Msg("word must not contain slashes, question marks, hashes or spaces")
+	}
+	if _, ok := shortLink.Targets[ShortLinkDefaultLocale]; !ok {
+		return pgerr.ErrInvalidReq.Msg("targets must contain a %q target", ShortLinkDefaultLocale)
+	}
+	for locale, target := range shortLink.Targets {
+		if !strings.HasPrefix(target, "/") && !strings.HasPrefix(target, "https://") {
+			return pgerr.ErrInvalidReq.Msg("target of locale %q must either be a path starting with a slash or an https URL", locale)
+		}
+	}
+	taken, err := s.ShortLinkRepo.IsWordTaken(ctx, shortLink.Word, shortLink.LinkID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return pgerr.ErrInvalidReq.Msg("word %q is already taken by another short link", shortLink.Word)
+	}
+
+	now := time.Now()
+	shortLink.UpdatedAt = &now
+	if shortLink.LinkID == 0 {
+		shortLink.CreatedAt = &now
+		err = s.ShortLinkRepo.CreateShortLink(ctx, shortLink)
+	} else {
+		err = s.ShortLinkRepo.UpdateShortLink(ctx, shortLink)
+	}
+	if err != nil {
+		return err
+	}
+
+	return cache.ShortLinksMapByWord.Delete()
+}
+
+// DeleteShortLink deletes the short link. Its aggregated clicks are kept.
+func (s *ShortLink) DeleteShortLink(ctx context.Context, linkId int) error {
+	if err := s.ShortLinkRepo.DeleteShortLink(ctx, linkId); err != nil {
+		return err
+	}
+	return cache.ShortLinksMapByWord.Delete()
+}
+
+// RecordShortLinkClick counts a click of the short link from the country (ISO code, empty if unknown) for today.
+// Clicks are counted in memory and flushed to the database every ShortLinkClicksFlushInterval, so that recording
+// them never holds up redirects nor takes a database round trip per click.
+func (s *ShortLink) RecordShortLinkClick(linkId int, country string) {
+	key := shortLinkClickKey{
+		linkId:  linkId,
+		date:    time.Now().UTC().Truncate(24 * time.Hour),
+		country: country,
+	}
+	counter, _ := s.clicks.LoadOrStore(key, new(atomic.Int64))
+	counter.(*atomic.Int64).Add(1)
+}
+
+// flushShortLinkClicks writes the clicks counted in memory to the database in a batch. Clicks failed to be
+// written are counted again, to be retried on the next flush.
+func (s *ShortLink) flushShortLinkClicks(ctx context.Context) {
+	clicks := make([]*model.ShortLinkClick, 0)
+	counters := make([]*atomic.Int64, 0)
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	s.clicks.Range(func(k, v any) bool {
+		key, counter := k.(shortLinkClickKey), v.(*atomic.Int64)
+		n := counter.Swap(0)
+		if n == 0 {
+			// counters of past days are no longer incremented
+			if key.date.Before(today) {
+				s.clicks.Delete(key)
+			}
+			return true
+		}
+		clicks = append(clicks, &model.ShortLinkClick{
+			LinkID:  key.linkId,
+			Date:    key.date,
+			Country: key.country,
+			Clicks:  int(n),
+		})
+		counters = append(counters, counter)
+		return true
+	})
+	if len(clicks) == 0 {
+		return
+	}
+
+	if err := s.ShortLinkRepo.IncrShortLinkClicks(ctx, clicks); err != nil {
+		for i, click := range clicks {
+			counters[i].Add(int64(click.Clicks))
+		}
+		log.Warn().Err(err).
+			Str("evt.name", "shortlink.click.flush").
+			Int("count", len(clicks)).
+			Msg("failed to flush short link clicks; they will be retried on the next flush")
+	}
+}
+
+// GetShortLinkClickStats aggregates the clicks of the short link within the most recent days (in UTC, including
+// today), by country (most clicks first) and by day (earliest first).
+func (s *ShortLink) GetShortLinkClickStats(ctx context.Context, linkId int, days int) (*model.ShortLinkClickStats, error) {
+	if days <= 0 {
+		days = ShortLinkClicksDefaultDays
+	}
+	since := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -(days - 1))
+	clicks, err := s.ShortLinkRepo.GetShortLinkClicks(ctx, linkId, since)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &model.ShortLinkClickStats{
+		LinkID:    linkId,
+		Since:     since.Format(time.DateOnly),
+		ByCountry: make([]*model.ShortLinkCountryClick, 0),
+		ByDay:     make([]*model.ShortLinkDayClick, 0),
+	}
+	byCountry := make(map[string]*model.ShortLinkCountryClick)
+	byDay := make(map[string]*model.ShortLinkDayClick)
+	for _, click := range clicks {
+		stats.Total += click.Clicks
+
+		if _, ok := byCountry[click.Country]; !ok {
+			byCountry[click.Country] = &model.ShortLinkCountryClick{Country: click.Country}
+			stats.ByCountry = append(stats.ByCountry, byCountry[click.Country])
+		}
+		byCountry[click.Country].Clicks += click.Clicks
+
+		date := click.Date.Format(time.DateOnly)
+		if _, ok := byDay[date]; !ok {
+			byDay[date] = &model.ShortLinkDayClick{Date: date}
+			stats.ByDay = append(stats.ByDay, byDay[date])
+		}
+		byDay[date].Clicks += click.Clicks
+	}
+	sort.SliceStable(stats.ByCountry, func(i, j int) bool {
+		return stats.ByCountry[i].Clicks > stats.ByCountry[j].Clicks
+	})
+	return stats, nil
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestRecordShortLinkClick(t *testing.T) {
+	s := &ShortLink{}
+	s.RecordShortLinkClick(1, "CN")
+	s.RecordShortLinkClick(1, "CN")
+	s.RecordShortLinkClick(1, "")
+	s.RecordShortLinkClick(2, "CN")
+
+	counts := make(map[shortLinkClickKey]int64)
+	s.clicks.Range(func(k, v any) bool {
+		counts[k.(shortLinkClickKey)] = v.(*atomic.Int64).Load()
+		return true
+	})
+	if len(counts) != 3 {
+		t.Fatalf("Expected clicks to be counted by link and country, got %v", counts)
+	}
+	for key, count := range counts {
+		want := int64(1)
+		if key.linkId == 1 && key.country == "CN" {
+			want = 2
+		}
+		if count != want {
+			t.Errorf("Expected %d clicks of link %d from %q, got %d", want, key.linkId, key.country, count)
+		}
+	}
+}
@@ -3,26 +3,45 @@ package service
 import (
 	"context"
 	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
 
+	modelv3 "exusiai.dev/backend-next/internal/model/v3"
 	"exusiai.dev/backend-next/internal/util"
 	"exusiai.dev/gommon/constant"
 )
 
+var errNoConfidentSearchHit = errors.New("no confident search hit")
+
+// shortURLSearchMinScore is the minimum score of the best search hit for it to be redirected to, instead of the
+// frontend search page. It allows a typo or two in queries of a reasonable length.
+const shortURLSearchMinScore = 0.5
+
+// shortURLSearchMinQueryLength is the minimum length of a query, in runes, for an inexact search hit to be
+// redirected to. Shorter queries are too ambiguous to be fuzzily matched, and are only redirected on exact hits.
+const shortURLSearchMinQueryLength = 3
+
 type ShortURL struct {
-	ItemService  *Item
-	StageService *Stage
-	ZoneService  *Zone
-	GeoIPService *GeoIP
+	ItemService      *Item
+	StageService     *Stage
+	ZoneService      *Zone
+	GeoIPService     *GeoIP
+	SearchService    *Search
+	ShortLinkService *ShortLink
 }
 
-func NewShortURL(itemService *Item, stageService *Stage, zoneService *Zone, geoIPService *GeoIP) *ShortURL {
+func NewShortURL(itemService *Item, stageService *Stage, zoneService *Zone, geoIPService *GeoIP, searchService *Search, shortLinkService *ShortLink) *ShortURL {
 	return &ShortURL{
-		ItemService:  itemService,
-		StageService: stageService,
-		ZoneService:  zoneService,
-		GeoIPService: geoIPService,
+		ItemService:      itemService,
+		StageService:     stageService,
+		ZoneService:      zoneService,
+		GeoIPService:     geoIPService,
+		SearchService:    searchService,
+		ShortLinkService: shortLinkService,
 	}
 }
 
@@ -52,6 +71,11 @@ func (s *ShortURL) Resolve(ctx *fiber.Ctx, path string) string {
 	}
 	path = escapedPath
 
+	// Custom Short Links, which take precedence over everything else
+	if resolved, err := s.resolveByShortLink(ctx, path); err == nil {
+		return resolved
+	}
+
 	// Simple Keyword Matching
 	if path == "item" {
 		return s.siteURL(ctx, "/result/item")
@@ -63,11 +87,8 @@ func (s *ShortURL) Resolve(ctx *fiber.Ctx, path string) string {
 		return s.siteURL(ctx, "/planner")
 	}
 
-	// Item Name Matching
-	if resolved, err := s.resolveByItemName(ctx.UserContext(), path); err == nil {
-		return s.siteURL(ctx, resolved)
-	}
-	if resolved, err := s.resolveByStageCode(ctx.UserContext(), path); err == nil {
+	// Fuzzy Matching on names, codes and keywords
+	if resolved, err := s.resolveBySearch(ctx.UserContext(), path); err == nil {
 		return s.siteURL(ctx, resolved)
 	}
 	if resolved, err := s.resolveByItemId(ctx.UserContext(), path); err == nil {
@@ -81,27 +102,69 @@ func (s *ShortURL) Resolve(ctx *fiber.Ctx, path string) string {
 	return s.siteURL(ctx, resolved)
 }
 
-func (s *ShortURL) resolveByItemName(ctx context.Context, path string) (string, error) {
-	item, err := s.ItemService.SearchItemByName(ctx, path)
+// resolveByShortLink returns the target of the custom short link of path, in the first locale accepted by the
+// visitor that the link has a target of, and records the click.
+func (s *ShortURL) resolveByShortLink(ctx *fiber.Ctx, path string) (string, error) {
+	shortLink, err := s.ShortLinkService.GetActiveShortLinkByWord(ctx.UserContext(), path)
 	if err != nil {
 		return "", err
 	}
 
-	return "/result/item/" + item.ArkItemID + "?utm_source=exusiai&utm_medium=item&utm_campaign=name", nil
+	locales := make([]string, 0, len(shortLink.Targets))
+	for locale := range shortLink.Targets {
+		if locale != ShortLinkDefaultLocale {
+			locales = append(locales, locale)
+		}
+	}
+	sort.Strings(locales)
+	target := shortLink.Targets[ShortLinkDefaultLocale]
+	// without an Accept-Language header, fiber would pick the first locale instead of none
+	if ctx.Get(fiber.HeaderAcceptLanguage) != "" {
+		if locale := ctx.AcceptsLanguages(locales...); locale != "" {
+			target = shortLink.Targets[locale]
+		}
+	}
+
+	s.recordShortLinkClick(util.ExtractIP(ctx), shortLink.LinkID)
+
+	if strings.HasPrefix(target, "/") {
+		return s.siteURL(ctx, target), nil
+	}
+	return target, nil
 }
 
-func (s *ShortURL) resolveByStageCode(ctx context.Context, path string) (string, error) {
-	stage, err := s.StageService.SearchStageByCode(ctx, path)
+// recordShortLinkClick counts the click in memory, so that redirects are never held up by the database.
+func (s *ShortURL) recordShortLinkClick(ip string, linkId int) {
+	s.ShortLinkService.RecordShortLinkClick(linkId, s.GeoIPService.CountryCode(ip))
+}
+
+func (s *ShortURL) resolveBySearch(ctx context.Context, path string) (string, error) {
+	result, err := s.SearchService.Search(ctx, path, nil, 1)
 	if err != nil {
 		return "", err
 	}
+	if len(result.Hits) == 0 || !isConfidentSearchHit(result.Hits[0], path) {
+		return "", errNoConfidentSearchHit
+	}
 
-	zone, err := s.ZoneService.GetZoneById(ctx, stage.ZoneID)
-	if err != nil {
-		return "", err
+	hit := result.Hits[0]
+	switch hit.Type {
+	case SearchTypeItem:
+		return "/result/item/" + hit.ID + "?utm_source=exusiai&utm_medium=item&utm_campaign=search", nil
+	case SearchTypeStage:
+		return "/result/stage/" + hit.ZoneID + "/" + hit.ID + "?utm_source=exusiai&utm_medium=stage&utm_campaign=search", nil
+	default:
+		return "/result/stage/" + hit.ZoneID + "?utm_source=exusiai&utm_medium=zone&utm_campaign=search", nil
 	}
+}
 
-	return "/result/stage/" + zone.ArkZoneID + "/" + stage.ArkStageID + "?utm_source=exusiai&utm_medium=stage&utm_campaign=code", nil
+// isConfidentSearchHit reports whether the search hit of path is confident enough to be redirected to.
+func isConfidentSearchHit(hit *modelv3.SearchHit, path string) bool {
+	if hit.Score < shortURLSearchMinScore {
+		return false
+	}
+	normalizedPath := util.NormalizeSearchText(path)
+	return hit.Matched == normalizedPath || utf8.RuneCountInString(normalizedPath) >= shortURLSearchMinQueryLength
 }
 
 func (s *ShortURL) resolveByStageId(ctx context.Context, path string) (string, error) {
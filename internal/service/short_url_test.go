@@ -0,0 +1,30 @@
+package service
+
+import (
+	"testing"
+
+	modelv3 "exusiai.dev/backend-next/internal/model/v3"
+)
+
+func TestIsConfidentSearchHit(t *testing.T) {
+	tests := []struct {
+		name string
+		hit  *modelv3.SearchHit
+		path string
+		want bool
+	}{
+		{"exact hit of a short query", &modelv3.SearchHit{Score: 1, Matched: "17"}, "1-7", true},
+		{"exact hit of a short query ignoring case", &modelv3.SearchHit{Score: 1, Matched: "ce"}, "CE", true},
+		{"prefix hit of a short query", &modelv3.SearchHit{Score: 0.9, Matched: "ce5"}, "ce", false},
+		{"prefix hit of a long enough query", &modelv3.SearchHit{Score: 0.9, Matched: "orirock"}, "ori", true},
+		{"punctuations do not count towards the length", &modelv3.SearchHit{Score: 0.9, Matched: "ce5"}, "c-e", false},
+		{"short query in runes", &modelv3.SearchHit{Score: 0.9, Matched: "固源岩组"}, "固源", false},
+		{"long enough query in runes", &modelv3.SearchHit{Score: 0.9, Matched: "固源岩组"}, "固源岩", true},
+		{"low score", &modelv3.SearchHit{Score: 0.4, Matched: "orirock"}, "orirok cube", false},
+	}
+	for _, tt := range tests {
+		if got := isConfidentSearchHit(tt.hit, tt.path); got != tt.want {
+			t.Errorf("%s: isConfidentSearchHit(%q) = %v, want %v", tt.name, tt.path, got, tt.want)
+		}
+	}
+}
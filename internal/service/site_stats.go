@@ -2,24 +2,38 @@ package service
 
 import (
 	"context"
+	"sort"
 	"time"
 
+	"exusiai.dev/backend-next/internal/model"
 	"exusiai.dev/backend-next/internal/model/cache"
 	modelv2 "exusiai.dev/backend-next/internal/model/v2"
+	modelv3 "exusiai.dev/backend-next/internal/model/v3"
+	"exusiai.dev/backend-next/internal/repo"
+	"exusiai.dev/backend-next/internal/util"
+	"exusiai.dev/backend-next/internal/util/reportverifs"
+)
+
+const (
+	SiteStatsSeriesDefaultDays = 30
+	SiteStatsSeriesMaxDays     = 365
 )
 
 type SiteStats struct {
 	DropReportService        *DropReport
 	DropMatrixElementService *DropMatrixElement
+	SiteStatsDailyRepo       *repo.SiteStatsDaily
 }
 
 func NewSiteStats(
 	dropReportService *DropReport,
 	dropMatrixElementService *DropMatrixElement,
+	siteStatsDailyRepo *repo.SiteStatsDaily,
 ) *SiteStats {
 	return &SiteStats{
 		DropReportService:        dropReportService,
 		DropMatrixElementService: dropMatrixElementService,
+		SiteStatsDailyRepo:       siteStatsDailyRepo,
 	}
 }
 
@@ -73,3 +87,164 @@ func (s *SiteStats) RefreshShimSiteStats(ctx context.Context, server string) (*m
 	cache.LastModifiedTime.Set("[shimSiteStats#server:"+server+"]", time.Now(), 0)
 	return &results, nil
 }
+
+// =========== Daily ===========
+
+// Cache: siteStatsSeries#server:{server}, 24hrs, records last modified time
+// The whole series of SiteStatsSeriesMaxDays days is cached, and cut to the requested amount of days.
+func (s *SiteStats) GetSiteStatsSeries(ctx context.Context, server string, days int) (*modelv3.SiteStatsSeries, error) {
+	valueFunc := func() (*modelv3.SiteStatsSeries, error) {
+		now := time.Now()
+		statsList, err := s.SiteStatsDailyRepo.GetSiteStatsDailyByServer(ctx, server, util.GetDayNum(&now, server)-SiteStatsSeriesMaxDays+1)
+		if err != nil {
+			return nil, err
+		}
+		series := &modelv3.SiteStatsSeries{
+			Server: server,
+			Days:   make([]*modelv3.SiteStatsDay, 0, len(statsList)),
+		}
+		for _, stats := range statsList {
+			series.Days = append(series.Days, convertSiteStatsDaily(stats))
+		}
+		return series, nil
+	}
+
+	var series modelv3.SiteStatsSeries
+	calculated, err := cache.SiteStatsSeries.MutexGetSet(server, &series, valueFunc, 24*time.Hour)
+	if err != nil {
+		return nil, err
+	} else if calculated {
+		cache.LastModifiedTime.Set("[siteStatsSeries#server:"+server+"]", time.Now(), 0)
+	}
+
+	if days <= 0 {
+		days = SiteStatsSeriesDefaultDays
+	}
+	if len(series.Days) > days {
+		series.Days = series.Days[len(series.Days)-days:]
+	}
+	return &series, nil
+}
+
+// RunSiteStatsDailyJob persists the site stats of today so far, along with the ones of yesterday, so that reports
+// submitted at the end of yesterday are counted in after the day has passed.
+func (s *SiteStats) RunSiteStatsDailyJob(ctx context.Context, server string) error {
+	now := time.Now()
+	today := util.GetDayNum(&now, server)
+	for _, dayNum := range []int{today - 1, today} {
+		stats, err := s.calcSiteStatsDaily(ctx, server, dayNum)
+		if err != nil {
+			return err
+		}
+		if err := s.SiteStatsDailyRepo.UpsertSiteStatsDaily(ctx, stats); err != nil {
+			return err
+		}
+	}
+
+	return cache.SiteStatsSeries.Delete(server)
+}
+
+func (s *SiteStats) calcSiteStatsDaily(ctx context.Context, server string, dayNum int) (*model.SiteStatsDaily, error) {
+	start := time.UnixMilli(util.GetDayStartTimestampFromDayNum(dayNum, server))
+	end := time.UnixMilli(util.GetDayStartTimestampFromDayNum(dayNum+1, server))
+
+	uniqueAccounts, err := s.SiteStatsDailyRepo.CalcUniqueAccountsForSiteStatsDaily(ctx, server, start, end)
+	if err != nil {
+		return nil, err
+	}
+	reportCounts, err := s.SiteStatsDailyRepo.CalcReportCountsForSiteStatsDaily(ctx, server, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	stats := &model.SiteStatsDaily{
+		Server:         server,
+		DayNum:         dayNum,
+		StartTime:      &start,
+		UniqueAccounts: uniqueAccounts,
+		Sources:        make([]*model.SiteStatsDailySource, 0),
+		Rejections:     make([]*model.SiteStatsDailyRejection, 0),
+		UpdatedAt:      &now,
+	}
+	sources := make(map[[2]string]*model.SiteStatsDailySource)
+	rejections := make(map[string]*model.SiteStatsDailyRejection)
+	for _, count := range reportCounts {
+		stats.Reports += count.Reports
+		stats.Times += count.Times
+
+		key := [2]string{count.SourceName, count.Version}
+		source, ok := sources[key]
+		if !ok {
+			source = &model.SiteStatsDailySource{
+				SourceName: count.SourceName,
+				Version:    count.Version,
+			}
+			sources[key] = source
+			stats.Sources = append(stats.Sources, source)
+		}
+		source.Reports += count.Reports
+		source.Times += count.Times
+
+		// negative reliabilities are reports recalled by their accounts, not rejections
+		if count.Reliability <= 0 {
+			continue
+		}
+		source.Rejected += count.Reports
+		verifier := reportverifs.VerifierNameByReliability(count.Reliability)
+		rejection, ok := rejections[verifier]
+		if !ok {
+			rejection = &model.SiteStatsDailyRejection{
+				Verifier: verifier,
+			}
+			rejections[verifier] = rejection
+			stats.Rejections = append(stats.Rejections, rejection)
+		}
+		rejection.Reports += count.Reports
+	}
+	sort.Slice(stats.Sources, func(i, j int) bool {
+		return stats.Sources[i].Reports > stats.Sources[j].Reports
+	})
+	sort.Slice(stats.Rejections, func(i, j int) bool {
+		return stats.Rejections[i].Verifier < stats.Rejections[j].Verifier
+	})
+	return stats, nil
+}
+
+func convertSiteStatsDaily(stats *model.SiteStatsDaily) *modelv3.SiteStatsDay {
+	day := &modelv3.SiteStatsDay{
+		StartTime:      stats.StartTime.UnixMilli(),
+		UniqueAccounts: stats.UniqueAccounts,
+		Reports:        stats.Reports,
+		Times:          stats.Times,
+		Sources:        make([]*modelv3.SiteStatsSource, 0, len(stats.Sources)),
+		Rejections:     make([]*modelv3.SiteStatsRejected, 0, len(stats.Rejections)),
+	}
+	for _, source := range stats.Sources {
+		day.Sources = append(day.Sources, &modelv3.SiteStatsSource{
+			SourceName: source.SourceName,
+			Version:    source.Version,
+			Reports:    source.Reports,
+			Times:      source.Times,
+			Rejected:   source.Rejected,
+		})
+	}
+	rejected := 0
+	for _, rejection := range stats.Rejections {
+		rejected += rejection.Reports
+		day.Rejections = append(day.Rejections, &modelv3.SiteStatsRejected{
+			Verifier: rejection.Verifier,
+			Reports:  rejection.Reports,
+			Ratio:    siteStatsRatio(rejection.Reports, stats.Reports),
+		})
+	}
+	day.RejectionRatio = siteStatsRatio(rejected, stats.Reports)
+	return day
+}
+
+func siteStatsRatio(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return util.RoundFloat64(float64(part)/float64(total), 6)
+}
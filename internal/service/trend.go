@@ -2,40 +2,62 @@ package service
 
 import (
 	"context"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"exusiai.dev/gommon/constant"
 	"github.com/ahmetb/go-linq/v3"
 	"github.com/rs/zerolog/log"
+	"github.com/samber/lo"
 	"gopkg.in/guregu/null.v3"
 
+	"exusiai.dev/backend-next/internal/app/appconfig"
 	"exusiai.dev/backend-next/internal/model"
 	"exusiai.dev/backend-next/internal/model/cache"
+	"exusiai.dev/backend-next/internal/model/types"
 	modelv2 "exusiai.dev/backend-next/internal/model/v2"
+	modelv3 "exusiai.dev/backend-next/internal/model/v3"
 	"exusiai.dev/backend-next/internal/util"
 )
 
+const (
+	TrendGranularityDay   = "day"
+	TrendGranularityWeek  = "week"
+	TrendGranularityRange = "range"
+
+	TrendDefaultDailyMovingAverage = 7
+	TrendDefaultMovingAverage      = 3
+)
+
 type Trend struct {
+	Config                   *appconfig.Config
 	DropReportService        *DropReport
 	DropInfoService          *DropInfo
 	StageService             *Stage
 	ItemService              *Item
 	DropMatrixElementService *DropMatrixElement
+	TimeRangeService         *TimeRange
 }
 
 func NewTrend(
+	config *appconfig.Config,
 	dropReportService *DropReport,
 	dropInfoService *DropInfo,
 	stageService *Stage,
 	itemService *Item,
 	dropMatrixElementService *DropMatrixElement,
+	timeRangeService *TimeRange,
 ) *Trend {
 	return &Trend{
+		Config:                   config,
 		DropReportService:        dropReportService,
 		DropInfoService:          dropInfoService,
 		StageService:             stageService,
 		ItemService:              itemService,
 		DropMatrixElementService: dropMatrixElementService,
+		TimeRangeService:         timeRangeService,
 	}
 }
 
@@ -116,9 +138,9 @@ func (s *Trend) calcTrendFromDropMatrixElements(ctx context.Context, server stri
 // =========== Customized ===========
 
 func (s *Trend) GetShimCustomizedTrendResults(
-	ctx context.Context, server string, startTime *time.Time, intervalLength time.Duration, intervalNum int, stageIds []int, itemIds []int, accountId null.Int, sourceCategory string,
+	ctx context.Context, server string, startTime *time.Time, intervalLength time.Duration, intervalNum int, stageIds []int, itemIds []int, accountId null.Int, sourceCategory string, country string, weighted bool,
 ) (*modelv2.TrendQueryResult, error) {
-	trendQueryResult, err := s.queryTrend(ctx, server, startTime, intervalLength, intervalNum, stageIds, itemIds, accountId, sourceCategory)
+	trendQueryResult, err := s.queryTrend(ctx, server, startTime, intervalLength, intervalNum, stageIds, itemIds, accountId, sourceCategory, country, weighted)
 	if err != nil {
 		return nil, err
 	}
@@ -126,9 +148,9 @@ func (s *Trend) GetShimCustomizedTrendResults(
 }
 
 func (s *Trend) queryTrend(
-	ctx context.Context, server string, startTime *time.Time, intervalLength time.Duration, intervalNum int, stageIdFilter []int, itemIdFilter []int, accountId null.Int, sourceCategory string,
+	ctx context.Context, server string, startTime *time.Time, intervalLength time.Duration, intervalNum int, stageIdFilter []int, itemIdFilter []int, accountId null.Int, sourceCategory string, country string, weighted bool,
 ) (*model.TrendQueryResult, error) {
-	trendElements, err := s.calcTrend(ctx, server, startTime, intervalLength, intervalNum, stageIdFilter, itemIdFilter, accountId, sourceCategory)
+	trendElements, err := s.calcTrend(ctx, server, startTime, intervalLength, intervalNum, stageIdFilter, itemIdFilter, accountId, sourceCategory, country, weighted)
 	if err != nil {
 		return nil, err
 	}
@@ -136,7 +158,7 @@ func (s *Trend) queryTrend(
 }
 
 func (s *Trend) calcTrend(
-	ctx context.Context, server string, startTime *time.Time, intervalLength time.Duration, intervalNum int, stageIdFilter []int, itemIdFilter []int, accountId null.Int, sourceCategory string,
+	ctx context.Context, server string, startTime *time.Time, intervalLength time.Duration, intervalNum int, stageIdFilter []int, itemIdFilter []int, accountId null.Int, sourceCategory string, country string, weighted bool,
 ) ([]*model.TrendElement, error) {
 	endTime := startTime.Add(time.Hour * time.Duration(int(intervalLength.Hours())*intervalNum))
 	if e := log.Trace(); e.Enabled() {
@@ -156,11 +178,11 @@ func (s *Trend) calcTrend(
 		return nil, err
 	}
 
-	quantityResults, err := s.DropReportService.CalcTotalQuantityForTrend(ctx, server, startTime, intervalLength, intervalNum, util.GetStageIdItemIdMapFromDropInfos(dropInfos), accountId, sourceCategory)
+	quantityResults, err := s.DropReportService.CalcTotalQuantityForTrend(ctx, server, startTime, intervalLength, intervalNum, util.GetStageIdItemIdMapFromDropInfos(dropInfos), accountId, sourceCategory, country, weighted)
 	if err != nil {
 		return nil, err
 	}
-	timesResults, err := s.DropReportService.CalcTotalTimesForTrend(ctx, server, startTime, intervalLength, intervalNum, util.GetStageIdsFromDropInfos(dropInfos), accountId, sourceCategory)
+	timesResults, err := s.DropReportService.CalcTotalTimesForTrend(ctx, server, startTime, intervalLength, intervalNum, util.GetStageIdsFromDropInfos(dropInfos), accountId, sourceCategory, country, weighted)
 	if err != nil {
 		return nil, err
 	}
@@ -336,6 +358,219 @@ func (s *Trend) convertTrendElementsToTrendQueryResult(trendElements []*model.Tr
 	return trendQueryResult, nil
 }
 
+// =========== Rolling ===========
+
+// GetRollingTrend aggregates the daily drop matrix elements of a stage within the last req.Window days into buckets
+// of the requested granularity, attaching the credible interval and the moving average of the rate to each bucket.
+// The moving average pools the last req.MovingAverage buckets of the granularity, where buckets without any
+// drops count as gaps of zero times rather than being skipped.
+//
+// Cache: rollingTrend#server|arkStageId|window|category|granularity|movingAverage|confidenceLevel|arkItemId:{...}, WorkerInterval
+func (s *Trend) GetRollingTrend(ctx context.Context, server string, arkStageId string, req *types.TrendRequest) (*modelv3.TrendResult, error) {
+	normalized := types.TrendRequest{
+		ItemID:          req.ItemID,
+		Category:        lo.Ternary(req.Category == "", constant.SourceCategoryAll, req.Category),
+		Window:          lo.Ternary(req.Window <= 0, constant.DefaultIntervalNum, req.Window),
+		Granularity:     lo.Ternary(req.Granularity == "", TrendGranularityDay, req.Granularity),
+		MovingAverage:   req.MovingAverage,
+		ConfidenceLevel: lo.Ternary(req.ConfidenceLevel <= 0, util.DefaultConfidenceLevel, req.ConfidenceLevel),
+	}
+	if normalized.MovingAverage <= 0 {
+		normalized.MovingAverage = lo.Ternary(normalized.Granularity == TrendGranularityDay, TrendDefaultDailyMovingAverage, TrendDefaultMovingAverage)
+	}
+
+	valueFunc := func() (*modelv3.TrendResult, error) {
+		return s.calcRollingTrend(ctx, server, arkStageId, &normalized)
+	}
+
+	var result modelv3.TrendResult
+	key := strings.Join([]string{
+		server,
+		arkStageId,
+		strconv.Itoa(normalized.Window),
+		normalized.Category,
+		normalized.Granularity,
+		strconv.Itoa(normalized.MovingAverage),
+		strconv.FormatFloat(normalized.ConfidenceLevel, 'f', -1, 64),
+		normalized.ItemID,
+	}, constant.CacheSep)
+	if _, err := cache.RollingTrend.MutexGetSet(key, &result, valueFunc, s.Config.WorkerInterval); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// calcRollingTrend calculates the rolling trend of GetRollingTrend, for a request with all defaults applied.
+func (s *Trend) calcRollingTrend(ctx context.Context, server string, arkStageId string, req *types.TrendRequest) (*modelv3.TrendResult, error) {
+	stage, err := s.StageService.GetStageByArkId(ctx, arkStageId)
+	if err != nil {
+		return nil, err
+	}
+	var itemIdFilter int
+	if req.ItemID != "" {
+		item, err := s.ItemService.GetItemByArkId(ctx, req.ItemID)
+		if err != nil {
+			return nil, err
+		}
+		itemIdFilter = item.ItemID
+	}
+
+	result := &modelv3.TrendResult{
+		Server:          server,
+		StageID:         stage.ArkStageID,
+		SourceCategory:  req.Category,
+		Granularity:     req.Granularity,
+		Window:          req.Window,
+		MovingAverage:   req.MovingAverage,
+		ConfidenceLevel: req.ConfidenceLevel,
+		Items:           make([]*modelv3.ItemTrend, 0),
+	}
+
+	now := time.Now()
+	endDayNum := util.GetDayNum(&now, server)
+	startDayNum := endDayNum - result.Window + 1
+	start := time.UnixMilli(util.GetDayStartTimestampFromDayNum(startDayNum, server))
+	end := time.UnixMilli(util.GetDayStartTimestampFromDayNum(endDayNum+1, server))
+	elements, err := s.DropMatrixElementService.GetElementsByServerAndStageIdAndTimeRange(ctx, server, stage.StageID, &model.TimeRange{
+		StartTime: &start,
+		EndTime:   &end,
+	}, result.SourceCategory)
+	if err != nil {
+		return nil, err
+	}
+
+	var timeRangesByItemId map[int][]*model.TimeRange
+	if result.Granularity == TrendGranularityRange {
+		maxAccumulableTimeRanges, err := s.TimeRangeService.GetAllMaxAccumulableTimeRangesByServer(ctx, server)
+		if err != nil {
+			return nil, err
+		}
+		timeRangesByItemId = maxAccumulableTimeRanges[stage.StageID]
+	}
+
+	bucketsByItemId := make(map[int]map[int]*rollingTrendBucket)
+	for _, element := range elements {
+		if itemIdFilter != 0 && element.ItemID != itemIdFilter {
+			continue
+		}
+
+		var key int
+		var bucketStart, bucketEnd time.Time
+		switch result.Granularity {
+		case TrendGranularityWeek:
+			key = (element.DayNum - startDayNum) / 7
+			bucketStart = time.UnixMilli(util.GetDayStartTimestampFromDayNum(startDayNum+key*7, server))
+			bucketEnd = time.UnixMilli(util.GetDayStartTimestampFromDayNum(startDayNum+key*7+7, server))
+		case TrendGranularityRange:
+			// elements are intersections of days and max accumulable time ranges, so they belong to exactly one range
+			key = -1
+			for idx, timeRange := range timeRangesByItemId[element.ItemID] {
+				if timeRange.Includes(*element.StartTime) {
+					key = idx
+					bucketStart, bucketEnd = *timeRange.StartTime, *timeRange.EndTime
+					break
+				}
+			}
+			if key == -1 {
+				continue
+			}
+		default:
+			key = element.DayNum
+			bucketStart = time.UnixMilli(util.GetDayStartTimestampFromDayNum(element.DayNum, server))
+			bucketEnd = time.UnixMilli(util.GetDayStartTimestampFromDayNum(element.DayNum+1, server))
+		}
+		// the first and the last buckets could be cut by the window
+		if bucketStart.Before(start) {
+			bucketStart = start
+		}
+		if bucketEnd.After(end) {
+			bucketEnd = end
+		}
+
+		if _, ok := bucketsByItemId[element.ItemID]; !ok {
+			bucketsByItemId[element.ItemID] = make(map[int]*rollingTrendBucket)
+		}
+		bucket, ok := bucketsByItemId[element.ItemID][key]
+		if !ok {
+			bucket = &rollingTrendBucket{
+				key:             key,
+				startTime:       bucketStart,
+				endTime:         bucketEnd,
+				quantityBuckets: make(map[int]int),
+			}
+			bucketsByItemId[element.ItemID][key] = bucket
+		}
+		bucket.times += element.Times
+		bucket.quantity += element.Quantity
+		for quantity, count := range element.QuantityBuckets {
+			bucket.quantityBuckets[quantity] += count
+		}
+	}
+
+	itemsMapById, err := s.ItemService.GetItemsMapById(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for itemId, bucketsByKey := range bucketsByItemId {
+		item, ok := itemsMapById[itemId]
+		if !ok {
+			continue
+		}
+		buckets := lo.Values(bucketsByKey)
+		sort.Slice(buckets, func(i, j int) bool {
+			return buckets[i].startTime.Before(buckets[j].startTime)
+		})
+		result.Items = append(result.Items, &modelv3.ItemTrend{
+			ItemID:  item.ArkItemID,
+			Buckets: convertRollingTrendBuckets(buckets, result.MovingAverage, result.ConfidenceLevel),
+		})
+	}
+	sort.Slice(result.Items, func(i, j int) bool {
+		return result.Items[i].ItemID < result.Items[j].ItemID
+	})
+	return result, nil
+}
+
+type rollingTrendBucket struct {
+	// key is the position of the bucket within the window, in units of the granularity
+	key             int
+	startTime       time.Time
+	endTime         time.Time
+	times           int
+	quantity        int
+	quantityBuckets map[int]int
+}
+
+func convertRollingTrendBuckets(buckets []*rollingTrendBucket, movingAverage int, level float64) []*modelv3.TrendBucket {
+	results := make([]*modelv3.TrendBucket, 0, len(buckets))
+	for i, bucket := range buckets {
+		if bucket.times == 0 {
+			continue
+		}
+		lower, upper := util.CalcQuantityCredibleInterval(bucket.quantityBuckets, bucket.times, level)
+
+		// pooling (instead of averaging the rates) keeps buckets with few runs from dominating the average.
+		// buckets are pooled by their keys, so that missing (empty) buckets count as gaps instead of being skipped.
+		pooledTimes, pooledQuantity := 0, 0
+		for j := i; j >= 0 && buckets[j].key > bucket.key-movingAverage; j-- {
+			pooledTimes += buckets[j].times
+			pooledQuantity += buckets[j].quantity
+		}
+
+		results = append(results, &modelv3.TrendBucket{
+			StartTime:     bucket.startTime.UnixMilli(),
+			EndTime:       bucket.endTime.UnixMilli(),
+			Times:         bucket.times,
+			Quantity:      bucket.quantity,
+			Rate:          util.RoundFloat64(float64(bucket.quantity)/float64(bucket.times), constant.StdDevDigits+2),
+			Lower:         util.RoundFloat64(lower, constant.StdDevDigits+2),
+			Upper:         util.RoundFloat64(upper, constant.StdDevDigits+2),
+			MovingAverage: util.RoundFloat64(float64(pooledQuantity)/float64(pooledTimes), constant.StdDevDigits+2),
+		})
+	}
+	return results
+}
+
 // =========== Helpers ===========
 
 func (s *Trend) applyShimForTrendQuery(ctx context.Context, queryResult *model.TrendQueryResult, startTime *time.Time) (*modelv2.TrendQueryResult, error) {
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConvertRollingTrendBucketsMovingAverage(t *testing.T) {
+	day := func(key int) time.Time {
+		return time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, key)
+	}
+	bucket := func(key int, times int, quantity int) *rollingTrendBucket {
+		return &rollingTrendBucket{
+			key:             key,
+			startTime:       day(key),
+			endTime:         day(key + 1),
+			times:           times,
+			quantity:        quantity,
+			quantityBuckets: map[int]int{1: quantity},
+		}
+	}
+
+	// days 2 to 4 have no drops
+	buckets := []*rollingTrendBucket{
+		bucket(0, 10, 10),
+		bucket(1, 10, 0),
+		bucket(5, 10, 10),
+	}
+	results := convertRollingTrendBuckets(buckets, 3, 0.95)
+	if len(results) != 3 {
+		t.Fatalf("Expected a result per bucket, got %d", len(results))
+	}
+
+	tests := []struct {
+		name string
+		want float64
+	}{
+		{"first bucket", 1},
+		{"pooled with the first bucket", 0.5},
+		// the window of day 5 covers days 3 to 5, so days 0 and 1 must not be pooled
+		{"after the gap", 1},
+	}
+	for i, tt := range tests {
+		if results[i].MovingAverage != tt.want {
+			t.Errorf("%s: expected moving average %f, got %f", tt.name, tt.want, results[i].MovingAverage)
+		}
+	}
+}
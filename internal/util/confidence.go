@@ -0,0 +1,150 @@
+package util
+
+import (
+	"math"
+)
+
+const (
+	ConfidenceMethodWilson         = "wilson"
+	ConfidenceMethodClopperPearson = "clopper-pearson"
+
+	DefaultConfidenceLevel = 0.95
+)
+
+func IsValidConfidenceMethod(method string) bool {
+	return method == ConfidenceMethodWilson || method == ConfidenceMethodClopperPearson
+}
+
+// CalcNormalQuantile returns the z score such that P(Z <= z) = p for a standard normal distribution.
+func CalcNormalQuantile(p float64) float64 {
+	return math.Sqrt2 * math.Erfinv(2*p-1)
+}
+
+// CalcWilsonInterval calculates the Wilson score interval of a binomial proportion with the given
+// number of successes out of n trials, at the given confidence level (e.g. 0.95).
+func CalcWilsonInterval(successes, n int, level float64) (lower, upper float64) {
+	if n <= 0 {
+		return 0, 1
+	}
+	z := CalcNormalQuantile(1 - (1-level)/2)
+	p := float64(successes) / float64(n)
+	nf := float64(n)
+	denominator := 1 + z*z/nf
+	center := (p + z*z/(2*nf)) / denominator
+	margin := z / denominator * math.Sqrt(p*(1-p)/nf+z*z/(4*nf*nf))
+	return math.Max(0, center-margin), math.Min(1, center+margin)
+}
+
+// CalcClopperPearsonInterval calculates the exact (Clopper-Pearson) interval of a binomial proportion
+// with the given number of successes out of n trials, at the given confidence level (e.g. 0.95).
+func CalcClopperPearsonInterval(successes, n int, level float64) (lower, upper float64) {
+	if n <= 0 {
+		return 0, 1
+	}
+	alpha := 1 - level
+	lower, upper = 0, 1
+	if successes > 0 {
+		lower = calcBetaQuantile(alpha/2, float64(successes), float64(n-successes+1))
+	}
+	if successes < n {
+		upper = calcBetaQuantile(1-alpha/2, float64(successes+1), float64(n-successes))
+	}
+	return lower, upper
+}
+
+// CalcQuantityCredibleInterval calculates a credible interval of the expected drop quantity per run.
+// The distribution of quantities is modeled as a categorical distribution over the observed quantity
+// buckets with a Dirichlet posterior; the posterior of the expected quantity is then approximated
+// by a normal distribution using its exact posterior mean and variance.
+func CalcQuantityCredibleInterval(quantityBuckets map[int]int, times int, level float64) (lower, upper float64) {
+	if times <= 0 {
+		return 0, 0
+	}
+	// buckets might not sum up to times: the remaining runs are considered as 0 quantity
+	alpha0 := float64(times)
+	mean := 0.0
+	squareMean := 0.0
+	for quantity, count := range quantityBuckets {
+		p := float64(count) / alpha0
+		mean += float64(quantity) * p
+		squareMean += float64(quantity*quantity) * p
+	}
+	variance := (squareMean - mean*mean) / (alpha0 + 1)
+	if variance < 0 {
+		variance = 0
+	}
+	z := CalcNormalQuantile(1 - (1-level)/2)
+	margin := z * math.Sqrt(variance)
+	return math.Max(0, mean-margin), mean + margin
+}
+
+// calcBetaQuantile finds x such that I_x(a, b) = p using bisection.
+func calcBetaQuantile(p, a, b float64) float64 {
+	lo, hi := 0.0, 1.0
+	for i := 0; i < 100; i++ {
+		mid := (lo + hi) / 2
+		if calcRegularizedIncompleteBeta(mid, a, b) < p {
+			lo = mid
+		} else {
+			hi = mid
+		}
+		if hi-lo < 1e-12 {
+			break
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// calcRegularizedIncompleteBeta evaluates I_x(a, b) with the continued fraction representation
+// (modified Lentz's method).
+func calcRegularizedIncompleteBeta(x, a, b float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	lgammaA, _ := math.Lgamma(a)
+	lgammaB, _ := math.Lgamma(b)
+	lgammaAB, _ := math.Lgamma(a + b)
+	front := math.Exp(lgammaAB - lgammaA - lgammaB + a*math.Log(x) + b*math.Log(1-x))
+
+	// the continued fraction converges rapidly only for x < (a+1)/(a+b+2)
+	if x > (a+1)/(a+b+2) {
+		return 1 - calcRegularizedIncompleteBeta(1-x, b, a)
+	}
+
+	const (
+		epsilon = 1e-14
+		tiny    = 1e-300
+	)
+	f, c, d := 1.0, 1.0, 0.0
+	for i := 0; i <= 300; i++ {
+		m := float64(i / 2)
+		var numerator float64
+		switch {
+		case i == 0:
+			numerator = 1
+		case i%2 == 0:
+			numerator = m * (b - m) * x / ((a + 2*m - 1) * (a + 2*m))
+		default:
+			numerator = -(a + m) * (a + b + m) * x / ((a + 2*m) * (a + 2*m + 1))
+		}
+
+		d = 1 + numerator*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		d = 1 / d
+		c = 1 + numerator/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		cd := c * d
+		f *= cd
+		if math.Abs(1-cd) < epsilon {
+			break
+		}
+	}
+	return front * (f - 1) / a
+}
@@ -0,0 +1,42 @@
+package util
+
+import (
+	"math"
+	"testing"
+)
+
+func assertInterval(t *testing.T, name string, gotLower, gotUpper, wantLower, wantUpper float64) {
+	t.Helper()
+	if math.Abs(gotLower-wantLower) > 1e-4 || math.Abs(gotUpper-wantUpper) > 1e-4 {
+		t.Errorf("%s: expected [%.4f, %.4f], got [%.4f, %.4f]", name, wantLower, wantUpper, gotLower, gotUpper)
+	}
+}
+
+func TestCalcWilsonInterval(t *testing.T) {
+	lower, upper := CalcWilsonInterval(5, 10, 0.95)
+	assertInterval(t, "5/10", lower, upper, 0.2366, 0.7634)
+
+	lower, upper = CalcWilsonInterval(0, 10, 0.95)
+	assertInterval(t, "0/10", lower, upper, 0, 0.2775)
+}
+
+func TestCalcClopperPearsonInterval(t *testing.T) {
+	lower, upper := CalcClopperPearsonInterval(5, 10, 0.95)
+	assertInterval(t, "5/10", lower, upper, 0.1871, 0.8129)
+
+	lower, upper = CalcClopperPearsonInterval(0, 10, 0.95)
+	assertInterval(t, "0/10", lower, upper, 0, 1-math.Pow(0.025, 0.1))
+
+	lower, upper = CalcClopperPearsonInterval(10, 10, 0.95)
+	assertInterval(t, "10/10", lower, upper, math.Pow(0.025, 0.1), 1)
+}
+
+func TestCalcQuantityCredibleInterval(t *testing.T) {
+	lower, upper := CalcQuantityCredibleInterval(map[int]int{1: 100}, 100, 0.95)
+	assertInterval(t, "constant", lower, upper, 1, 1)
+
+	lower, upper = CalcQuantityCredibleInterval(map[int]int{0: 50, 2: 50}, 100, 0.95)
+	if lower >= 1 || upper <= 1 {
+		t.Errorf("expected interval to contain the mean 1, got [%.4f, %.4f]", lower, upper)
+	}
+}
@@ -0,0 +1,166 @@
+package util
+
+import (
+	"errors"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/optimize/convex/lp"
+)
+
+var ErrPlannerInfeasible = errors.New("planner: demands could not be satisfied by the given activities")
+
+const plannerTolerance = 1e-9
+
+// PlannerActivity is something that could be done repeatedly for a cost, producing and/or consuming items,
+// e.g. a stage run or a crafting.
+type PlannerActivity struct {
+	Cost float64
+	// Yields is the expected net amount of each item produced by doing the activity once. Consumed items
+	// have negative yields.
+	Yields map[string]float64
+}
+
+// SolvePlanner finds how many times each activity should be done to minimize the total cost, while the net
+// yields of all activities cover the demands. demands maps item IDs to the required net amount; items not in
+// demands must not be consumed more than they are produced. The returned amounts are in the same order as
+// activities.
+func SolvePlanner(activities []*PlannerActivity, demands map[string]float64) (amounts []float64, totalCost float64, err error) {
+	amounts = make([]float64, len(activities))
+
+	hasDemand := false
+	for _, demand := range demands {
+		if demand > 0 {
+			hasDemand = true
+			break
+		}
+	}
+	if !hasDemand {
+		return amounts, 0, nil
+	}
+
+	// rows are items, sorted so that the problem (and therefore the solution) is deterministic
+	itemSet := make(map[string]struct{})
+	for itemId := range demands {
+		itemSet[itemId] = struct{}{}
+	}
+	// activities yielding nothing would be all-zero columns, which the simplex refuses
+	columns := make([]int, 0, len(activities))
+	for i, activity := range activities {
+		nonZero := false
+		for itemId, yield := range activity.Yields {
+			if yield != 0 {
+				itemSet[itemId] = struct{}{}
+				nonZero = true
+			}
+		}
+		if nonZero {
+			columns = append(columns, i)
+		}
+	}
+	items := make([]string, 0, len(itemSet))
+	for itemId := range itemSet {
+		items = append(items, itemId)
+	}
+	sort.Strings(items)
+	rows := make(map[string]int, len(items))
+	for i, itemId := range items {
+		rows[itemId] = i
+	}
+
+	// standard form: activities followed by one surplus variable per item, so that
+	//   sum(yield * amount) - surplus = demand, amount >= 0, surplus >= 0
+	// the surplus variables also guarantee A to be of full row rank.
+	m, n := len(items), len(columns)+len(items)
+	c := make([]float64, n)
+	a := mat.NewDense(m, n, nil)
+	b := make([]float64, m)
+	for j, i := range columns {
+		c[j] = activities[i].Cost
+		for itemId, yield := range activities[i].Yields {
+			a.Set(rows[itemId], j, yield)
+		}
+	}
+	for i, itemId := range items {
+		a.Set(i, len(columns)+i, -1)
+		b[i] = demands[itemId]
+	}
+
+	totalCost, x, err := lp.Simplex(c, a, b, plannerTolerance, nil)
+	if err != nil {
+		if errors.Is(err, lp.ErrInfeasible) {
+			return nil, 0, ErrPlannerInfeasible
+		}
+		return nil, 0, err
+	}
+
+	for j, i := range columns {
+		if x[j] > plannerTolerance {
+			amounts[i] = x[j]
+		}
+	}
+	return amounts, totalCost, nil
+}
+
+// SolveItemValues derives the value of items in units of activity cost (e.g. "equivalent sanity"), as the
+// largest values such that no activity produces more value than it costs:
+//
+//	maximize sum(value), s.t. sum(yield * value) <= cost for every activity, value >= 0
+//
+// Activities with the best cost efficiency end up producing exactly their cost in value. Only items produced
+// by at least one activity are valued, since the value of the others is unbounded.
+func SolveItemValues(activities []*PlannerActivity) (map[string]float64, error) {
+	itemSet := make(map[string]struct{})
+	for _, activity := range activities {
+		for itemId, yield := range activity.Yields {
+			if yield > 0 {
+				itemSet[itemId] = struct{}{}
+			}
+		}
+	}
+	if len(itemSet) == 0 || len(activities) == 0 {
+		return map[string]float64{}, nil
+	}
+	items := make([]string, 0, len(itemSet))
+	for itemId := range itemSet {
+		items = append(items, itemId)
+	}
+	sort.Strings(items)
+	columns := make(map[string]int, len(items))
+	for i, itemId := range items {
+		columns[itemId] = i
+	}
+
+	// standard form: item values followed by one slack variable per activity, so that
+	//   sum(yield * value) + slack = cost, value >= 0, slack >= 0
+	// the slack variables are a feasible initial basis since costs are non-negative.
+	m, n := len(activities), len(items)+len(activities)
+	c := make([]float64, n)
+	a := mat.NewDense(m, n, nil)
+	b := make([]float64, m)
+	initialBasic := make([]int, m)
+	for j := range items {
+		c[j] = -1
+	}
+	for i, activity := range activities {
+		for itemId, yield := range activity.Yields {
+			if j, ok := columns[itemId]; ok {
+				a.Set(i, j, yield)
+			}
+		}
+		a.Set(i, len(items)+i, 1)
+		b[i] = activity.Cost
+		initialBasic[i] = len(items) + i
+	}
+
+	_, x, err := lp.Simplex(c, a, b, plannerTolerance, initialBasic)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]float64, len(items))
+	for j, itemId := range items {
+		values[itemId] = x[j]
+	}
+	return values, nil
+}
@@ -0,0 +1,57 @@
+package util
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestSolvePlanner(t *testing.T) {
+	activities := []*PlannerActivity{
+		// cheap stage dropping the base material
+		{Cost: 6, Yields: map[string]float64{"base": 1}},
+		// expensive stage dropping the advanced material directly
+		{Cost: 20, Yields: map[string]float64{"advanced": 1}},
+		// crafting 1 advanced from 2 base
+		{Cost: 0, Yields: map[string]float64{"base": -2, "advanced": 1}},
+	}
+
+	amounts, totalCost, err := SolvePlanner(activities, map[string]float64{"advanced": 3, "base": -1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// 3 advanced need 6 base, 1 of which is owned: 5 runs of the cheap stage, then craft 3 times
+	if math.Abs(totalCost-30) > 1e-6 {
+		t.Errorf("expected total cost 30, got %f", totalCost)
+	}
+	if math.Abs(amounts[0]-5) > 1e-6 || amounts[1] != 0 || math.Abs(amounts[2]-3) > 1e-6 {
+		t.Errorf("expected amounts [5 0 3], got %v", amounts)
+	}
+
+	amounts, totalCost, err = SolvePlanner(activities, map[string]float64{"base": -10})
+	if err != nil || totalCost != 0 || amounts[0] != 0 {
+		t.Errorf("expected nothing to do without demands, got %v, %f, %v", amounts, totalCost, err)
+	}
+
+	_, _, err = SolvePlanner(activities, map[string]float64{"unobtainable": 1})
+	if !errors.Is(err, ErrPlannerInfeasible) {
+		t.Errorf("expected ErrPlannerInfeasible, got %v", err)
+	}
+}
+
+func TestSolveItemValues(t *testing.T) {
+	activities := []*PlannerActivity{
+		{Cost: 6, Yields: map[string]float64{"base": 1}},
+		{Cost: 20, Yields: map[string]float64{"advanced": 1}},
+		{Cost: 0, Yields: map[string]float64{"base": -2, "advanced": 1}},
+	}
+
+	values, err := SolveItemValues(activities)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// crafting caps the value of advanced at twice the value of base, which is cheaper than its own stage
+	if math.Abs(values["base"]-6) > 1e-6 || math.Abs(values["advanced"]-12) > 1e-6 {
+		t.Errorf("expected values base=6 advanced=12, got %v", values)
+	}
+}
@@ -200,3 +200,16 @@ func ValidCategory(ctx *fiber.Ctx, category string) error {
 
 	return nil
 }
+
+func ValidConfidence(ctx *fiber.Ctx, method string, level float64) error {
+	type request struct {
+		Method string  `validate:"omitempty,oneof=wilson clopper-pearson"`
+		Level  float64 `validate:"gt=0,lt=1"`
+	}
+
+	if err := ValidStruct(ctx, request{method, level}); err != nil {
+		return err
+	}
+
+	return nil
+}
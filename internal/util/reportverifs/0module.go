@@ -9,5 +9,6 @@ func Module() fx.Option {
 		NewDropVerifier,
 		NewReportVerifier,
 		NewRejectRuleVerifier,
+		NewRateAnomalyVerifier,
 	))
 }
@@ -0,0 +1,90 @@
+package reportverifs
+
+import (
+	"sync"
+	"time"
+
+	"github.com/antonmedv/expr"
+	"github.com/antonmedv/expr/vm"
+	"github.com/nats-io/nats.go"
+
+	"exusiai.dev/backend-next/internal/model"
+)
+
+// RejectRuleProgramsInvalidateSubject is the NATS subject used to broadcast reject rule changes,
+// so that every instance could drop its compiled reject rule programs.
+const RejectRuleProgramsInvalidateSubject = "REJECT_RULE.INVALIDATE"
+
+// CompileRejectRuleExpr compiles a reject rule expression against the ReportContext environment.
+// The expression must evaluate to a boolean, which indicates whether the report should be rejected.
+func CompileRejectRuleExpr(exprStr string) (*vm.Program, error) {
+	return expr.Compile(exprStr, expr.Env(ReportContext{}), expr.AsBool())
+}
+
+type rejectRuleProgramKey struct {
+	ruleId    int
+	updatedAt int64
+}
+
+type rejectRuleProgram struct {
+	program *vm.Program
+	// err is the compilation error, if any. It is cached as well so that a broken rule
+	// is only reported once per revision instead of on every report.
+	err error
+}
+
+// RejectRulePrograms caches compiled reject rule programs by rule ID and revision (UpdatedAt).
+type RejectRulePrograms struct {
+	mu       sync.RWMutex
+	programs map[rejectRuleProgramKey]*rejectRuleProgram
+}
+
+func NewRejectRulePrograms() *RejectRulePrograms {
+	return &RejectRulePrograms{
+		programs: make(map[rejectRuleProgramKey]*rejectRuleProgram),
+	}
+}
+
+// Get returns the compiled program of the rule, compiling it if the revision has not been seen yet.
+// The returned bool indicates whether the program is freshly compiled by this call.
+func (p *RejectRulePrograms) Get(rule *model.RejectRule) (*vm.Program, bool, error) {
+	key := rejectRuleProgramKey{ruleId: rule.RuleID}
+	if rule.UpdatedAt != nil {
+		key.updatedAt = rule.UpdatedAt.UnixNano()
+	}
+
+	p.mu.RLock()
+	cached, ok := p.programs[key]
+	p.mu.RUnlock()
+	if ok {
+		return cached.program, false, cached.err
+	}
+
+	program, err := CompileRejectRuleExpr(rule.Expr)
+	compiled := &rejectRuleProgram{program: program, err: err}
+
+	p.mu.Lock()
+	// previous revisions of the same rule will never be requested again
+	for k := range p.programs {
+		if k.ruleId == key.ruleId {
+			delete(p.programs, k)
+		}
+	}
+	p.programs[key] = compiled
+	p.mu.Unlock()
+
+	return program, true, err
+}
+
+// Flush drops all compiled programs.
+func (p *RejectRulePrograms) Flush() {
+	p.mu.Lock()
+	p.programs = make(map[rejectRuleProgramKey]*rejectRuleProgram)
+	p.mu.Unlock()
+}
+
+// BroadcastRejectRuleProgramsInvalidation notifies every instance (including the current one) that
+// reject rules have been changed and their compiled programs should be dropped.
+func BroadcastRejectRuleProgramsInvalidation(natsConn *nats.Conn) error {
+	return natsConn.Publish(RejectRuleProgramsInvalidateSubject, []byte(time.Now().UTC().Format(time.RFC3339Nano)))
+}
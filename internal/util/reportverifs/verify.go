@@ -19,12 +19,14 @@ type Verifier interface {
 
 type ReportVerifiers []Verifier
 
-func NewReportVerifier(userVerifier *UserVerifier, dropVerifier *DropVerifier, md5Verifier *MD5Verifier, rejectRuleVerifier *RejectRuleVerifier) *ReportVerifiers {
+func NewReportVerifier(userVerifier *UserVerifier, dropVerifier *DropVerifier, md5Verifier *MD5Verifier, rejectRuleVerifier *RejectRuleVerifier, rateAnomalyVerifier *RateAnomalyVerifier) *ReportVerifiers {
 	return &ReportVerifiers{
 		userVerifier,
 		md5Verifier,
 		dropVerifier,
 		rejectRuleVerifier,
+		// keep rate_anomaly the last one, as it only lowers reliability of reports otherwise accepted
+		rateAnomalyVerifier,
 	}
 }
 
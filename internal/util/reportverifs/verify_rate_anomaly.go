@@ -0,0 +1,345 @@
+package reportverifs
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"exusiai.dev/gommon/constant"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+
+	"exusiai.dev/backend-next/internal/app/appconfig"
+	"exusiai.dev/backend-next/internal/model"
+	"exusiai.dev/backend-next/internal/model/cache"
+	"exusiai.dev/backend-next/internal/model/types"
+	"exusiai.dev/backend-next/internal/repo"
+)
+
+const (
+	rateAnomalyRedisPrefix = "reportverifs:rate_anomaly:"
+
+	stageItemDropStatsCacheTTL = time.Minute * 10
+)
+
+// RateAnomalyVerifier looks at how fast an account (or IP) submits reports, and how plausible the drops
+// reported by an account in a stage are compared with the current drop matrix of that stage.
+// It never fails hard: reports are only marked with a lowered reliability, and infrastructure errors
+// let the report pass.
+type RateAnomalyVerifier struct {
+	Config                *appconfig.Config
+	Redis                 *redis.Client
+	DropInfoRepo          *repo.DropInfo
+	TimeRangeRepo         *repo.TimeRange
+	DropMatrixElementRepo *repo.DropMatrixElement
+}
+
+// ensure RateAnomalyVerifier conforms to Verifier
+var _ Verifier = (*RateAnomalyVerifier)(nil)
+
+func NewRateAnomalyVerifier(conf *appconfig.Config, redisClient *redis.Client, dropInfoRepo *repo.DropInfo, timeRangeRepo *repo.TimeRange, dropMatrixElementRepo *repo.DropMatrixElement) *RateAnomalyVerifier {
+	return &RateAnomalyVerifier{
+		Config:                conf,
+		Redis:                 redisClient,
+		DropInfoRepo:          dropInfoRepo,
+		TimeRangeRepo:         timeRangeRepo,
+		DropMatrixElementRepo: dropMatrixElementRepo,
+	}
+}
+
+func (v *RateAnomalyVerifier) Name() string {
+	return "rate_anomaly"
+}
+
+func (v *RateAnomalyVerifier) Verify(ctx context.Context, report *types.ReportTaskSingleReport, reportTask *types.ReportTask) *Rejection {
+	if reportTask.AccountID == 0 {
+		return nil
+	}
+
+	submittedAt := time.Now()
+	if reportTask.CreatedAt > 0 {
+		submittedAt = time.UnixMicro(reportTask.CreatedAt)
+	}
+	member := rateWindowMember(report, reportTask)
+
+	accountRuns, err := v.countWindowRuns(ctx, "account:"+strconv.Itoa(reportTask.AccountID), member, submittedAt, v.Config.ReportRateAccountWindow)
+	if err != nil {
+		v.logError(err, "failed to count account submission rate")
+		return nil
+	}
+	if accountRuns > v.Config.ReportRateAccountLimit {
+		return &Rejection{
+			Reliability: ViolationReliabilityRate,
+			Message:     fmt.Sprintf("account submitted %d runs within %s, exceeding the limit of %d", accountRuns, v.Config.ReportRateAccountWindow, v.Config.ReportRateAccountLimit),
+		}
+	}
+
+	if reportTask.IP != "" {
+		ipRuns, err := v.countWindowRuns(ctx, "ip:"+reportTask.IP, member, submittedAt, v.Config.ReportRateIPWindow)
+		if err != nil {
+			v.logError(err, "failed to count ip submission rate")
+			return nil
+		}
+		if ipRuns > v.Config.ReportRateIPLimit {
+			return &Rejection{
+				Reliability: ViolationReliabilityRate,
+				Message:     fmt.Sprintf("ip submitted %d runs within %s, exceeding the limit of %d", ipRuns, v.Config.ReportRateIPWindow, v.Config.ReportRateIPLimit),
+			}
+		}
+	}
+
+	sequenceTimes, sequenceQuantities, err := v.accumulateSequence(ctx, report, reportTask, submittedAt)
+	if err != nil {
+		v.logError(err, "failed to accumulate drop sequence")
+		return nil
+	}
+	if sequenceTimes < v.Config.ReportAnomalyMinRuns {
+		return nil
+	}
+
+	stats, err := v.getStageItemDropStats(ctx, reportTask.Server, report.StageID)
+	if err != nil {
+		v.logError(err, "failed to get stage item drop stats")
+		return nil
+	}
+
+	itemId, zScore := v.scoreSequence(sequenceTimes, sequenceQuantities, stats)
+	if math.Abs(zScore) > v.Config.ReportAnomalyZScoreThreshold {
+		log.Warn().
+			Str("evt.name", "verifier.rate_anomaly.implausible_sequence").
+			Int("accountId", reportTask.AccountID).
+			Str("stageId", report.StageID).
+			Int("itemId", itemId).
+			Int("sequenceTimes", sequenceTimes).
+			Float64("zScore", zScore).
+			Msg("drop sequence of account is statistically implausible, lowering reliability")
+
+		return &Rejection{
+			Reliability: ViolationReliabilityAnomaly,
+			Message:     fmt.Sprintf("drop sequence of item %d is implausible (z-score %.2f over %d runs)", itemId, zScore, sequenceTimes),
+		}
+	}
+
+	return nil
+}
+
+// countWindowRuns records the report into the sliding window identified by subject, and returns the
+// total amount of runs recorded within the window.
+func (v *RateAnomalyVerifier) countWindowRuns(ctx context.Context, subject, member string, submittedAt time.Time, window time.Duration) (int, error) {
+	key := rateAnomalyRedisPrefix + "rate:" + subject
+
+	pipe := v.Redis.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, key, "-inf", "("+strconv.FormatInt(submittedAt.Add(-window).UnixMicro(), 10))
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(submittedAt.UnixMicro()), Member: member})
+	members := pipe.ZRange(ctx, key, 0, -1)
+	pipe.Expire(ctx, key, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+
+	runs := 0
+	for _, m := range members.Val() {
+		times, err := strconv.Atoi(m[strings.LastIndexByte(m, ':')+1:])
+		if err != nil {
+			continue
+		}
+		runs += times
+	}
+
+	return runs, nil
+}
+
+// accumulateSequence adds the report into the drop sequence of the account in the stage, and returns the
+// accumulated times and quantities by item ID. Sequences are accumulated over fixed windows of
+// ReportAnomalyWindow.
+//
+// Every report is stored as its own field keyed by its task ID and index, rather than incremented into running
+// totals, so that verifying the same report again (e.g. when a failed report task is redelivered) does not count
+// it twice.
+func (v *RateAnomalyVerifier) accumulateSequence(ctx context.Context, report *types.ReportTaskSingleReport, reportTask *types.ReportTask, submittedAt time.Time) (int, map[int]int, error) {
+	window := v.Config.ReportAnomalyWindow
+	if window <= 0 {
+		return 0, nil, nil
+	}
+	bucket := submittedAt.UnixMicro() / window.Microseconds()
+	// contributions are stored per report since v2 of the key, and the hashes of running totals of v1 are left to expire
+	key := rateAnomalyRedisPrefix + "seq:v2:" + strings.Join([]string{
+		reportTask.Server,
+		strconv.Itoa(reportTask.AccountID),
+		report.StageID,
+		strconv.FormatInt(bucket, 10),
+	}, ":")
+
+	pipe := v.Redis.TxPipeline()
+	pipe.HSet(ctx, key, sequenceField(report, reportTask), encodeSequenceContribution(report))
+	all := pipe.HVals(ctx, key)
+	pipe.Expire(ctx, key, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, nil, err
+	}
+
+	times := 0
+	quantities := make(map[int]int)
+	for _, value := range all.Val() {
+		decodeSequenceContribution(value, &times, quantities)
+	}
+
+	return times, quantities, nil
+}
+
+// encodeSequenceContribution encodes the times and drops of the report as "times;itemId:quantity,...".
+func encodeSequenceContribution(report *types.ReportTaskSingleReport) string {
+	var b strings.Builder
+	b.WriteString(strconv.Itoa(report.Times))
+	b.WriteByte(';')
+	for i, drop := range report.Drops {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.Itoa(drop.ItemID))
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(drop.Quantity))
+	}
+	return b.String()
+}
+
+// decodeSequenceContribution adds a contribution encoded by encodeSequenceContribution into times and quantities.
+// Malformed parts are skipped.
+func decodeSequenceContribution(value string, times *int, quantities map[int]int) {
+	timesStr, dropsStr, _ := strings.Cut(value, ";")
+	n, err := strconv.Atoi(timesStr)
+	if err != nil {
+		return
+	}
+	*times += n
+
+	if dropsStr == "" {
+		return
+	}
+	for _, drop := range strings.Split(dropsStr, ",") {
+		itemIdStr, quantityStr, ok := strings.Cut(drop, ":")
+		if !ok {
+			continue
+		}
+		itemId, err := strconv.Atoi(itemIdStr)
+		if err != nil {
+			continue
+		}
+		quantity, err := strconv.Atoi(quantityStr)
+		if err != nil {
+			continue
+		}
+		quantities[itemId] += quantity
+	}
+}
+
+// scoreSequence returns the item whose accumulated quantity deviates the most from the drop matrix, along with
+// its z-score. The per-run quantity variance is derived from quantity buckets when available, and otherwise
+// approximated by a poisson distribution.
+func (v *RateAnomalyVerifier) scoreSequence(times int, quantities map[int]int, stats map[int]*model.StageItemDropStats) (int, float64) {
+	var maxItemId int
+	var maxZScore float64
+	for itemId, stat := range stats {
+		if stat.Times < v.Config.ReportAnomalyMinRuns {
+			continue
+		}
+
+		mean := float64(stat.Quantity) / float64(stat.Times)
+		variance := mean
+		if stat.HasBuckets {
+			variance = float64(stat.QuantitySquareSum)/float64(stat.Times) - mean*mean
+		}
+		if variance <= 0 {
+			// constant drops are already covered by the bounds checked in DropVerifier
+			continue
+		}
+
+		n := float64(times)
+		zScore := (float64(quantities[itemId]) - n*mean) / math.Sqrt(n*variance)
+		if math.Abs(zScore) > math.Abs(maxZScore) {
+			maxItemId = itemId
+			maxZScore = zScore
+		}
+	}
+
+	return maxItemId, maxZScore
+}
+
+func (v *RateAnomalyVerifier) getStageItemDropStats(ctx context.Context, server string, arkStageId string) (map[int]*model.StageItemDropStats, error) {
+	var stats map[int]*model.StageItemDropStats
+	valueFunc := func() (*map[int]*model.StageItemDropStats, error) {
+		stats := make(map[int]*model.StageItemDropStats)
+
+		dropInfos, err := v.DropInfoRepo.GetForCurrentTimeRange(ctx, &repo.DropInfoQuery{
+			Server:     server,
+			ArkStageId: arkStageId,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(dropInfos) == 0 {
+			return &stats, nil
+		}
+
+		timeRange, err := v.TimeRangeRepo.GetTimeRangeById(ctx, dropInfos[0].RangeID)
+		if err != nil {
+			return nil, err
+		}
+
+		elements, err := v.DropMatrixElementRepo.GetElementsByServerAndStageIdAndTimeRange(ctx, server, dropInfos[0].StageID, timeRange, constant.SourceCategoryAll)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, el := range elements {
+			stat, ok := stats[el.ItemID]
+			if !ok {
+				stat = &model.StageItemDropStats{ItemID: el.ItemID, HasBuckets: true}
+				stats[el.ItemID] = stat
+			}
+			stat.Times += el.Times
+			stat.Quantity += el.Quantity
+			if el.QuantityBuckets == nil {
+				stat.HasBuckets = false
+			}
+			for quantity, count := range el.QuantityBuckets {
+				stat.QuantitySquareSum += quantity * quantity * count
+			}
+		}
+
+		return &stats, nil
+	}
+
+	if _, err := cache.StageItemDropStats.MutexGetSet(server+constant.CacheSep+arkStageId, &stats, valueFunc, stageItemDropStatsCacheTTL); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func (v *RateAnomalyVerifier) logError(err error, msg string) {
+	log.Error().
+		Str("evt.name", "verifier.rate_anomaly.error").
+		Err(err).
+		Msg(msg + ": letting report pass")
+}
+
+// rateWindowMember identifies a single report in the sliding windows. The report times is suffixed
+// so that the runs within a window could be summed up.
+func rateWindowMember(report *types.ReportTaskSingleReport, reportTask *types.ReportTask) string {
+	return sequenceField(report, reportTask) + ":" + strconv.Itoa(report.Times)
+}
+
+// sequenceField identifies a single report by its task ID and its index within the task.
+func sequenceField(report *types.ReportTaskSingleReport, reportTask *types.ReportTask) string {
+	index := 0
+	for i, r := range reportTask.Reports {
+		if r == report {
+			index = i
+			break
+		}
+	}
+	return reportTask.TaskID + ":" + strconv.Itoa(index)
+}
@@ -0,0 +1,73 @@
+package reportverifs
+
+import (
+	"testing"
+
+	"exusiai.dev/backend-next/internal/app/appconfig"
+	"exusiai.dev/backend-next/internal/model"
+	"exusiai.dev/backend-next/internal/model/types"
+)
+
+func TestRateAnomalyVerifierScoreSequence(t *testing.T) {
+	v := &RateAnomalyVerifier{
+		Config: &appconfig.Config{
+			ConfigSpec: appconfig.ConfigSpec{ReportAnomalyMinRuns: 30},
+		},
+	}
+
+	// item 1 drops once in half of the runs; item 2 has too few samples to be scored
+	stats := map[int]*model.StageItemDropStats{
+		1: {ItemID: 1, Times: 1000, Quantity: 500, QuantitySquareSum: 500, HasBuckets: true},
+		2: {ItemID: 2, Times: 10, Quantity: 10},
+	}
+
+	itemId, zScore := v.scoreSequence(100, map[int]int{1: 50, 2: 100}, stats)
+	if zScore != 0 {
+		t.Errorf("expected a plausible sequence to score 0, got item %d with %.2f", itemId, zScore)
+	}
+
+	itemId, zScore = v.scoreSequence(100, map[int]int{1: 100}, stats)
+	if itemId != 1 || zScore < 9.99 || zScore > 10.01 {
+		t.Errorf("expected item 1 to score 10, got item %d with %.2f", itemId, zScore)
+	}
+}
+
+func TestSequenceContributionRoundTrip(t *testing.T) {
+	report := &types.ReportTaskSingleReport{
+		Times: 3,
+		Drops: []*types.Drop{
+			{ItemID: 1, Quantity: 2},
+			{ItemID: 5, Quantity: 7},
+		},
+	}
+
+	encoded := encodeSequenceContribution(report)
+	if encoded != "3;1:2,5:7" {
+		t.Errorf("expected report to be encoded as %q, got %q", "3;1:2,5:7", encoded)
+	}
+
+	times := 0
+	quantities := make(map[int]int)
+	for _, value := range []string{encoded, "2;", "1;1:1,bad,9:x", "malformed"} {
+		decodeSequenceContribution(value, &times, quantities)
+	}
+	if times != 6 {
+		t.Errorf("expected 6 accumulated times, got %d", times)
+	}
+	if len(quantities) != 2 || quantities[1] != 3 || quantities[5] != 7 {
+		t.Errorf("expected accumulated quantities {1: 3, 5: 7}, got %v", quantities)
+	}
+}
+
+func TestSequenceFieldIdentifiesReport(t *testing.T) {
+	first := &types.ReportTaskSingleReport{Times: 1}
+	second := &types.ReportTaskSingleReport{Times: 1}
+	task := &types.ReportTask{TaskID: "task", Reports: []*types.ReportTaskSingleReport{first, second}}
+
+	if sequenceField(first, task) != "task:0" || sequenceField(second, task) != "task:1" {
+		t.Errorf("expected fields task:0 and task:1, got %s and %s", sequenceField(first, task), sequenceField(second, task))
+	}
+	if rateWindowMember(second, task) != "task:1:1" {
+		t.Errorf("expected member task:1:1, got %s", rateWindowMember(second, task))
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"exusiai.dev/gommon/constant"
 	"github.com/antonmedv/expr"
+	"github.com/nats-io/nats.go"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 	"golang.org/x/mod/semver"
@@ -19,15 +20,31 @@ var ErrExprMatched = errors.New("reject expr matched")
 
 type RejectRuleVerifier struct {
 	RejectRuleRepo *repo.RejectRule
+	Programs       *RejectRulePrograms
 }
 
 // ensure RejectRuleVerifier conforms to Verifier
 var _ Verifier = (*RejectRuleVerifier)(nil)
 
-func NewRejectRuleVerifier(rejectRuleRepo *repo.RejectRule) *RejectRuleVerifier {
-	return &RejectRuleVerifier{
+func NewRejectRuleVerifier(rejectRuleRepo *repo.RejectRule, natsConn *nats.Conn) (*RejectRuleVerifier, error) {
+	v := &RejectRuleVerifier{
 		RejectRuleRepo: rejectRuleRepo,
+		Programs:       NewRejectRulePrograms(),
 	}
+
+	// core NATS subscription (not a queue group) so that every instance flushes its own programs
+	if _, err := natsConn.Subscribe(RejectRuleProgramsInvalidateSubject, func(msg *nats.Msg) {
+		log.Info().
+			Str("evt.name", "verifier.reject_rule.programs_invalidated").
+			Str("data", string(msg.Data)).
+			Msg("reject rules changed: flushing compiled reject rule programs")
+		v.Programs.Flush()
+	}); err != nil {
+		log.Error().Err(err).Msg("verifier: reject_rule: failed to subscribe to reject rule invalidations")
+		return nil, err
+	}
+
+	return v, nil
 }
 
 func (d *RejectRuleVerifier) Name() string {
@@ -75,7 +92,20 @@ func (d *RejectRuleVerifier) Verify(ctx context.Context, report *types.ReportTas
 			continue
 		}
 
-		result, err := expr.Eval(rejectRule.Expr, reportContext)
+		program, compiled, err := d.Programs.Get(rejectRule)
+		if err != nil {
+			// compilation errors are cached per rule revision, so only log them once
+			if compiled {
+				log.Error().
+					Str("evt.name", "verifier.reject_rule.expr_compile_error").
+					Int("ruleId", rejectRule.RuleID).
+					Err(err).
+					Msgf("failed to compile reject rule %d: rule will be skipped until it is updated", rejectRule.RuleID)
+			}
+			continue
+		}
+
+		result, err := expr.Run(program, reportContext)
 		if err != nil {
 			log.Error().
 				Str("evt.name", "verifier.reject_rule.expr_eval_error").
@@ -1,6 +1,18 @@
 package reportverifs
 
-import "bytes"
+import (
+	"bytes"
+
+	"exusiai.dev/gommon/constant"
+)
+
+// Reliabilities of violations which are not (yet) defined in gommon's constant package, next to the ones which are.
+const (
+	// ViolationReliabilityRate marks reports submitted while the account or IP exceeded its submission rate.
+	ViolationReliabilityRate = 5
+	// ViolationReliabilityAnomaly marks reports whose account has a statistically implausible drop sequence.
+	ViolationReliabilityAnomaly = 6
+)
 
 type Violations map[int]*Violation
 
@@ -34,3 +46,28 @@ type Rejection struct {
 	Reliability int    `json:"reliability"`
 	Message     string `json:"message"`
 }
+
+// VerifierNameByReliability returns the name of the verifier which marks reports with the given reliability.
+// Reliability 0 (accepted) and -1 (recalled by the account) are not produced by verifiers, and are returned as
+// "accepted" and "recalled" respectively.
+func VerifierNameByReliability(reliability int) string {
+	switch {
+	case reliability == 0:
+		return "accepted"
+	case reliability == -1:
+		return "recalled"
+	case reliability == constant.ViolationReliabilityUser:
+		return "user"
+	case reliability == constant.ViolationReliabilityMD5:
+		return "md5"
+	case reliability == constant.ViolationReliabilityDrop:
+		return "drop"
+	case reliability == constant.ViolationReliabilityRejectRuleUnexpected,
+		reliability >= constant.ViolationReliabilityRejectRuleRangeLeast && reliability < constant.ViolationReliabilityRejectRuleRangeMost:
+		return "reject_rule"
+	case reliability == ViolationReliabilityRate, reliability == ViolationReliabilityAnomaly:
+		return "rate_anomaly"
+	default:
+		return "unknown"
+	}
+}
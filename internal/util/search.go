@@ -0,0 +1,85 @@
+package util
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/width"
+)
+
+// NormalizeSearchText folds s for matching: full-width characters are folded to their half-width forms (and
+// half-width katakana to full-width), letters are lower-cased, and whitespaces and punctuations are removed,
+// so that e.g. "１－７", "1-7" and "1 7" all become "17".
+func NormalizeSearchText(s string) string {
+	s = width.Fold.String(s)
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for _, r := range s {
+		if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			continue
+		}
+		sb.WriteRune(unicode.ToLower(r))
+	}
+	return sb.String()
+}
+
+// CalcSearchScore scores how well candidate matches query, both of which should have been normalized by
+// NormalizeSearchText. The score is in [0, 1]: 1 for an exact match, followed by prefix matches, substring matches,
+// and at last matches tolerating typos, scored by the edit distance between the query and the candidate (or its
+// prefix of the same length, so that a partially typed query is matched as well). 0 means no match.
+func CalcSearchScore(query, candidate string) float64 {
+	if query == "" || candidate == "" {
+		return 0
+	}
+	if query == candidate {
+		return 1
+	}
+	if strings.HasPrefix(candidate, query) {
+		return 0.9
+	}
+	if strings.Contains(candidate, query) {
+		return 0.8
+	}
+
+	q := []rune(query)
+	c := []rune(candidate)
+	// a single typo in a query of 1 or 2 characters leaves nothing to be matched
+	if len(q) < 3 {
+		return 0
+	}
+	similarity := calcSimilarity(q, c)
+	if len(c) > len(q) {
+		similarity = max(similarity, calcSimilarity(q, c[:len(q)]))
+	}
+	// allow at most one typo for every 3 characters
+	if similarity < 2.0/3 {
+		return 0
+	}
+	return 0.7 * similarity
+}
+
+// calcSimilarity returns 1 - (levenshtein distance of a and b) / (length of the longer one).
+func calcSimilarity(a, b []rune) float64 {
+	longer := max(len(a), len(b))
+	return 1 - float64(calcLevenshteinDistance(a, b))/float64(longer)
+}
+
+func calcLevenshteinDistance(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
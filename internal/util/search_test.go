@@ -0,0 +1,34 @@
+package util
+
+import "testing"
+
+func TestNormalizeSearchText(t *testing.T) {
+	for _, s := range []string{"1-7", "１－７", "1 7"} {
+		if got := NormalizeSearchText(s); got != "17" {
+			t.Errorf("NormalizeSearchText(%q) = %q, want %q", s, got, "17")
+		}
+	}
+	if got := NormalizeSearchText("Orirock Cube"); got != "orirockcube" {
+		t.Errorf("NormalizeSearchText(%q) = %q, want %q", "Orirock Cube", got, "orirockcube")
+	}
+}
+
+func TestCalcSearchScore(t *testing.T) {
+	exact := CalcSearchScore("orirock", "orirock")
+	prefix := CalcSearchScore("orirock", "orirockcube")
+	substring := CalcSearchScore("rock", "orirock")
+	typo := CalcSearchScore("orirok", "orirock")
+	partialTypo := CalcSearchScore("orirok", "orirockcluster")
+	if !(exact > prefix && prefix > substring && substring > typo && typo > 0) {
+		t.Errorf("unexpected ranking: exact %f, prefix %f, substring %f, typo %f", exact, prefix, substring, typo)
+	}
+	if partialTypo <= 0 {
+		t.Errorf("expected a partially typed query with a typo to match, got %f", partialTypo)
+	}
+	if score := CalcSearchScore("orirock", "device"); score != 0 {
+		t.Errorf("expected unrelated candidate not to match, got %f", score)
+	}
+	if score := CalcSearchScore("xy", "xz"); score != 0 {
+		t.Errorf("expected short queries not to tolerate typos, got %f", score)
+	}
+}
@@ -19,13 +19,17 @@ import (
 type WorkerDeps struct {
 	fx.In
 
-	Config               *appconfig.Config
-	DropMatrixService    *service.DropMatrix
-	PatternMatrixService *service.PatternMatrix
-	TrendService         *service.Trend
-	SiteStatsService     *service.SiteStats
-	ArchiveService       *service.Archive
-	RedSync              *redsync.Redsync
+	Config                 *appconfig.Config
+	DropMatrixService      *service.DropMatrix
+	PatternMatrixService   *service.PatternMatrix
+	MatrixDeltaService     *service.MatrixDelta
+	TrendService           *service.Trend
+	SiteStatsService       *service.SiteStats
+	ArchiveService         *service.Archive
+	SanityValueService     *service.SanityValue
+	DriftService           *service.Drift
+	DropReportExtraService *service.DropReportExtra
+	RedSync                *redsync.Redsync
 }
 
 type Worker struct {
@@ -104,8 +108,11 @@ func (w *Worker) doMainCalc(sourceCategories []string) {
 	w.task(context.Background(), WorkerCalcTypeStatsCalc, func(ctx context.Context, server string) error {
 		var err error
 
-		// DropMatrixService
+		// DropMatrixService: the report worker maintains the elements itself if incremental, so they are only reconciled
 		if err = w.microtask(ctx, "dropMatrix", server, func() error {
+			if w.Config.MatrixIncrementalEnabled {
+				return w.MatrixDeltaService.RunReconcileDropMatrixJob(ctx, server)
+			}
 			return w.DropMatrixService.RunCalcDropMatrixJob(ctx, server)
 		}); err != nil {
 			return err
@@ -114,12 +121,31 @@ func (w *Worker) doMainCalc(sourceCategories []string) {
 
 		// PatternMatrixService
 		if err = w.microtask(ctx, "patternMatrix", server, func() error {
+			if w.Config.MatrixIncrementalEnabled {
+				return w.MatrixDeltaService.RunReconcilePatternMatrixJob(ctx, server)
+			}
 			return w.PatternMatrixService.RunCalcPatternMatrixJob(ctx, server)
 		}); err != nil {
 			return err
 		}
 		time.Sleep(w.sep)
 
+		// SanityValueService: relies on the drop matrix calculated above
+		if err = w.microtask(ctx, "sanityValue", server, func() error {
+			return w.SanityValueService.RunCalcSanityValueJob(ctx, server)
+		}); err != nil {
+			return err
+		}
+		time.Sleep(w.sep)
+
+		// DriftService: relies on the drop matrix elements calculated above
+		if err = w.microtask(ctx, "drift", server, func() error {
+			return w.DriftService.RunDropDriftJob(ctx, server)
+		}); err != nil {
+			return err
+		}
+		time.Sleep(w.sep)
+
 		// SiteStatsService
 		if err = w.microtask(ctx, "siteStats", server, func() error {
 			_, err := w.SiteStatsService.RefreshShimSiteStats(ctx, server)
@@ -127,8 +153,27 @@ func (w *Worker) doMainCalc(sourceCategories []string) {
 		}); err != nil {
 			return err
 		}
+		time.Sleep(w.sep)
+
+		// SiteStatsService: daily series
+		if err = w.microtask(ctx, "siteStatsDaily", server, func() error {
+			return w.SiteStatsService.RunSiteStatsDailyJob(ctx, server)
+		}); err != nil {
+			return err
+		}
+
+		// server == "CN": we only run server-agnostic jobs on a singular server
+		if w.Config.DropReportIPRetentionDays > 0 && server == "CN" {
+			// IP retention; erased before archiving so that archives contain no expired IPs either
+			if err = w.microtask(ctx, "ipRetention", server, func() error {
+				return w.DropReportExtraService.RunEraseExpiredIPsJob(ctx)
+			}); err != nil {
+				return err
+			}
+
+			time.Sleep(w.sep)
+		}
 
-		// server == "CN": we only run archive job on a singular server
 		if w.Config.DropReportArchiveEnabled && server == "CN" {
 			// Archive
 			if err = w.microtask(ctx, "archive", server, func() error {
@@ -22,7 +22,9 @@ import (
 
 	"exusiai.dev/backend-next/internal/app/appconfig"
 	"exusiai.dev/backend-next/internal/model"
+	"exusiai.dev/backend-next/internal/model/pb"
 	"exusiai.dev/backend-next/internal/model/types"
+	modelv3 "exusiai.dev/backend-next/internal/model/v3"
 	"exusiai.dev/backend-next/internal/pkg/jetstream"
 	"exusiai.dev/backend-next/internal/pkg/observability"
 	"exusiai.dev/backend-next/internal/repo"
@@ -33,17 +35,29 @@ import (
 
 var tracer = otel.Tracer("reportwkr")
 
+const (
+	reportVerdictRedisPrefix = "report:verdict:"
+	// reportVerdictTTL outlasts the redeliveries of a task, which are at most ReportWorkerMaxDeliver backoffs apart
+	reportVerdictTTL = time.Hour * 24
+)
+
 type WorkerDeps struct {
 	fx.In
-	DB                     *bun.DB
-	Redis                  *redis.Client
-	NatsJS                 nats.JetStreamContext
-	StageService           *service.Stage
-	DropReportRepo         *repo.DropReport
-	DropPatternRepo        *repo.DropPattern
-	DropReportExtraRepo    *repo.DropReportExtra
-	DropPatternElementRepo *repo.DropPatternElement
-	ReportVerifier         *reportverifs.ReportVerifiers
+	Config                  *appconfig.Config
+	DB                      *bun.DB
+	Redis                   *redis.Client
+	NatsJS                  nats.JetStreamContext
+	StageService            *service.Stage
+	DropReportRepo          *repo.DropReport
+	DropPatternRepo         *repo.DropPattern
+	DropReportExtraRepo     *repo.DropReportExtra
+	DropPatternElementRepo  *repo.DropPatternElement
+	ReportVerifier          *reportverifs.ReportVerifiers
+	LiveService             *service.Live
+	GeoIPService            *service.GeoIP
+	ReportDeadLetterService *service.ReportDeadLetter
+	ReportTaskStatusService *service.ReportTaskStatus
+	MatrixDeltaService      *service.MatrixDelta
 }
 
 type Worker struct {
@@ -107,12 +121,6 @@ func (w *Worker) Consumer(ctx context.Context, ch chan error) error {
 }
 
 func (w *Worker) ingestPreprocess(ctx context.Context, msg *nats.Msg) error {
-	defer func() {
-		if err := msg.Ack(); err != nil {
-			log.Error().Err(err).Msg("failed to ack")
-		}
-	}()
-
 	taskCtx, cancelTask := context.WithTimeout(ctx, time.Second*10)
 	defer cancelTask()
 
@@ -124,8 +132,17 @@ func (w *Worker) ingestPreprocess(ctx context.Context, msg *nats.Msg) error {
 	})
 	defer inprogressInformer.Stop()
 
+	metadata, err := msg.Metadata()
+	if err != nil {
+		// should not happen: the message should be always a jetstream message
+		w.ack(msg)
+		return err
+	}
+
 	reportTask := &types.ReportTask{}
 	if err := json.Unmarshal(msg.Data, reportTask); err != nil {
+		// a malformed task would never succeed, so it is dead-lettered without retrying
+		w.deadLetter(ctx, msg, "", metadata.NumDelivered, err)
 		return err
 	}
 
@@ -134,12 +151,6 @@ func (w *Worker) ingestPreprocess(ctx context.Context, msg *nats.Msg) error {
 		WithLabelValues().
 		Observe(time.Since(start).Seconds())
 
-	metadata, err := msg.Metadata()
-	if err != nil {
-		// should not happen: the message should be always a jetstream message
-		return err
-	}
-
 	var span trace.Span
 	taskCtx, span = tracer.
 		Start(taskCtx, "reportwkr.ConsumeTask",
@@ -156,15 +167,18 @@ func (w *Worker) ingestPreprocess(ctx context.Context, msg *nats.Msg) error {
 		log.Error().
 			Err(err).
 			Str("taskId", reportTask.TaskID).
+			Uint64("deliveries", metadata.NumDelivered).
 			Interface("reportTask", reportTask).
 			Msg("failed to consume report task")
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
 		span.End()
+		w.retryOrDeadLetter(ctx, msg, reportTask.TaskID, metadata.NumDelivered, err)
 		return err
 	}
 	span.SetStatus(codes.Ok, "")
 	span.End()
+	w.ack(msg)
 
 	log.Info().
 		Str("evt.name", "reportwkr.processed").
@@ -176,6 +190,99 @@ func (w *Worker) ingestPreprocess(ctx context.Context, msg *nats.Msg) error {
 	return nil
 }
 
+func (w *Worker) ack(msg *nats.Msg) {
+	if err := msg.Ack(); err != nil {
+		log.Error().Err(err).Msg("failed to ack")
+	}
+}
+
+// retryOrDeadLetter naks the message to have it redelivered after a backoff, or moves it to the dead letter stream
+// once it has been delivered ReportWorkerMaxDeliver times. The delivery count is checked here rather than set as
+// the MaxDeliver of the consumer, as JetStream would silently stop redelivering the message after that.
+func (w *Worker) retryOrDeadLetter(ctx context.Context, msg *nats.Msg, taskId string, deliveries uint64, cause error) {
+	if w.deliveriesExhausted(deliveries) {
+		w.deadLetter(ctx, msg, taskId, deliveries, cause)
+		return
+	}
+
+	observability.ReportRetries.WithLabelValues().Inc()
+	if err := msg.NakWithDelay(w.retryBackoff(deliveries)); err != nil {
+		log.Error().Err(err).Msg("failed to nak")
+	}
+}
+
+// deliveriesExhausted tells whether a task which failed on its deliveries-th delivery shall be dead-lettered
+// rather than retried.
+func (w *Worker) deliveriesExhausted(deliveries uint64) bool {
+	return deliveries >= uint64(w.Config.ReportWorkerMaxDeliver)
+}
+
+// retryBackoff is ReportWorkerRetryBackoff doubled for every delivery after the first one, up to
+// ReportWorkerRetryMaxBackoff.
+func (w *Worker) retryBackoff(deliveries uint64) time.Duration {
+	backoff := w.Config.ReportWorkerRetryBackoff
+	for i := uint64(1); i < deliveries && backoff < w.Config.ReportWorkerRetryMaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, w.Config.ReportWorkerRetryMaxBackoff)
+}
+
+func (w *Worker) deadLetter(ctx context.Context, msg *nats.Msg, taskId string, deliveries uint64, cause error) {
+	publishCtx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	if err := w.ReportDeadLetterService.DeadLetterReportTask(publishCtx, msg, taskId, deliveries, cause); err != nil {
+		log.Error().
+			Err(err).
+			Str("subject", msg.Subject).
+			Msg("failed to dead letter report task: it will be redelivered and dead-lettered again")
+		if err := msg.NakWithDelay(w.Config.ReportWorkerRetryMaxBackoff); err != nil {
+			log.Error().Err(err).Msg("failed to nak")
+		}
+		return
+	}
+
+	log.Warn().
+		Str("evt.name", "reportwkr.deadlettered").
+		Str("taskId", taskId).
+		Str("subject", msg.Subject).
+		Uint64("deliveries", deliveries).
+		AnErr("cause", cause).
+		Msg("report task moved to the dead letter stream")
+	observability.ReportDeadLettered.WithLabelValues().Inc()
+	w.ack(msg)
+}
+
+// verify runs the verifiers against the report task, or returns the verdict of a previous delivery of the task.
+// Verdicts are reused as some verifiers are stateful (e.g. rate_anomaly counts the runs of every report it
+// verifies), and a redelivered task would otherwise be counted once per delivery. Verdicts are kept in Redis on a
+// best-effort basis: if they could not be stored or loaded, the task is verified again.
+func (w *Worker) verify(ctx context.Context, reportTask *types.ReportTask) reportverifs.Violations {
+	key := reportVerdictRedisPrefix + reportTask.TaskID
+
+	b, err := w.Redis.Get(ctx, key).Bytes()
+	if err == nil {
+		var violations reportverifs.Violations
+		if err := json.Unmarshal(b, &violations); err == nil {
+			return violations
+		}
+	} else if !errors.Is(err, redis.Nil) {
+		log.Warn().Err(err).Str("taskId", reportTask.TaskID).Msg("failed to load report task verdict: verifying again")
+	}
+
+	violations := w.ReportVerifier.Verify(ctx, reportTask)
+
+	b, err = json.Marshal(violations)
+	if err == nil {
+		err = w.Redis.Set(ctx, key, b, reportVerdictTTL).Err()
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("taskId", reportTask.TaskID).Msg("failed to store report task verdict: redeliveries will verify again")
+	}
+
+	return violations
+}
+
 func (w *Worker) process(ctx context.Context, reportTask *types.ReportTask) error {
 	L := log.With().
 		Interface("task", reportTask).
@@ -196,7 +303,7 @@ func (w *Worker) process(ctx context.Context, reportTask *types.ReportTask) erro
 		Start(ctx, "reportwkr.process.Verify",
 			trace.WithSpanKind(trace.SpanKindInternal))
 
-	violations := w.ReportVerifier.Verify(verifyCtx, reportTask)
+	violations := w.verify(verifyCtx, reportTask)
 	if len(violations) > 0 {
 		L.Warn().
 			Str("evt.name", "reportwkr.violations").
@@ -225,6 +332,12 @@ func (w *Worker) process(ctx context.Context, reportTask *types.ReportTask) erro
 		}
 	}()
 
+	// liveElements collects matrix deltas of reliable reports, which are pushed to live subscribers after commit
+	liveElements := make([]*pb.MatrixUpdateMessage_Element, 0)
+	results := make([]*modelv3.ReportTaskResult, 0, len(reportTask.Reports))
+	// matrixDeltas collects reliable reports, which are applied to the matrix elements before commit
+	matrixDeltas := make([]*service.MatrixDeltaReport, 0, len(reportTask.Reports))
+
 	// calculate drop pattern hash for each report
 	for idx, report := range reportTask.Reports {
 		report.Drops = reportutil.MergeDropsByItemID(report.Drops)
@@ -264,6 +377,31 @@ func (w *Worker) process(ctx context.Context, reportTask *types.ReportTask) erro
 
 		observability.ReportReliability.WithLabelValues(strconv.Itoa(reliability), reportTask.Source).Inc()
 
+		result := &modelv3.ReportTaskResult{
+			ReportID:    dropReport.ReportID,
+			Reliability: reliability,
+		}
+		if violation, ok := violations[idx]; ok {
+			result.Violation = violation.Name
+			result.Message = violation.Message
+		}
+		results = append(results, result)
+
+		if reliability == 0 {
+			matrixDeltas = append(matrixDeltas, &service.MatrixDeltaReport{
+				Report: dropReport,
+				Drops:  report.Drops,
+				Sign:   1,
+			})
+
+			elements, err := w.LiveService.BuildMatrixUpdateElements(pstCtx, reportTask.Server, stage.StageID, report.Times, report.Drops)
+			if err != nil {
+				L.Warn().Err(err).Msg("failed to build live matrix update elements: skipping")
+			} else {
+				liveElements = append(liveElements, elements...)
+			}
+		}
+
 		md5 := ""
 		if report.Metadata != nil && report.Metadata.MD5 != "" {
 			md5 = report.Metadata.MD5
@@ -278,11 +416,16 @@ func (w *Worker) process(ctx context.Context, reportTask *types.ReportTask) erro
 
 			reportTask.IP = "127.0.0.1"
 		}
+		country := ""
+		if w.Config.DropReportCountryAttributionEnabled {
+			country = w.GeoIPService.CountryCode(reportTask.IP)
+		}
 		if err = w.DropReportExtraRepo.CreateDropReportExtra(pstCtx, tx, &model.DropReportExtra{
 			ReportID: dropReport.ReportID,
 			IP:       reportTask.IP,
 			Metadata: report.Metadata,
 			MD5:      null.NewString(md5, md5 != ""),
+			Country:  null.NewString(country, country != ""),
 		}); err != nil {
 			return errors.Wrap(err, "failed to create drop report extra")
 		}
@@ -292,10 +435,31 @@ func (w *Worker) process(ctx context.Context, reportTask *types.ReportTask) erro
 		}
 	}
 
+	matrixServers, err := w.MatrixDeltaService.ApplyReports(pstCtx, tx, matrixDeltas)
+	if err != nil {
+		return errors.Wrap(err, "failed to update matrix elements")
+	}
+
 	intendedCommit = true
 	if err := tx.Commit(); err != nil {
 		return errors.Wrap(err, "failed to commit transaction")
 	}
 
+	w.MatrixDeltaService.SchedulePurge(matrixServers...)
+
+	if err := w.Redis.Del(ctx, reportVerdictRedisPrefix+reportTask.TaskID).Err(); err != nil {
+		L.Warn().Err(err).Msg("failed to delete report task verdict: it will expire instead")
+	}
+
+	// live updates are best-effort: failing to publish them shall not fail the task
+	if err := w.LiveService.PublishMatrixUpdate(reportTask.Server, liveElements); err != nil {
+		L.Warn().Err(err).Msg("failed to publish live matrix update")
+	}
+
+	// the reports have been committed, so failing to update the status shall not fail the task either
+	if err := w.ReportTaskStatusService.SetReportTaskProcessed(ctx, reportTask.TaskID, results); err != nil {
+		L.Warn().Err(err).Msg("failed to set report task status as processed")
+	}
+
 	return nil
 }
@@ -0,0 +1,82 @@
+package reportwkr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+
+	"exusiai.dev/backend-next/internal/app/appconfig"
+	"exusiai.dev/backend-next/internal/util/reportverifs"
+)
+
+func newTestWorker() *Worker {
+	return &Worker{
+		WorkerDeps: WorkerDeps{
+			Config: &appconfig.Config{
+				ConfigSpec: appconfig.ConfigSpec{
+					ReportWorkerMaxDeliver:      5,
+					ReportWorkerRetryBackoff:    time.Second * 5,
+					ReportWorkerRetryMaxBackoff: time.Second * 30,
+				},
+			},
+		},
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	w := newTestWorker()
+
+	tests := []struct {
+		deliveries uint64
+		want       time.Duration
+	}{
+		{1, time.Second * 5},
+		{2, time.Second * 10},
+		{3, time.Second * 20},
+		// capped at ReportWorkerRetryMaxBackoff
+		{4, time.Second * 30},
+		{100, time.Second * 30},
+	}
+	for _, tt := range tests {
+		if got := w.retryBackoff(tt.deliveries); got != tt.want {
+			t.Errorf("retryBackoff(%d) = %s, want %s", tt.deliveries, got, tt.want)
+		}
+	}
+}
+
+func TestDeliveriesExhausted(t *testing.T) {
+	w := newTestWorker()
+
+	for deliveries := uint64(1); deliveries < 5; deliveries++ {
+		if w.deliveriesExhausted(deliveries) {
+			t.Errorf("expected delivery %d to be retried", deliveries)
+		}
+	}
+	for _, deliveries := range []uint64{5, 6} {
+		if !w.deliveriesExhausted(deliveries) {
+			t.Errorf("expected delivery %d to be dead-lettered", deliveries)
+		}
+	}
+}
+
+func TestVerdictRoundTrip(t *testing.T) {
+	violations := reportverifs.Violations{
+		1: {
+			Name:      "rate_anomaly",
+			Rejection: reportverifs.Rejection{Reliability: reportverifs.ViolationReliabilityRate, Message: "too fast"},
+		},
+	}
+
+	b, err := json.Marshal(violations)
+	if err != nil {
+		t.Fatalf("failed to marshal verdict: %v", err)
+	}
+	var got reportverifs.Violations
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("failed to unmarshal verdict: %v", err)
+	}
+	if got.Reliability(0) != 0 || got.Reliability(1) != reportverifs.ViolationReliabilityRate || got[1].Name != "rate_anomaly" {
+		t.Errorf("expected verdict to survive a round trip, got %+v", got)
+	}
+}